@@ -0,0 +1,299 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Validate return error unless lattice is non-singular 3x3, cell has atoms,
+// Natom matches Elem and rows of Position and all values are finite
+func (c *Cell) Validate() error {
+	if c.Lattice == nil {
+		return fmt.Errorf("cell has no lattice")
+	}
+	if r, k := c.Lattice.Dims(); r != 3 || k != 3 {
+		return fmt.Errorf("expect 3x3 lattice, got %dx%d", r, k)
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if v := c.Lattice.At(i, j); math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("lattice %d%d is %g", i, j, v)
+			}
+		}
+	}
+	if math.Abs(mat.Det(c.Lattice)) < 1e-5 {
+		return fmt.Errorf("lattice cannot be det=0")
+	}
+	if c.Natom <= 0 {
+		return fmt.Errorf("cell must have atoms, got Natom=%d", c.Natom)
+	}
+	if len(c.Elem) != c.Natom {
+		return fmt.Errorf("atom number not compatible, Natom=%d, len(Elem)=%d", c.Natom, len(c.Elem))
+	}
+	if c.Position == nil {
+		return fmt.Errorf("cell of %d atoms has no positions", c.Natom)
+	}
+	if r, k := c.Position.Dims(); r != c.Natom || k != 3 {
+		return fmt.Errorf("expect %dx3 positions, got %dx%d", c.Natom, r, k)
+	}
+	for i := 0; i < c.Natom; i++ {
+		for j := 0; j < 3; j++ {
+			if v := c.Position.At(i, j); math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("position %d of atom %d is %g", j, i, v)
+			}
+		}
+	}
+	return nil
+}
+
+// checkIndices return error unless indices are distinct atoms of c
+func (c *Cell) checkIndices(indices []int) error {
+	seen := make(map[int]bool)
+	for _, i := range indices {
+		if i < 0 || i >= c.Natom {
+			return fmt.Errorf("atom index %d out of range [0, %d)", i, c.Natom)
+		}
+		if seen[i] {
+			return fmt.Errorf("atom index %d repeated", i)
+		}
+		seen[i] = true
+	}
+	return nil
+}
+
+// setAtoms replace atoms of c by positions pos and types elem, at least
+// one atom
+func (c *Cell) setAtoms(pos [][3]float64, elem []int) {
+	c.Natom = len(elem)
+	c.Elem = elem
+	c.Position = mat.NewDense(c.Natom, 3, nil)
+	for i, p := range pos {
+		c.Position.SetRow(i, p[:])
+	}
+}
+
+// atoms return copy of fractional positions and types
+func (c *Cell) atoms() ([][3]float64, []int) {
+	pos := make([][3]float64, c.Natom)
+	for i := range pos {
+		pos[i] = rowF3(c.Position, i)
+	}
+	return pos, append([]int(nil), c.Elem...)
+}
+
+// ToCartesian return Cartesian coordinate of fractional coordinate p
+func (c *Cell) ToCartesian(p [3]float64) [3]float64 {
+	var r [3]float64
+	for j := 0; j < 3; j++ {
+		for k := 0; k < 3; k++ {
+			r[j] += p[k] * c.Lattice.At(k, j)
+		}
+	}
+	return r
+}
+
+// ToFractional return fractional coordinate of Cartesian coordinate r
+func (c *Cell) ToFractional(r [3]float64) [3]float64 {
+	var inv mat.Dense
+	inv.Inverse(c.Lattice)
+	var p [3]float64
+	for j := 0; j < 3; j++ {
+		for k := 0; k < 3; k++ {
+			p[j] += r[k] * inv.At(k, j)
+		}
+	}
+	return p
+}
+
+// CartesianPositions return Cartesian positions of atoms as rows
+func (c *Cell) CartesianPositions() *mat.Dense {
+	var r mat.Dense
+	r.Mul(c.Position, c.Lattice)
+	return &r
+}
+
+// SetCartesianPositions set positions of atoms from Cartesian rows
+func (c *Cell) SetCartesianPositions(r *mat.Dense) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if n, k := r.Dims(); n != c.Natom || k != 3 {
+		return fmt.Errorf("expect %dx3 positions, got %dx%d", c.Natom, n, k)
+	}
+	var inv, p mat.Dense
+	if err := inv.Inverse(c.Lattice); err != nil {
+		return err
+	}
+	p.Mul(r, &inv)
+	c.Position = &p
+	return nil
+}
+
+// AddAtom append atom of type elem at p, Cartesian coordinate if
+// cartesian is true
+func (c *Cell) AddAtom(elem int, p [3]float64, cartesian bool) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	for _, v := range p {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("invalid position %v", p)
+		}
+	}
+	if cartesian {
+		p = c.ToFractional(p)
+	}
+	pos, types := c.atoms()
+	c.setAtoms(append(pos, p), append(types, elem))
+	return nil
+}
+
+// RemoveAtoms remove atoms of indices, the others keep their order. At
+// least one atom must be kept
+func (c *Cell) RemoveAtoms(indices ...int) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if err := c.checkIndices(indices); err != nil {
+		return err
+	}
+	if len(indices) == c.Natom {
+		return fmt.Errorf("cannot remove all %d atoms of cell", c.Natom)
+	}
+	removed := make(map[int]bool)
+	for _, i := range indices {
+		removed[i] = true
+	}
+	var pos [][3]float64
+	var types []int
+	for i := 0; i < c.Natom; i++ {
+		if !removed[i] {
+			pos = append(pos, rowF3(c.Position, i))
+			types = append(types, c.Elem[i])
+		}
+	}
+	c.setAtoms(pos, types)
+	return nil
+}
+
+// ReplaceAtoms change type of atoms of indices to elem
+func (c *Cell) ReplaceAtoms(elem int, indices ...int) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if err := c.checkIndices(indices); err != nil {
+		return err
+	}
+	for _, i := range indices {
+		c.Elem[i] = elem
+	}
+	return nil
+}
+
+// TranslateAtoms move atoms of indices, all atoms if none, by v,
+// Cartesian vector if cartesian is true. Positions are not wrapped
+func (c *Cell) TranslateAtoms(v [3]float64, cartesian bool, indices ...int) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if err := c.checkIndices(indices); err != nil {
+		return err
+	}
+	for _, x := range v {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return fmt.Errorf("invalid translation %v", v)
+		}
+	}
+	if cartesian {
+		v = c.ToFractional(v)
+	}
+	if len(indices) == 0 {
+		for i := 0; i < c.Natom; i++ {
+			indices = append(indices, i)
+		}
+	}
+	for _, i := range indices {
+		for j := 0; j < 3; j++ {
+			c.Position.Set(i, j, c.Position.At(i, j)+v[j])
+		}
+	}
+	return nil
+}
+
+// Wrap move fractional positions of atoms into [0, 1)
+func (c *Cell) Wrap() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	for i := 0; i < c.Natom; i++ {
+		for j := 0; j < 3; j++ {
+			v := c.Position.At(i, j)
+			v -= math.Floor(v)
+			if v >= 1 {
+				v = 0
+			}
+			c.Position.Set(i, j, v)
+		}
+	}
+	return nil
+}
+
+// SortAtoms reorder atoms stably by less of their current indices and
+// return order, the old index of each new atom
+func (c *Cell) SortAtoms(less func(i, j int) bool) ([]int, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	order := make([]int, c.Natom)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return less(order[a], order[b]) })
+	pos := make([][3]float64, c.Natom)
+	types := make([]int, c.Natom)
+	for k, i := range order {
+		pos[k] = rowF3(c.Position, i)
+		types[k] = c.Elem[i]
+	}
+	c.setAtoms(pos, types)
+	return order, nil
+}
+
+// SortByKey reorder atoms stably by increasing key of type and fractional
+// position
+func (c *Cell) SortByKey(key func(elem int, p [3]float64) float64) ([]int, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	keys := make([]float64, c.Natom)
+	for i := range keys {
+		keys[i] = key(c.Elem[i], rowF3(c.Position, i))
+	}
+	return c.SortAtoms(func(i, j int) bool { return keys[i] < keys[j] })
+}
+
+// SortBySpecies reorder atoms stably by increasing type
+func (c *Cell) SortBySpecies() ([]int, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	elem := append([]int(nil), c.Elem...)
+	return c.SortAtoms(func(i, j int) bool { return elem[i] < elem[j] })
+}
+
+// SortByCoordinate reorder atoms stably by increasing coordinate axis 0-2,
+// Cartesian if cartesian is true
+func (c *Cell) SortByCoordinate(axis int, cartesian bool) ([]int, error) {
+	if axis < 0 || axis > 2 {
+		return nil, fmt.Errorf("axis must be 0, 1 or 2, got %d", axis)
+	}
+	return c.SortByKey(func(_ int, p [3]float64) float64 {
+		if cartesian {
+			return c.ToCartesian(p)[axis]
+		}
+		return p[axis]
+	})
+}
@@ -0,0 +1,122 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCellValidate(t *testing.T) {
+	c := rocksalt(5.64, 11, 17)
+	if err := c.Validate(); err != nil {
+		t.Fatalf("expect valid cell, got %v", err)
+	}
+	bad := CellCopyOf(c)
+	bad.Natom = 7
+	if err := bad.Validate(); err == nil {
+		t.Errorf("expect error of Natom not matching Elem")
+	}
+	bad = CellCopyOf(c)
+	bad.Elem = append(bad.Elem, 11)
+	bad.Natom++
+	if err := bad.Validate(); err == nil {
+		t.Errorf("expect error of Natom not matching Position")
+	}
+	bad = CellCopyOf(c)
+	bad.Position.Set(0, 0, math.NaN())
+	if err := bad.Validate(); err == nil {
+		t.Errorf("expect error of NaN position")
+	}
+	bad = CellCopyOf(c)
+	bad.Lattice.Set(2, 2, 0)
+	if err := bad.Validate(); err == nil {
+		t.Errorf("expect error of singular lattice")
+	}
+	// state is kept on error
+	if err := bad.AddAtom(3, [3]float64{}, false); err == nil || bad.Natom != 8 {
+		t.Errorf("expect error and 8 atoms, got %v and %d", err, bad.Natom)
+	}
+}
+
+func TestCellAtoms(t *testing.T) {
+	a := 5.64
+	c := rocksalt(a, 11, 17)
+	if err := c.AddAtom(3, [3]float64{a / 4, a / 4, a / 4}, true); err != nil {
+		t.Fatalf("add atom error: %v", err)
+	}
+	if c.Natom != 9 || c.Elem[8] != 3 || c.Position.At(8, 0) != 0.25 {
+		t.Errorf("expect Li at 1/4, got %d atoms %v", c.Natom, c.Elem)
+	}
+	if err := c.RemoveAtoms(0, 4); err != nil {
+		t.Fatalf("remove atoms error: %v", err)
+	}
+	if c.Natom != 7 || c.Elem[3] != 17 || c.Position.At(3, 0) != 0.5 || c.Validate() != nil {
+		t.Errorf("unexpected cell after removal, %v %v", c.Elem, c.PositionSlice())
+	}
+	if err := c.RemoveAtoms(1, 1); err == nil || c.Natom != 7 {
+		t.Errorf("expect error of repeated index")
+	}
+	if err := c.ReplaceAtoms(19, 0, 1); err != nil || c.Elem[0] != 19 || c.Elem[1] != 19 {
+		t.Errorf("replace atoms fail: %v %v", err, c.Elem)
+	}
+	if err := c.ReplaceAtoms(19, 7); err == nil {
+		t.Errorf("expect error of index out of range")
+	}
+
+	if err := c.TranslateAtoms([3]float64{-a / 2, 0, 0}, true, 6); err != nil {
+		t.Fatalf("translate error: %v", err)
+	}
+	if c.Position.At(6, 0) != -0.25 || c.Position.At(5, 0) != 0 {
+		t.Errorf("expect only atom 6 moved, got %v", c.PositionSlice())
+	}
+	if err := c.Wrap(); err != nil || c.Position.At(6, 0) != 0.75 {
+		t.Errorf("expect wrapped 0.75, got %v", c.Position.At(6, 0))
+	}
+	cart := c.CartesianPositions()
+	if math.Abs(cart.At(6, 0)-0.75*a) > 1e-12 {
+		t.Errorf("expect cartesian %g, got %g", 0.75*a, cart.At(6, 0))
+	}
+	cart.Set(6, 1, a/2)
+	if err := c.SetCartesianPositions(cart); err != nil || math.Abs(c.Position.At(6, 1)-0.5) > 1e-12 {
+		t.Errorf("set cartesian positions fail: %v", err)
+	}
+
+	order, err := c.SortBySpecies()
+	if err != nil {
+		t.Fatalf("sort error: %v", err)
+	}
+	for i := 1; i < c.Natom; i++ {
+		if c.Elem[i] < c.Elem[i-1] {
+			t.Fatalf("expect sorted species, got %v", c.Elem)
+		}
+	}
+	if c.Elem[0] != 3 || order[0] != 6 {
+		t.Errorf("expect Li first from atom 6, got %v %v", c.Elem, order)
+	}
+	if _, err := c.SortByCoordinate(2, true); err != nil {
+		t.Fatalf("sort error: %v", err)
+	}
+	for i := 1; i < c.Natom; i++ {
+		if c.Position.At(i, 2) < c.Position.At(i-1, 2) {
+			t.Fatalf("expect sorted z, got %v", c.PositionSlice())
+		}
+	}
+	if _, err := c.SortByCoordinate(3, false); err == nil {
+		t.Errorf("expect error of axis")
+	}
+
+	// removing every atom would leave no positions, cell is kept
+	if err := c.RemoveAtoms(0, 1, 2, 3, 4, 5, 6); err == nil || c.Natom != 7 || c.Validate() != nil {
+		t.Errorf("expect error and 7 atoms, got %v and %d", err, c.Natom)
+	}
+	if _, err := c.SupercellMatrix([3][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 2}}); err != nil {
+		t.Errorf("supercell error: %v", err)
+	}
+	if err := c.RemoveAtoms(0, 1, 2, 3, 4, 5); err != nil || c.Natom != 1 || c.Validate() != nil {
+		t.Errorf("expect one atom left, got %v", err)
+	}
+	empty := CellCopyOf(c)
+	empty.Natom, empty.Elem, empty.Position = 0, nil, nil
+	if err := empty.Validate(); err == nil {
+		t.Errorf("expect error of cell without atoms")
+	}
+}
@@ -17,6 +17,8 @@ type Cell struct {
 	Elem []int
 	// number of atoms
 	Natom int
+	// System comment of cell, e.g. the first line of POSCAR
+	System string
 }
 
 func CellCopyOf(c *Cell) *Cell {
@@ -32,6 +34,7 @@ func CellCopyOf(c *Cell) *Cell {
 		Position: pos,
 		Elem:     elem,
 		Natom:    n,
+		System:   c.System,
 	}
   return r
 }
@@ -81,50 +84,141 @@ func (c *Cell) PositionSlice() []float64 {
 	return matToSlice(c.Position)
 }
 
-// Primitive return primitive cell
-func (c *Cell) Primitive(symprec float64) {
-	lattice := c.LatticeSlice()
-	position := c.PositionSlice()
-	elem := c.Elem
-	n := c.Natom
-	newLattice, newPosition, newElem :=
-		spgolib.Standardize(lattice, position, elem, n, true, true, symprec)
-	c.Lattice = mat.NewDense(3, 3, newLattice)
-	c.Position = mat.NewDense(len(newElem), 3, newPosition)
-	c.Elem = newElem
-	c.Natom = len(newElem)
+// Transform relate cell to its standardized cell
+type Transform struct {
+	// Matrix M and Shift s map fraction coordinate x of original cell to
+	// x' = M x + s of new cell, new lattice column vectors are
+	// (a b c) M^-1 up to a rotation
+	Matrix *mat.Dense
+	Shift  *mat.VecDense
+	// Mapping is index of atom of new cell at position of each atom of
+	// original cell
+	Mapping []int
 }
 
-// Refine return conventional cell
-func (c *Cell) Refine(symprec float64) {
-	lattice := c.LatticeSlice()
-	position := c.PositionSlice()
-	elem := c.Elem
-	n := c.Natom
-	newLattice, newPosition, newElem :=
-		spgolib.Standardize(lattice, position, elem, n, false, false, symprec)
-	c.Lattice = mat.NewDense(3, 3, newLattice)
-	c.Position = mat.NewDense(len(newElem), 3, newPosition)
-	c.Elem = newElem
-	c.Natom = len(newElem)
+// Primitive return standardized primitive cell, c is not changed
+func (c *Cell) Primitive(symprec float64) (*Cell, *Transform, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := c.standardize(true, true, symprec)
+	if err != nil {
+		return nil, nil, err
+	}
+	// lattice is not idealized, so x' = (L L'^-1)^T x + M P^-1 p with P and
+	// p transforming to conventional cell
+	var inv, lm mat.Dense
+	if err := inv.Inverse(p.Lattice); err != nil {
+		return nil, nil, fmt.Errorf("primitive: %v", err)
+	}
+	lm.Mul(c.Lattice, &inv)
+	m := mat.DenseCopyOf(lm.T())
+	var pinv mat.Dense
+	if err := pinv.Inverse(ds.Transformation); err != nil {
+		return nil, nil, fmt.Errorf("primitive: %v", err)
+	}
+	var mp mat.Dense
+	mp.Mul(m, &pinv)
+	var shift mat.VecDense
+	shift.MulVec(&mp, ds.OriginShift)
+	tr, err := c.transform(p, m, &shift, symprec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, tr, nil
 }
 
-func (c *Cell) Spacegroup(symprec float64) string {
-  ds := spgolib.NewDataset(c.LatticeSlice(), c.PositionSlice(), c.Elem, symprec)
-	return fmt.Sprintf("%s (%d)", ds.SpaceSymbol, ds.SpaceNumber)
+// Refine return standardized conventional cell with idealized lattice and
+// positions, c is not changed
+func (c *Cell) Refine(symprec float64) (*Cell, *Transform, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := c.standardize(false, false, symprec)
+	if err != nil {
+		return nil, nil, err
+	}
+	tr, err := c.transform(r, ds.Transformation, ds.OriginShift, symprec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, tr, nil
+}
+
+// standardize call spglib standardization
+func (c *Cell) standardize(toPrimitive, noIdealize bool, symprec float64) (*Cell, error) {
+	lattice, position, elem := spgolib.Standardize(
+		c.LatticeSlice(), c.PositionSlice(), c.Elem, c.Natom, toPrimitive, noIdealize, symprec)
+	if len(elem) == 0 || len(lattice) != 9 || len(position) != 3*len(elem) {
+		return nil, fmt.Errorf("spglib: standardization failed with symprec %g", symprec)
+	}
+	// spglib may return its own buffers, do not share them with c
+	s, err := NewCell(
+		append([]float64(nil), lattice...),
+		append([]float64(nil), position...),
+		append([]int(nil), elem...),
+		false,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("spglib: standardization failed, %v", err)
+	}
+	s.System = c.System
+	return s, nil
+}
+
+// transform find atom of s at M x + shift of each atom of c
+func (c *Cell) transform(s *Cell, m *mat.Dense, shift *mat.VecDense, symprec float64) (*Transform, error) {
+	// standardized positions may be idealized
+	tol := math.Max(10*symprec, 1e-3)
+	mapping := make([]int, c.Natom)
+	x := mat.NewVecDense(3, nil)
+	var xs mat.VecDense
+	for i := 0; i < c.Natom; i++ {
+		x.SetVec(0, c.Position.At(i, 0))
+		x.SetVec(1, c.Position.At(i, 1))
+		x.SetVec(2, c.Position.At(i, 2))
+		xs.MulVec(m, x)
+		xs.AddVec(&xs, shift)
+		mapping[i] = -1
+		best := tol
+		for j := 0; j < s.Natom; j++ {
+			if s.Elem[j] != c.Elem[i] {
+				continue
+			}
+			var d [3]float64
+			for k := 0; k < 3; k++ {
+				d[k] = s.Position.At(j, k) - xs.AtVec(k)
+				d[k] -= math.Floor(d[k] + 0.5)
+			}
+			n, _ := s.neighbor(j, [3]int{}, d, math.Inf(1))
+			if n.Distance < best {
+				best = n.Distance
+				mapping[i] = j
+			}
+		}
+		if mapping[i] < 0 {
+			return nil, fmt.Errorf("standardized cell has no atom at position of atom %d", i)
+		}
+	}
+	return &Transform{Matrix: mat.DenseCopyOf(m), Shift: mat.VecDenseCopyOf(shift), Mapping: mapping}, nil
+}
+
+// Spacegroup return space group symbol and number, e.g. "P6/mmm (191)"
+func (c *Cell) Spacegroup(symprec float64) (string, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%d)", ds.SpaceSymbol, ds.SpaceNumber), nil
 }
 
 // Symmetry find rotation and translation of cell
 func (c *Cell) Symmetry(symprec float64) (nop int, rotations []Rotation, transitions []Translation) {
   ds := spgolib.NewDataset(c.LatticeSlice(), c.PositionSlice(), c.Elem, symprec)
-  nop = ds.Nops
-  rots := make([]Rotation, nop, nop)
-  trans := make([]Translation, nop, nop)
-  for i:=0; i<nop; i++ {
-    rots[i].data = mat.NewDense(3, 3, intToFloat(ds.Rotations[i*9:i*9+9]))
-    trans[i].data = mat.NewVecDense(3, ds.Translations[i*3:i*3+3])
-  }
-  return nop, rots, trans
+  rots, trans := operations(ds)
+  return ds.Nops, rots, trans
 }
 
 func (c *Cell) String() string {
@@ -59,7 +59,15 @@ func TestPrimitive(t *testing.T) {
 		false,
 	)
 
-  c.Primitive(1e-5)
+  orig := CellCopyOf(c)
+  p, tr, err := c.Primitive(1e-5)
+  if err != nil {
+    t.Fatalf("primitive error: %v", err)
+  }
+  if !mat.Equal(c.Lattice, orig.Lattice) || !mat.Equal(c.Position, orig.Position) || c.Natom != 2 {
+    t.Errorf("primitive changed original cell: %v", c)
+  }
+  c = p
 	expected, _ := NewCell(
 		[]float64{-2, 2, 2, 2, -2, 2, 2, 2, -2},
 		[]float64{0, 0, 0},
@@ -77,6 +85,9 @@ func TestPrimitive(t *testing.T) {
   		t.Errorf("elem: primitive expected %v, got %v.", expected, c)
   	}
   }
+  if len(tr.Mapping) != 2 || tr.Mapping[0] != 0 || tr.Mapping[1] != 0 {
+    t.Errorf("mapping: primitive expected [0 0], got %v", tr.Mapping)
+  }
 }
 
 func TestRefine(t *testing.T) {
@@ -86,7 +97,14 @@ func TestRefine(t *testing.T) {
 		[]int{1},
 		false,
 	)
-  c.Refine(1e-5)
+  r, tr, err := c.Refine(1e-5)
+  if err != nil {
+    t.Fatalf("refine error: %v", err)
+  }
+  if c.Natom != 1 {
+    t.Errorf("refine changed original cell: %v", c)
+  }
+  c = r
 
 	expected, _ := NewCell(
 		[]float64{4, 0, 0, 0, 4, 0, 0, 0, 4},
@@ -106,4 +124,7 @@ func TestRefine(t *testing.T) {
   		t.Errorf("elem: primitive expected %v, got %v.", expected, c)
   	}
   }
+  if len(tr.Mapping) != 1 || tr.Mapping[0] != 0 {
+    t.Errorf("mapping: refine expected [0], got %v", tr.Mapping)
+  }
 }
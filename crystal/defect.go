@@ -0,0 +1,468 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Default options of Interstitials
+const (
+	DefaultVoidSpacing     = 0.2
+	DefaultVoidMinDistance = 1.0
+)
+
+// DefectKind is kind of point defect
+type DefectKind int
+
+const (
+	Vacancy DefectKind = iota
+	Substitution
+	Interstitial
+)
+
+func (k DefectKind) String() string {
+	switch k {
+	case Vacancy:
+		return "vacancy"
+	case Substitution:
+		return "substitution"
+	case Interstitial:
+		return "interstitial"
+	}
+	return fmt.Sprintf("DefectKind(%d)", int(k))
+}
+
+// Defect is point defect in supercell of host cell
+type Defect struct {
+	Kind DefectKind
+	// Cell is supercell of host with the defect, atoms stay grouped by type
+	Cell *Cell
+	// Site is index of removed or substituted atom of host, -1 for
+	// interstitial
+	Site int
+	// Index is index of substituting or interstitial atom in Cell, -1 for
+	// vacancy
+	Index int
+	// Position is site of defect in fraction coordinate of Cell
+	Position [3]float64
+	// Elem is atomic number of substituting or interstitial atom, 0 for
+	// vacancy
+	Elem int
+	// Host is atomic number of removed or substituted atom, 0 for
+	// interstitial
+	Host int
+	// Multiplicity is number of equivalent sites in host cell
+	Multiplicity int
+	// Wyckoff letter of site in host
+	Wyckoff string
+}
+
+// Name return defect name as V_Na, Mg_Na or Li_i
+func (d *Defect) Name() string {
+	switch d.Kind {
+	case Vacancy:
+		return "V_" + NumToSym(d.Host)
+	case Substitution:
+		return NumToSym(d.Elem) + "_" + NumToSym(d.Host)
+	}
+	return NumToSym(d.Elem) + "_i"
+}
+
+// InterstitialOptions control void search of Interstitials, zero values
+// mean the defaults
+type InterstitialOptions struct {
+	// Spacing of grid in Å
+	Spacing float64
+	// MinDistance in Å from void to nearest atom
+	MinDistance float64
+}
+
+// Vacancies return a vacancy in supercell m of c for each set of
+// equivalent atoms, see SupercellMatrix
+func (c *Cell) Vacancies(m [3][3]int, symprec float64) ([]*Defect, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, err
+	}
+	return c.vacancies(ds, m)
+}
+
+// Substitutions return defects in supercell m of c replacing one atom of
+// each set of equivalent atoms of type e by each type of subs[e]
+func (c *Cell) Substitutions(m [3][3]int, subs map[int][]int, symprec float64) ([]*Defect, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, err
+	}
+	return c.substitutions(ds, m, subs)
+}
+
+// Interstitials return defects in supercell m of c adding an atom of each
+// type of elems at each symmetry distinct void. Voids are local maxima of
+// distance to nearest atom on a grid, symmetrized by operations of c.
+// opt can be nil
+func (c *Cell) Interstitials(m [3][3]int, elems []int, opt *InterstitialOptions, symprec float64) ([]*Defect, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, err
+	}
+	voids, err := c.voids(ds, opt, symprec)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := c.SupercellMatrix(m)
+	if err != nil {
+		return nil, err
+	}
+	var defects []*Defect
+	for _, v := range voids {
+		for _, e := range elems {
+			// Wyckoff letter from symmetry of host with the whole orbit of
+			// the void filled, which keeps the symmetry of host
+			h, site := c.withOrbit(e, v.orbit)
+			hds, err := h.Dataset(symprec)
+			if err != nil {
+				return nil, err
+			}
+			pos := supercellPosition(v.pos, m)
+			r, idx := sc.withAtom(e, pos)
+			defects = append(defects, &Defect{
+				Kind:         Interstitial,
+				Cell:         r,
+				Site:         -1,
+				Index:        idx,
+				Position:     pos,
+				Elem:         e,
+				Multiplicity: v.multiplicity,
+				Wyckoff:      hds.Wyckoffs[site],
+			})
+		}
+	}
+	return defects, nil
+}
+
+func (c *Cell) vacancies(ds *Dataset, m [3][3]int) ([]*Defect, error) {
+	sc, err := c.SupercellMatrix(m)
+	if err != nil {
+		return nil, err
+	}
+	det := sc.Natom / c.Natom
+	var defects []*Defect
+	for _, i := range ds.InequivalentAtoms() {
+		// images of atom i are consecutive in supercell
+		j := i * det
+		defects = append(defects, &Defect{
+			Kind:         Vacancy,
+			Cell:         sc.withoutAtom(j),
+			Site:         i,
+			Index:        -1,
+			Position:     rowF3(sc.Position, j),
+			Host:         c.Elem[i],
+			Multiplicity: ds.Multiplicity(i),
+			Wyckoff:      ds.Wyckoffs[i],
+		})
+	}
+	return defects, nil
+}
+
+func (c *Cell) substitutions(ds *Dataset, m [3][3]int, subs map[int][]int) ([]*Defect, error) {
+	sc, err := c.SupercellMatrix(m)
+	if err != nil {
+		return nil, err
+	}
+	det := sc.Natom / c.Natom
+	var defects []*Defect
+	for _, i := range ds.InequivalentAtoms() {
+		for _, e := range subs[c.Elem[i]] {
+			if e == c.Elem[i] {
+				return nil, fmt.Errorf("substitution of %s by itself", NumToSym(e))
+			}
+			j := i * det
+			pos := rowF3(sc.Position, j)
+			r, idx := sc.withoutAtom(j).withAtom(e, pos)
+			defects = append(defects, &Defect{
+				Kind:         Substitution,
+				Cell:         r,
+				Site:         i,
+				Index:        idx,
+				Position:     pos,
+				Elem:         e,
+				Host:         c.Elem[i],
+				Multiplicity: ds.Multiplicity(i),
+				Wyckoff:      ds.Wyckoffs[i],
+			})
+		}
+	}
+	return defects, nil
+}
+
+// void is symmetry distinct interstitial site of host
+type void struct {
+	pos          [3]float64
+	distance     float64
+	multiplicity int
+	// orbit of pos by operations of host, pos first
+	orbit [][3]float64
+}
+
+// voids search local maxima of distance to nearest atom on grid, move
+// each to the average of its images by site symmetry and keep one of each
+// orbit of operations of ds, in decreasing distance
+func (c *Cell) voids(ds *Dataset, opt *InterstitialOptions, symprec float64) ([]void, error) {
+	if opt == nil {
+		opt = &InterstitialOptions{}
+	}
+	spacing := opt.Spacing
+	if spacing <= 0 {
+		spacing = DefaultVoidSpacing
+	}
+	minDist := opt.MinDistance
+	if minDist <= 0 {
+		minDist = DefaultVoidMinDistance
+	}
+	if c.Natom == 0 {
+		return nil, fmt.Errorf("void search of empty cell")
+	}
+
+	var n [3]int
+	for i, l := range c.Lengths() {
+		n[i] = int(math.Ceil(l / spacing))
+	}
+	dist := make([]float64, n[0]*n[1]*n[2])
+	at := func(i, j, k int) int {
+		i, j, k = (i%n[0]+n[0])%n[0], (j%n[1]+n[1])%n[1], (k%n[2]+n[2])%n[2]
+		return i + n[0]*(j+n[1]*k)
+	}
+	for k := 0; k < n[2]; k++ {
+		for j := 0; j < n[1]; j++ {
+			for i := 0; i < n[0]; i++ {
+				p := [3]float64{float64(i) / float64(n[0]), float64(j) / float64(n[1]), float64(k) / float64(n[2])}
+				dist[at(i, j, k)] = c.nearestAtom(p)
+			}
+		}
+	}
+
+	type candidate struct {
+		pos  [3]float64
+		dist float64
+	}
+	var cands []candidate
+	for k := 0; k < n[2]; k++ {
+		for j := 0; j < n[1]; j++ {
+			for i := 0; i < n[0]; i++ {
+				d := dist[at(i, j, k)]
+				if d < minDist {
+					continue
+				}
+				isMax := true
+				for di := -1; di <= 1 && isMax; di++ {
+					for dj := -1; dj <= 1 && isMax; dj++ {
+						for dk := -1; dk <= 1 && isMax; dk++ {
+							isMax = dist[at(i+di, j+dj, k+dk)] <= d
+						}
+					}
+				}
+				if isMax {
+					p := [3]float64{float64(i) / float64(n[0]), float64(j) / float64(n[1]), float64(k) / float64(n[2])}
+					cands = append(cands, candidate{p, d})
+				}
+			}
+		}
+	}
+
+	// a flat maximum spans neighbouring grid points, symmetrize with
+	// tolerance of a few grid steps
+	tol := math.Max(2*spacing, symprec)
+	ops := ds.Operations()
+	var voids []void
+	for _, cd := range cands {
+		p := c.symmetrizeSite(cd.pos, ops, tol)
+		d := c.nearestAtom(p)
+		if d < minDist {
+			continue
+		}
+		dup := false
+		for _, v := range voids {
+			for _, op := range ops {
+				if c.fracDistance(op.Apply(v.pos), p) < tol {
+					dup = true
+					break
+				}
+			}
+			if dup {
+				break
+			}
+		}
+		if dup {
+			continue
+		}
+		orbit := [][3]float64{wrapF3(p)}
+		for _, op := range ops {
+			q := wrapF3(op.Apply(p))
+			seen := false
+			for _, o := range orbit {
+				if c.fracDistance(o, q) < symprec {
+					seen = true
+					break
+				}
+			}
+			if !seen {
+				orbit = append(orbit, q)
+			}
+		}
+		voids = append(voids, void{wrapF3(p), d, len(orbit), orbit})
+	}
+	sort.SliceStable(voids, func(i, j int) bool { return voids[i].distance > voids[j].distance })
+	return voids, nil
+}
+
+// symmetrizeSite return average of images of p within tol by operations,
+// the site fixed by its site symmetry
+func (c *Cell) symmetrizeSite(p [3]float64, ops []Operation, tol float64) [3]float64 {
+	var sum [3]float64
+	count := 0
+	for _, op := range ops {
+		q := op.Apply(p)
+		if c.fracDistance(q, p) >= tol {
+			continue
+		}
+		for d := 0; d < 3; d++ {
+			diff := q[d] - p[d]
+			sum[d] += p[d] + diff - math.Round(diff)
+		}
+		count++
+	}
+	if count == 0 {
+		return p
+	}
+	return [3]float64{sum[0] / float64(count), sum[1] / float64(count), sum[2] / float64(count)}
+}
+
+// nearestAtom return distance from fraction coordinate p to nearest atom
+func (c *Cell) nearestAtom(p [3]float64) float64 {
+	best := math.Inf(1)
+	for i := 0; i < c.Natom; i++ {
+		best = math.Min(best, c.fracDistance(p, rowF3(c.Position, i)))
+	}
+	return best
+}
+
+// fracDistance return shortest distance between periodic images of
+// fraction coordinates p and q, searching neighbouring cells
+func (c *Cell) fracDistance(p, q [3]float64) float64 {
+	var d [3]float64
+	for i := 0; i < 3; i++ {
+		d[i] = p[i] - q[i] - math.Round(p[i]-q[i])
+	}
+	best := math.Inf(1)
+	for x := -1; x <= 1; x++ {
+		for y := -1; y <= 1; y++ {
+			for z := -1; z <= 1; z++ {
+				v := [3]float64{d[0] + float64(x), d[1] + float64(y), d[2] + float64(z)}
+				var r [3]float64
+				for j := 0; j < 3; j++ {
+					r[j] = v[0]*c.Lattice.At(0, j) + v[1]*c.Lattice.At(1, j) + v[2]*c.Lattice.At(2, j)
+				}
+				best = math.Min(best, norm3(r))
+			}
+		}
+	}
+	return best
+}
+
+// withoutAtom return copy of c without atom i
+func (c *Cell) withoutAtom(i int) *Cell {
+	pos := make([]float64, 0, 3*(c.Natom-1))
+	elem := make([]int, 0, c.Natom-1)
+	for j := 0; j < c.Natom; j++ {
+		if j != i {
+			pos = append(pos, c.Position.At(j, 0), c.Position.At(j, 1), c.Position.At(j, 2))
+			elem = append(elem, c.Elem[j])
+		}
+	}
+	return &Cell{
+		Lattice:  mat.DenseCopyOf(c.Lattice),
+		Position: mat.NewDense(len(elem), 3, pos),
+		Elem:     elem,
+		Natom:    len(elem),
+		System:   c.System,
+	}
+}
+
+// withOrbit return copy of c with atoms e added at orbit and index of the
+// one at orbit[0]
+func (c *Cell) withOrbit(e int, orbit [][3]float64) (*Cell, int) {
+	h, site := c.withAtom(e, orbit[0])
+	for _, q := range orbit[1:] {
+		// later atoms go after the first one, site is kept
+		h, _ = h.withAtom(e, q)
+	}
+	return h, site
+}
+
+// withAtom return copy of c with atom e added at p after the last atom of
+// the same type, or at the end, and its index
+func (c *Cell) withAtom(e int, p [3]float64) (*Cell, int) {
+	idx := c.Natom
+	for j := 0; j < c.Natom; j++ {
+		if c.Elem[j] == e {
+			idx = j + 1
+		}
+	}
+	pos := make([]float64, 0, 3*(c.Natom+1))
+	elem := make([]int, 0, c.Natom+1)
+	for j := 0; j <= c.Natom; j++ {
+		if j == idx {
+			pos = append(pos, p[:]...)
+			elem = append(elem, e)
+		}
+		if j < c.Natom {
+			pos = append(pos, c.Position.At(j, 0), c.Position.At(j, 1), c.Position.At(j, 2))
+			elem = append(elem, c.Elem[j])
+		}
+	}
+	return &Cell{
+		Lattice:  mat.DenseCopyOf(c.Lattice),
+		Position: mat.NewDense(len(elem), 3, pos),
+		Elem:     elem,
+		Natom:    len(elem),
+		System:   c.System,
+	}, idx
+}
+
+// supercellPosition return fraction coordinate p of cell in supercell m,
+// wrapped into [0, 1)
+func supercellPosition(p [3]float64, m [3][3]int) [3]float64 {
+	tm := mat.NewDense(3, 3, nil)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			tm.Set(i, j, float64(m[i][j]))
+		}
+	}
+	var inv mat.Dense
+	inv.Inverse(tm)
+	var q [3]float64
+	for j := 0; j < 3; j++ {
+		for i := 0; i < 3; i++ {
+			q[j] += p[i] * inv.At(i, j)
+		}
+	}
+	return wrapF3(q)
+}
+
+func rowF3(m *mat.Dense, i int) [3]float64 {
+	return [3]float64{m.At(i, 0), m.At(i, 1), m.At(i, 2)}
+}
+
+func wrapF3(p [3]float64) [3]float64 {
+	for i := range p {
+		p[i] -= math.Floor(p[i])
+		if p[i] >= 1-1e-12 {
+			p[i] = 0
+		}
+	}
+	return p
+}
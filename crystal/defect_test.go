@@ -0,0 +1,134 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+)
+
+// fccDataset return dataset of Fm-3m in conventional cell with atoms of
+// equivalent sets and Wyckoff letters
+func fccDataset(equivalent []int, wyckoffs []string) *Dataset {
+	centring := [][3]float64{{0, 0, 0}, {0, 0.5, 0.5}, {0.5, 0, 0.5}, {0.5, 0.5, 0}}
+	ds := &Dataset{SpaceNumber: 225, SpaceSymbol: "Fm-3m", EquivalentAtoms: equivalent, Wyckoffs: wyckoffs}
+	for _, r := range rotationGroup("z,x,y", "-y,x,z", "-x,-y,-z") {
+		for _, t := range centring {
+			ds.Rotations = append(ds.Rotations, r)
+			ds.Translations = append(ds.Translations, NewTranslation(t))
+		}
+	}
+	return ds
+}
+
+func TestVacanciesSubstitutions(t *testing.T) {
+	c := rocksalt(5.64, 11, 17)
+	ds := fccDataset([]int{0, 0, 0, 0, 4, 4, 4, 4}, []string{"a", "a", "a", "a", "b", "b", "b", "b"})
+	m := [3][3]int{{2, 0, 0}, {0, 2, 0}, {0, 0, 2}}
+	vs, err := c.vacancies(ds, m)
+	if err != nil {
+		t.Fatalf("vacancy error: %v", err)
+	}
+	if len(vs) != 2 {
+		t.Fatalf("expect 2 vacancies, got %d", len(vs))
+	}
+	for i, v := range vs {
+		name := []string{"V_Na", "V_Cl"}[i]
+		if v.Name() != name || v.Cell.Natom != 63 || v.Multiplicity != 4 || v.Wyckoff != []string{"a", "b"}[i] {
+			t.Errorf("expect %s of 63 atoms, got %s of %d atoms multiplicity %d Wyckoff %s",
+				name, v.Name(), v.Cell.Natom, v.Multiplicity, v.Wyckoff)
+		}
+		for j := 0; j < v.Cell.Natom; j++ {
+			if v.Cell.fracDistance(rowF3(v.Cell.Position, j), v.Position) < 1e-8 {
+				t.Errorf("%s: atom %d left on vacant site", name, j)
+			}
+		}
+	}
+
+	ss, err := c.substitutions(ds, m, map[int][]int{11: {3, 19}})
+	if err != nil {
+		t.Fatalf("substitution error: %v", err)
+	}
+	if len(ss) != 2 || ss[0].Name() != "Li_Na" || ss[1].Name() != "K_Na" {
+		t.Fatalf("expect Li_Na and K_Na, got %v", ss)
+	}
+	for _, s := range ss {
+		count := countSpecies(s.Cell)
+		if s.Cell.Natom != 64 || count[11] != 31 || count[s.Elem] != 1 || s.Cell.Elem[s.Index] != s.Elem {
+			t.Errorf("%s: unexpected species %v", s.Name(), count)
+		}
+		if rowF3(s.Cell.Position, s.Index) != s.Position {
+			t.Errorf("%s: substituting atom not at %v", s.Name(), s.Position)
+		}
+	}
+	if _, err := c.substitutions(ds, m, map[int][]int{11: {11}}); err == nil {
+		t.Error("expect error for substitution by the same type")
+	}
+}
+
+func TestVoids(t *testing.T) {
+	a := 3.61
+	c, _ := NewCell([]float64{a, 0, 0, 0, a, 0, 0, 0, a},
+		[]float64{0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0}, []int{29, 29, 29, 29}, false)
+	ds := fccDataset([]int{0, 0, 0, 0}, []string{"a", "a", "a", "a"})
+	voids, err := c.voids(ds, nil, 1e-3)
+	if err != nil {
+		t.Fatalf("void error: %v", err)
+	}
+	// octahedral 4b and tetrahedral 8c
+	if len(voids) != 2 {
+		t.Fatalf("expect 2 voids, got %v", voids)
+	}
+	expect := []struct {
+		dist float64
+		mult int
+	}{{a / 2, 4}, {a * math.Sqrt(3) / 4, 8}}
+	for i, v := range voids {
+		if math.Abs(v.distance-expect[i].dist) > 1e-8 || v.multiplicity != expect[i].mult {
+			t.Errorf("void %d: expect distance %g multiplicity %d, got %g %d at %v",
+				i, expect[i].dist, expect[i].mult, v.distance, v.multiplicity, v.pos)
+		}
+	}
+
+	for _, v := range voids {
+		if len(v.orbit) != v.multiplicity || v.orbit[0] != v.pos {
+			t.Errorf("expect orbit of %d sites from %v, got %v", v.multiplicity, v.pos, v.orbit)
+		}
+	}
+
+	voids, _ = c.voids(ds, &InterstitialOptions{MinDistance: 1.7}, 1e-3)
+	if len(voids) != 1 {
+		t.Errorf("expect octahedral void only, got %v", voids)
+	}
+}
+
+func TestWithOrbit(t *testing.T) {
+	c := rocksalt(5.64, 11, 17)
+	orbit := [][3]float64{{0.25, 0.25, 0.25}, {0.75, 0.75, 0.25}, {0.75, 0.25, 0.75}, {0.25, 0.75, 0.75}}
+	h, site := c.withOrbit(11, orbit)
+	// Na atoms come first, the void after them and before Cl
+	if h.Natom != 12 || site != 4 || h.Elem[site] != 11 || rowF3(h.Position, site) != orbit[0] {
+		t.Fatalf("expect Na at 4 of 12 atoms, got %d of %d: %v", site, h.Natom, h.Elem)
+	}
+	for i := 4; i < 8; i++ {
+		if h.Elem[i] != 11 || h.Elem[i+4] != 17 {
+			t.Errorf("expect atoms grouped by type, got %v", h.Elem)
+		}
+	}
+}
+
+func TestInterstitials(t *testing.T) {
+	a := 3.61
+	c, _ := NewCell([]float64{a, 0, 0, 0, a, 0, 0, 0, a},
+		[]float64{0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0}, []int{29, 29, 29, 29}, false)
+	ds, err := c.Interstitials([3][3]int{{2, 0, 0}, {0, 2, 0}, {0, 0, 2}}, []int{1}, nil, 1e-3)
+	if err != nil {
+		t.Fatalf("interstitial error: %v", err)
+	}
+	if len(ds) != 2 || ds[0].Wyckoff != "b" || ds[1].Wyckoff != "c" {
+		t.Fatalf("expect H_i at 4b and 8c, got %v", ds)
+	}
+	for _, d := range ds {
+		if d.Name() != "H_i" || d.Cell.Natom != 33 || d.Cell.Elem[d.Index] != 1 {
+			t.Errorf("expect H_i in 33 atoms, got %s in %d", d.Name(), d.Cell.Natom)
+		}
+	}
+}
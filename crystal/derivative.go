@@ -0,0 +1,423 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Decoration is composition of sites of parent cell shared by several
+// species, e.g. an alloy or a site of partial occupancy
+type Decoration struct {
+	// Sites are indices of atoms of parent to decorate, nil means all
+	Sites []int
+	// Species are atomic numbers on the sites with Concentrations summing
+	// to 1
+	Species        []int
+	Concentrations []float64
+}
+
+// Derivative is superstructure of parent cell with decorated sites
+type Derivative struct {
+	// Cell is supercell with atoms grouped by type
+	Cell *Cell
+	// Matrix is Hermite normal form H of the supercell, lattice rows
+	// H·Lattice of parent
+	Matrix [3][3]int
+	// Size is number of parent cells in the supercell
+	Size int
+	// Degeneracy is number of decorations of the supercell equivalent to
+	// this one
+	Degeneracy int
+}
+
+// Derivatives enumerate symmetry inequivalent decorations of supercells of
+// c with minSize to maxSize parent cells, after Hart and Forcade, Phys.
+// Rev. B 77, 224115 (2008). Sizes at which the composition is not
+// realized are skipped, as are decorations which are periodic in a smaller
+// supercell, so each structure is found once at its smallest size
+func (c *Cell) Derivatives(d *Decoration, minSize, maxSize int, symprec float64) ([]*Derivative, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, err
+	}
+	return c.derivatives(d, minSize, maxSize, ds.Operations())
+}
+
+func (c *Cell) derivatives(d *Decoration, minSize, maxSize int, ops []Operation) ([]*Derivative, error) {
+	sites, err := d.sites(c)
+	if err != nil {
+		return nil, err
+	}
+	if minSize < 1 || maxSize < minSize {
+		return nil, fmt.Errorf("invalid supercell sizes %d to %d", minSize, maxSize)
+	}
+	if len(ops) == 0 {
+		ops = []Operation{{NewRotation([3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}), NewTranslation([3]float64{})}}
+	}
+	var ders []*Derivative
+	for n := minSize; n <= maxSize; n++ {
+		counts, err := d.counts(n * len(sites))
+		if err != nil {
+			continue
+		}
+		for _, h := range uniqueHNFs(n, ops) {
+			sc, err := c.SupercellMatrix(h)
+			if err != nil {
+				return nil, err
+			}
+			// images of each parent atom are consecutive in supercell
+			var decorated []int
+			for _, s := range sites {
+				for k := 0; k < n; k++ {
+					decorated = append(decorated, s*n+k)
+				}
+			}
+			group, shifts, err := sc.sitePermutations(h, decorated, ops)
+			if err != nil {
+				return nil, err
+			}
+			for _, col := range colorings(counts) {
+				if superperiodic(col, shifts) {
+					continue
+				}
+				deg, ok := canonical(col, group)
+				if !ok {
+					continue
+				}
+				r := CellCopyOf(sc)
+				for i, j := range decorated {
+					r.Elem[j] = d.Species[col[i]]
+				}
+				ders = append(ders, &Derivative{Cell: groupTypes(r), Matrix: h, Size: n, Degeneracy: deg})
+			}
+		}
+	}
+	return ders, nil
+}
+
+// sites return decorated atoms of c
+func (d *Decoration) sites(c *Cell) ([]int, error) {
+	if len(d.Species) == 0 || len(d.Species) != len(d.Concentrations) {
+		return nil, fmt.Errorf("expect one concentration per species, got %d for %d", len(d.Concentrations), len(d.Species))
+	}
+	var sum float64
+	for _, x := range d.Concentrations {
+		if x < 0 {
+			return nil, fmt.Errorf("negative concentration %g", x)
+		}
+		sum += x
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		return nil, fmt.Errorf("concentrations sum to %g, expect 1", sum)
+	}
+	if d.Sites == nil {
+		sites := make([]int, c.Natom)
+		for i := range sites {
+			sites[i] = i
+		}
+		return sites, nil
+	}
+	seen := make(map[int]bool)
+	for _, s := range d.Sites {
+		if s < 0 || s >= c.Natom || seen[s] {
+			return nil, fmt.Errorf("invalid or repeated site %d of %d atoms", s, c.Natom)
+		}
+		seen[s] = true
+	}
+	sites := append([]int(nil), d.Sites...)
+	sort.Ints(sites)
+	return sites, nil
+}
+
+// counts return number of sites of each species out of n, error if the
+// concentrations are not realized by n sites
+func (d *Decoration) counts(n int) ([]int, error) {
+	counts := make([]int, len(d.Species))
+	total := 0
+	for i, x := range d.Concentrations {
+		v := x * float64(n)
+		counts[i] = int(math.Round(v))
+		if math.Abs(v-float64(counts[i])) > 1e-6 {
+			return nil, fmt.Errorf("concentration %g not realized by %d sites", x, n)
+		}
+		total += counts[i]
+	}
+	if total != n {
+		return nil, fmt.Errorf("concentrations not realized by %d sites", n)
+	}
+	return counts, nil
+}
+
+// hnfs return lower triangular Hermite normal forms of determinant n, rows
+// are lattice vectors so entries below the diagonal are reduced modulo the
+// diagonal entry of their column
+func hnfs(n int) [][3][3]int {
+	var hs [][3][3]int
+	for a := 1; a <= n; a++ {
+		if n%a != 0 {
+			continue
+		}
+		for c := 1; c <= n/a; c++ {
+			if (n/a)%c != 0 {
+				continue
+			}
+			f := n / a / c
+			for b := 0; b < a; b++ {
+				for d := 0; d < a; d++ {
+					for e := 0; e < c; e++ {
+						hs = append(hs, [3][3]int{{a, 0, 0}, {b, c, 0}, {d, e, f}})
+					}
+				}
+			}
+		}
+	}
+	return hs
+}
+
+// uniqueHNFs return Hermite normal forms of determinant n of superlattices
+// inequivalent by rotations of ops
+func uniqueHNFs(n int, ops []Operation) [][3][3]int {
+	var rts [][3][3]int
+	seen := make(map[[3][3]int]bool)
+	for _, op := range ops {
+		r := op.Rotation.ints()
+		if !seen[r] {
+			seen[r] = true
+			rts = append(rts, transposeInt3(r))
+		}
+	}
+	var unique [][3][3]int
+	for _, h := range hnfs(n) {
+		dup := false
+		for _, u := range unique {
+			adj := adjugateInt3(u)
+			for _, rt := range rts {
+				// rotated lattice H·R^T is U·u for unimodular U
+				if divisible(mulInt3(mulInt3(h, rt), adj), n) {
+					dup = true
+					break
+				}
+			}
+			if dup {
+				break
+			}
+		}
+		if !dup {
+			unique = append(unique, h)
+		}
+	}
+	return unique
+}
+
+// sitePermutations return permutations of decorated atoms of supercell c
+// of parent matrix h by ops which keep the superlattice, combined with
+// lattice translations of parent, and the permutations by translations
+// alone. group[g][i] is image of decorated atom i
+func (c *Cell) sitePermutations(h [3][3]int, decorated []int, ops []Operation) (group, shifts [][]int, err error) {
+	n := detInt3(h)
+	adj := adjugateInt3(h)
+	find := func(p [3]float64) (int, bool) {
+		for i, j := range decorated {
+			same := true
+			for d := 0; d < 3 && same; d++ {
+				diff := p[d] - c.Position.At(j, d)
+				same = math.Abs(diff-math.Round(diff)) < 1e-3
+			}
+			if same {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	// parent fraction coordinate x·H of supercell coordinate x and back
+	toParent := func(x [3]float64) [3]float64 {
+		var p [3]float64
+		for j := 0; j < 3; j++ {
+			for i := 0; i < 3; i++ {
+				p[j] += x[i] * float64(h[i][j])
+			}
+		}
+		return p
+	}
+	toSuper := func(p [3]float64) [3]float64 {
+		var x [3]float64
+		for j := 0; j < 3; j++ {
+			for i := 0; i < 3; i++ {
+				x[j] += p[i] * float64(adj[i][j]) / float64(n)
+			}
+		}
+		return x
+	}
+
+	// lattice translations of parent are images of any atom
+	for k := 0; k < n; k++ {
+		perm := make([]int, len(decorated))
+		for i, j := range decorated {
+			var p [3]float64
+			for d := 0; d < 3; d++ {
+				p[d] = c.Position.At(j, d) + c.Position.At(k, d) - c.Position.At(0, d)
+			}
+			t, ok := find(p)
+			if !ok {
+				return nil, nil, fmt.Errorf("supercell %v: translation of atom %d not found", h, j)
+			}
+			perm[i] = t
+		}
+		shifts = append(shifts, perm)
+	}
+
+	seen := make(map[string]bool)
+	for _, op := range ops {
+		rt := transposeInt3(op.Rotation.ints())
+		if !divisible(mulInt3(mulInt3(h, rt), adj), n) {
+			continue
+		}
+		perm := make([]int, len(decorated))
+		for i, j := range decorated {
+			x := [3]float64{c.Position.At(j, 0), c.Position.At(j, 1), c.Position.At(j, 2)}
+			t, ok := find(toSuper(op.Apply(toParent(x))))
+			if !ok {
+				return nil, nil, fmt.Errorf("decorated sites are not closed under operation %s", op.XYZ())
+			}
+			perm[i] = t
+		}
+		for _, s := range shifts {
+			g := make([]int, len(perm))
+			for i := range perm {
+				g[i] = s[perm[i]]
+			}
+			if key := fmt.Sprint(g); !seen[key] {
+				seen[key] = true
+				group = append(group, g)
+			}
+		}
+	}
+	return group, shifts, nil
+}
+
+// colorings return sequences of species indices with counts of each
+// species in lexicographic order
+func colorings(counts []int) [][]int {
+	n := 0
+	for _, k := range counts {
+		n += k
+	}
+	left := append([]int(nil), counts...)
+	cur := make([]int, n)
+	var cols [][]int
+	var next func(i int)
+	next = func(i int) {
+		if i == n {
+			cols = append(cols, append([]int(nil), cur...))
+			return
+		}
+		for s := range left {
+			if left[s] > 0 {
+				left[s]--
+				cur[i] = s
+				next(i + 1)
+				left[s]++
+			}
+		}
+	}
+	next(0)
+	return cols
+}
+
+// canonical return number of distinct images of col by group, and whether
+// col is lexicographically smallest of them
+func canonical(col []int, group [][]int) (int, bool) {
+	images := make(map[string]bool)
+	img := make([]int, len(col))
+	for _, g := range group {
+		for i, t := range g {
+			img[t] = col[i]
+		}
+		for i := range img {
+			if img[i] != col[i] {
+				if img[i] < col[i] {
+					return 0, false
+				}
+				break
+			}
+		}
+		images[fmt.Sprint(img)] = true
+	}
+	return len(images), true
+}
+
+// superperiodic return true if a translation other than identity keeps col
+func superperiodic(col []int, shifts [][]int) bool {
+	for _, s := range shifts {
+		identity, same := true, true
+		for i, t := range s {
+			if t != i {
+				identity = false
+			}
+			if col[t] != col[i] {
+				same = false
+			}
+		}
+		if !identity && same {
+			return true
+		}
+	}
+	return false
+}
+
+// groupTypes return c with atoms stably sorted by type in order of first
+// appearance
+func groupTypes(c *Cell) *Cell {
+	order := make(map[int]int)
+	idx := make([]int, c.Natom)
+	for i, e := range c.Elem {
+		if _, ok := order[e]; !ok {
+			order[e] = len(order)
+		}
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return order[c.Elem[idx[i]]] < order[c.Elem[idx[j]]] })
+	r := CellCopyOf(c)
+	for i, j := range idx {
+		r.Elem[i] = c.Elem[j]
+		for d := 0; d < 3; d++ {
+			r.Position.Set(i, d, c.Position.At(j, d))
+		}
+	}
+	return r
+}
+
+func transposeInt3(m [3][3]int) [3][3]int {
+	var t [3][3]int
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			t[i][j] = m[j][i]
+		}
+	}
+	return t
+}
+
+// adjugateInt3 return adjugate of m, m·adj(m) = det(m)·I
+func adjugateInt3(m [3][3]int) [3][3]int {
+	var a [3][3]int
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			i1, i2 := (j+1)%3, (j+2)%3
+			j1, j2 := (i+1)%3, (i+2)%3
+			a[i][j] = m[i1][j1]*m[i2][j2] - m[i1][j2]*m[i2][j1]
+		}
+	}
+	return a
+}
+
+func divisible(m [3][3]int, n int) bool {
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if m[i][j]%n != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
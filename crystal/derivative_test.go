@@ -0,0 +1,113 @@
+package crystal
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// cubicOps return rotations of m-3m in basis whose fraction coordinates
+// are x_p with cubic coordinates p^T·x_p, without translations
+func cubicOps(p [3][3]float64) []Operation {
+	pt := mat.NewDense(3, 3, nil)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			pt.Set(i, j, p[j][i])
+		}
+	}
+	var inv mat.Dense
+	inv.Inverse(pt)
+	var ops []Operation
+	for _, r := range rotationGroup("z,x,y", "-y,x,z", "-x,-y,-z") {
+		m := r.Matrix()
+		var w, rp mat.Dense
+		w.Mul(&inv, mat.NewDense(3, 3, []float64{
+			m[0][0], m[0][1], m[0][2], m[1][0], m[1][1], m[1][2], m[2][0], m[2][1], m[2][2]}))
+		rp.Mul(&w, pt)
+		var q [3][3]float64
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				q[i][j] = rp.At(i, j)
+			}
+		}
+		ops = append(ops, Operation{NewRotation(q), NewTranslation([3]float64{})})
+	}
+	return ops
+}
+
+func TestHNFs(t *testing.T) {
+	// number of sublattices of index n, sum of d·e over n = d·e·f
+	for n, expect := range map[int]int{1: 1, 2: 7, 3: 13, 4: 35} {
+		if got := len(hnfs(n)); got != expect {
+			t.Errorf("expect %d HNFs of determinant %d, got %d", expect, n, got)
+		}
+	}
+	ops := cubicOps([3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}})
+	for n, expect := range map[int]int{2: 3, 3: 3, 4: 9} {
+		if got := len(uniqueHNFs(n, ops)); got != expect {
+			t.Errorf("expect %d simple cubic superlattices of size %d, got %d", expect, n, got)
+		}
+	}
+}
+
+func TestDerivatives(t *testing.T) {
+	sc, _ := NewCell([]float64{3, 0, 0, 0, 3, 0, 0, 0, 3}, []float64{0, 0, 0}, []int{29}, false)
+	fcc, _ := NewCell([]float64{0, 1.8, 1.8, 1.8, 0, 1.8, 1.8, 1.8, 0}, []float64{0, 0, 0}, []int{29}, false)
+	scOps := cubicOps([3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}})
+	fccOps := cubicOps([3][3]float64{{0, 0.5, 0.5}, {0.5, 0, 0.5}, {0.5, 0.5, 0}})
+	cases := []struct {
+		name   string
+		parent *Cell
+		ops    []Operation
+		conc   []float64
+		min    int
+		max    int
+		expect int
+	}{
+		// stacking along 100, 110 and 111
+		{"sc 1:1", sc, scOps, []float64{0.5, 0.5}, 1, 2, 3},
+		{"sc 1:2", sc, scOps, []float64{1.0 / 3, 2.0 / 3}, 1, 3, 3},
+		// L1_0 and L1_1
+		{"fcc 1:1", fcc, fccOps, []float64{0.5, 0.5}, 1, 2, 2},
+		{"fcc 1:2", fcc, fccOps, []float64{1.0 / 3, 2.0 / 3}, 3, 3, 3},
+		// L1_2, D0_22 and superlattice of 100
+		{"fcc 1:3", fcc, fccOps, []float64{0.25, 0.75}, 1, 4, 7},
+	}
+	for _, c := range cases {
+		ders, err := c.parent.derivatives(&Decoration{Species: []int{29, 79}, Concentrations: c.conc}, c.min, c.max, c.ops)
+		if err != nil {
+			t.Fatalf("%s: derivative error: %v", c.name, err)
+		}
+		if len(ders) != c.expect {
+			t.Errorf("%s: expect %d structures, got %d", c.name, c.expect, len(ders))
+		}
+		for _, d := range ders {
+			count := countSpecies(d.Cell)
+			if float64(count[29]) != c.conc[0]*float64(d.Cell.Natom) || detInt3(d.Matrix) != d.Size {
+				t.Errorf("%s: unexpected composition %v of size %d", c.name, count, d.Size)
+			}
+			for i := 1; i < d.Cell.Natom; i++ {
+				if d.Cell.Elem[i] != d.Cell.Elem[i-1] && d.Cell.Elem[i] == d.Cell.Elem[0] {
+					t.Errorf("%s: expect atoms grouped by type, got %v", c.name, d.Cell.Elem)
+					break
+				}
+			}
+		}
+	}
+
+	// degeneracies of decorations of a supercell add up to all decorations
+	ders, _ := fcc.derivatives(&Decoration{Species: []int{29, 79}, Concentrations: []float64{0.25, 0.75}}, 4, 4, fccOps)
+	total := make(map[[3][3]int]int)
+	for _, d := range ders {
+		total[d.Matrix] += d.Degeneracy
+	}
+	for h, n := range total {
+		if n > 4 {
+			t.Errorf("supercell %v: expect at most 4 decorations, got %d", h, n)
+		}
+	}
+
+	if _, err := sc.derivatives(&Decoration{Species: []int{29, 79}, Concentrations: []float64{0.5, 0.6}}, 1, 2, scOps); err == nil {
+		t.Error("expect error for concentrations not summing to 1")
+	}
+}
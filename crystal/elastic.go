@@ -0,0 +1,505 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DefaultElasticMagnitudes are magnitudes of strain patterns of
+// ElasticStrains
+var DefaultElasticMagnitudes = []float64{-0.01, -0.005, 0.005, 0.01}
+
+// Strain is symmetric strain tensor in Cartesian axes
+type Strain [3][3]float64
+
+// voigtIndex map Voigt index 0-5 to tensor indices
+var voigtIndex = [6][2]int{{0, 0}, {1, 1}, {2, 2}, {1, 2}, {0, 2}, {0, 1}}
+
+// StrainFromVoigt return strain of Voigt vector e1..e6, whose shear
+// components e4..e6 are engineering shear strains, twice the tensor ones
+func StrainFromVoigt(v [6]float64) Strain {
+	var s Strain
+	for k, ij := range voigtIndex {
+		f := v[k]
+		if k >= 3 {
+			f /= 2
+		}
+		s[ij[0]][ij[1]] = f
+		s[ij[1]][ij[0]] = f
+	}
+	return s
+}
+
+// Voigt return Voigt vector of strain with engineering shear strains
+func (s Strain) Voigt() [6]float64 {
+	var v [6]float64
+	for k, ij := range voigtIndex {
+		v[k] = s[ij[0]][ij[1]]
+		if k >= 3 {
+			v[k] = s[ij[0]][ij[1]] + s[ij[1]][ij[0]]
+		}
+	}
+	return v
+}
+
+func (s Strain) check() error {
+	for i := 0; i < 3; i++ {
+		for j := i + 1; j < 3; j++ {
+			if math.Abs(s[i][j]-s[j][i]) > 1e-12 {
+				return fmt.Errorf("strain must be symmetric, got %v", s)
+			}
+		}
+	}
+	return nil
+}
+
+// Deform return cell of lattice deformed by gradient f, x' = f·x for
+// Cartesian column vectors, fractional positions are kept
+func (c *Cell) Deform(f [3][3]float64) (*Cell, error) {
+	if det3(f) <= 0 {
+		return nil, fmt.Errorf("deformation must have positive determinant, got %v", f)
+	}
+	r := CellCopyOf(c)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			var v float64
+			for k := 0; k < 3; k++ {
+				v += c.Lattice.At(i, k) * f[j][k]
+			}
+			r.Lattice.Set(i, j, v)
+		}
+	}
+	return r, nil
+}
+
+// ApplyStrain return cell deformed by engineering (infinitesimal) strain
+// e, of gradient I + e
+func (c *Cell) ApplyStrain(e Strain) (*Cell, error) {
+	if err := e.check(); err != nil {
+		return nil, err
+	}
+	f := e
+	for i := 0; i < 3; i++ {
+		f[i][i]++
+	}
+	return c.Deform(f)
+}
+
+// ApplyLagrangianStrain return cell deformed by Lagrangian (Green) strain
+// eta, of symmetric gradient F with FᵀF = I + 2·eta
+func (c *Cell) ApplyLagrangianStrain(eta Strain) (*Cell, error) {
+	if err := eta.check(); err != nil {
+		return nil, err
+	}
+	m := mat.NewSymDense(3, nil)
+	for i := 0; i < 3; i++ {
+		for j := i; j < 3; j++ {
+			v := 2 * eta[i][j]
+			if i == j {
+				v++
+			}
+			m.SetSym(i, j, v)
+		}
+	}
+	var eig mat.EigenSym
+	if !eig.Factorize(m, true) {
+		return nil, fmt.Errorf("eigen decomposition of lagrangian strain failed")
+	}
+	vals := eig.Values(nil)
+	var vecs mat.Dense
+	eig.VectorsTo(&vecs)
+	var f [3][3]float64
+	for k, v := range vals {
+		if v <= 0 {
+			return nil, fmt.Errorf("I + 2η must be positive definite, got eigenvalue %g", v)
+		}
+		s := math.Sqrt(v)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				f[i][j] += s * vecs.At(i, k) * vecs.At(j, k)
+			}
+		}
+	}
+	return c.Deform(f)
+}
+
+// StressFromVASP convert stress of VASP in kBar, positive under
+// compression, to GPa, positive under tension as expected by FitElastic
+func StressFromVASP(s [3][3]float64) [3][3]float64 {
+	var r [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[i][j] = -0.1 * s[i][j]
+		}
+	}
+	return r
+}
+
+// elasticEntry is Voigt entry ij of stiffness with coefficient of a
+// parameter
+type elasticEntry struct {
+	i, j int
+	coef float64
+}
+
+// elasticClasses give independent elastic constants of each Laue class as
+// lists of entries, in the standard orientation, and strain patterns in
+// Voigt notation of Le Page and Saxe, Phys. Rev. B 65, 104104 (2002)
+var elasticClasses = map[string]struct {
+	params   [][]elasticEntry
+	patterns [][6]float64
+}{
+	"m-3m": {cubicElastic, [][6]float64{{1, 0, 0, 1, 0, 0}}},
+	"m-3":  {cubicElastic, [][6]float64{{1, 0, 0, 1, 0, 0}}},
+	"6/mmm": {hexagonalElastic, [][6]float64{
+		{1, 0, 0, 0, 0, 0}, {0, 0, 1, 1, 0, 0},
+	}},
+	"6/m": {hexagonalElastic, [][6]float64{
+		{1, 0, 0, 0, 0, 0}, {0, 0, 1, 1, 0, 0},
+	}},
+	"-3m": {append(hexagonalElastic[:len(hexagonalElastic):len(hexagonalElastic)],
+		[]elasticEntry{{0, 3, 1}, {1, 3, -1}, {4, 5, 1}},
+	), [][6]float64{
+		{1, 0, 0, 0, 0, 0}, {0, 0, 1, 1, 0, 0},
+	}},
+	"-3": {append(hexagonalElastic[:len(hexagonalElastic):len(hexagonalElastic)],
+		[]elasticEntry{{0, 3, 1}, {1, 3, -1}, {4, 5, 1}},
+		[]elasticEntry{{0, 4, 1}, {1, 4, -1}, {3, 5, -1}},
+	), [][6]float64{
+		{1, 0, 0, 0, 0, 0}, {0, 0, 1, 1, 0, 0},
+	}},
+	"4/mmm": {tetragonalElastic, [][6]float64{
+		{1, 0, 0, 0, 0, 0}, {0, 0, 1, 1, 0, 0}, {0, 0, 0, 0, 0, 1},
+	}},
+	"4/m": {append(tetragonalElastic[:len(tetragonalElastic):len(tetragonalElastic)],
+		[]elasticEntry{{0, 5, 1}, {1, 5, -1}},
+	), [][6]float64{
+		{1, 0, 0, 0, 0, 0}, {0, 0, 1, 1, 0, 0}, {0, 0, 0, 0, 0, 1},
+	}},
+	"mmm": {orthorhombicElastic, [][6]float64{
+		{1, 0, 0, 1, 0, 0}, {0, 1, 0, 0, 1, 0}, {0, 0, 1, 0, 0, 1},
+	}},
+	// unique axis b
+	"2/m": {append(orthorhombicElastic[:len(orthorhombicElastic):len(orthorhombicElastic)],
+		[]elasticEntry{{0, 4, 1}}, []elasticEntry{{1, 4, 1}},
+		[]elasticEntry{{2, 4, 1}}, []elasticEntry{{3, 5, 1}},
+	), unitPatterns},
+	"-1": {triclinicElastic(), unitPatterns},
+}
+
+var cubicElastic = [][]elasticEntry{
+	{{0, 0, 1}, {1, 1, 1}, {2, 2, 1}},
+	{{0, 1, 1}, {0, 2, 1}, {1, 2, 1}},
+	{{3, 3, 1}, {4, 4, 1}, {5, 5, 1}},
+}
+
+// hexagonalElastic has C66 = (C11 - C12)/2
+var hexagonalElastic = [][]elasticEntry{
+	{{0, 0, 1}, {1, 1, 1}, {5, 5, 0.5}},
+	{{0, 1, 1}, {5, 5, -0.5}},
+	{{0, 2, 1}, {1, 2, 1}},
+	{{2, 2, 1}},
+	{{3, 3, 1}, {4, 4, 1}},
+}
+
+var tetragonalElastic = [][]elasticEntry{
+	{{0, 0, 1}, {1, 1, 1}},
+	{{0, 1, 1}},
+	{{0, 2, 1}, {1, 2, 1}},
+	{{2, 2, 1}},
+	{{3, 3, 1}, {4, 4, 1}},
+	{{5, 5, 1}},
+}
+
+var orthorhombicElastic = [][]elasticEntry{
+	{{0, 0, 1}}, {{1, 1, 1}}, {{2, 2, 1}},
+	{{0, 1, 1}}, {{0, 2, 1}}, {{1, 2, 1}},
+	{{3, 3, 1}}, {{4, 4, 1}}, {{5, 5, 1}},
+}
+
+var unitPatterns = [][6]float64{
+	{1, 0, 0, 0, 0, 0}, {0, 1, 0, 0, 0, 0}, {0, 0, 1, 0, 0, 0},
+	{0, 0, 0, 1, 0, 0}, {0, 0, 0, 0, 1, 0}, {0, 0, 0, 0, 0, 1},
+}
+
+func triclinicElastic() [][]elasticEntry {
+	var ps [][]elasticEntry
+	for i := 0; i < 6; i++ {
+		for j := i; j < 6; j++ {
+			ps = append(ps, []elasticEntry{{i, j, 1}})
+		}
+	}
+	return ps
+}
+
+// ElasticStrains return strains to fit elastic constants of Laue class,
+// e.g. from PointGroup, each strain pattern scaled by each of magnitudes,
+// DefaultElasticMagnitudes if nil. Strains are engineering ones for
+// ApplyStrain, the cell must be in standard orientation of Refine
+func ElasticStrains(laue string, magnitudes []float64) ([]Strain, error) {
+	cls, ok := elasticClasses[laue]
+	if !ok {
+		return nil, fmt.Errorf("unknown laue class %q", laue)
+	}
+	if magnitudes == nil {
+		magnitudes = DefaultElasticMagnitudes
+	}
+	var strains []Strain
+	for _, p := range cls.patterns {
+		for _, m := range magnitudes {
+			var v [6]float64
+			for k := range v {
+				v[k] = m * p[k]
+			}
+			strains = append(strains, StrainFromVoigt(v))
+		}
+	}
+	return strains, nil
+}
+
+// ElasticCells return strains of ElasticStrains for Laue class of cell and
+// the strained cells. It is an error if the cell is not in the setting of
+// the tensor form of FitElastic, e.g. a -3m cell with 2-fold axis along y
+// or a 2/m cell of unique axis c
+func (c *Cell) ElasticCells(magnitudes []float64, symprec float64) ([]Strain, []*Cell, error) {
+	pg, err := c.PointGroup(symprec)
+	if err != nil {
+		return nil, nil, err
+	}
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, nil, err
+	}
+	cart := make([][3][3]float64, len(ds.Rotations))
+	for i, r := range ds.Rotations {
+		m := r.Cartesian(c.Lattice)
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				cart[i][j][k] = m.At(j, k)
+			}
+		}
+	}
+	if err := elasticSetting(pg.Laue, cart); err != nil {
+		return nil, nil, err
+	}
+	strains, err := ElasticStrains(pg.Laue, magnitudes)
+	if err != nil {
+		return nil, nil, err
+	}
+	cells := make([]*Cell, len(strains))
+	for i, e := range strains {
+		if cells[i], err = c.ApplyStrain(e); err != nil {
+			return nil, nil, err
+		}
+	}
+	return strains, cells, nil
+}
+
+// elasticSetting return error unless each Cartesian rotation leaves every
+// tensor of the form of Laue class unchanged
+func elasticSetting(laue string, rots [][3][3]float64) error {
+	cls, ok := elasticClasses[laue]
+	if !ok {
+		return fmt.Errorf("unknown laue class %q", laue)
+	}
+	for _, entries := range cls.params {
+		var b [6][6]float64
+		for _, e := range entries {
+			b[e.i][e.j] = e.coef
+			b[e.j][e.i] = e.coef
+		}
+		for _, r := range rots {
+			rb := rotateStiffness(b, r)
+			for i := 0; i < 6; i++ {
+				for j := 0; j < 6; j++ {
+					if math.Abs(rb[i][j]-b[i][j]) > 1e-6 {
+						return fmt.Errorf("cell is not in the setting of the %s elastic tensor, refine or rotate it", laue)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rotateStiffness return C'_ijkl = R_ip R_jq R_kr R_ls C_pqrs in Voigt
+// notation
+func rotateStiffness(c [6][6]float64, r [3][3]float64) [6][6]float64 {
+	var voigt [3][3]int
+	for k, ij := range voigtIndex {
+		voigt[ij[0]][ij[1]] = k
+		voigt[ij[1]][ij[0]] = k
+	}
+	var rc [6][6]float64
+	for m, ij := range voigtIndex {
+		for n, kl := range voigtIndex {
+			var v float64
+			for p := 0; p < 3; p++ {
+				for q := 0; q < 3; q++ {
+					a := r[ij[0]][p] * r[ij[1]][q]
+					if a == 0 {
+						continue
+					}
+					for s := 0; s < 3; s++ {
+						for t := 0; t < 3; t++ {
+							v += a * r[kl[0]][s] * r[kl[1]][t] * c[voigt[p][q]][voigt[s][t]]
+						}
+					}
+				}
+			}
+			rc[m][n] = v
+		}
+	}
+	return rc
+}
+
+// Elastic is stiffness of crystal and derived moduli, in GPa
+type Elastic struct {
+	// C is stiffness in Voigt notation
+	C [6][6]float64
+	// S is compliance, inverse of C, in 1/GPa
+	S [6][6]float64
+	// Residual stress of unstrained cell in Voigt notation
+	Residual [6]float64
+	// Bulk and shear moduli of Voigt, Reuss and Hill bounds
+	BulkVoigt, BulkReuss, BulkHill    float64
+	ShearVoigt, ShearReuss, ShearHill float64
+	// Young's modulus and Poisson's ratio of Hill moduli
+	Young, Poisson float64
+}
+
+// FitElastic fit elastic constants of Laue class to stresses in GPa,
+// positive under tension (see StressFromVASP), of cells under engineering
+// strains, by least squares of the independent constants and the residual
+// stress. The tensor form assumes the setting checked by ElasticCells, 2-fold
+// axis of -3m along x and unique axis b of 2/m
+func FitElastic(laue string, strains []Strain, stresses [][3][3]float64) (*Elastic, error) {
+	cls, ok := elasticClasses[laue]
+	if !ok {
+		return nil, fmt.Errorf("unknown laue class %q", laue)
+	}
+	if len(strains) != len(stresses) {
+		return nil, fmt.Errorf("expect a stress of each strain, got %d strains and %d stresses", len(strains), len(stresses))
+	}
+	np := len(cls.params)
+	nrow := 6 * len(strains)
+	if nrow < np+6 {
+		return nil, fmt.Errorf("expect at least %d stress components, got %d", np+6, nrow)
+	}
+	basis := make([][6][6]float64, np)
+	for p, entries := range cls.params {
+		for _, e := range entries {
+			basis[p][e.i][e.j] = e.coef
+			basis[p][e.j][e.i] = e.coef
+		}
+	}
+
+	// σ_i = σ0_i + Σ_p c_p (B_p e)_i
+	a := mat.NewDense(nrow, np+6, nil)
+	b := mat.NewVecDense(nrow, nil)
+	for n, s := range strains {
+		if err := s.check(); err != nil {
+			return nil, err
+		}
+		e := s.Voigt()
+		for i, ij := range voigtIndex {
+			row := 6*n + i
+			for p := range basis {
+				var v float64
+				for j := 0; j < 6; j++ {
+					v += basis[p][i][j] * e[j]
+				}
+				a.Set(row, p, v)
+			}
+			a.Set(row, np+i, 1)
+			b.SetVec(row, (stresses[n][ij[0]][ij[1]]+stresses[n][ij[1]][ij[0]])/2)
+		}
+	}
+	var qr mat.QR
+	qr.Factorize(a)
+	var r mat.Dense
+	qr.RTo(&r)
+	for k := 0; k < np+6; k++ {
+		if math.Abs(r.At(k, k)) < 1e-10 {
+			return nil, fmt.Errorf("strains do not determine the %d elastic constants of %s", np, laue)
+		}
+	}
+	var x mat.VecDense
+	if err := qr.SolveVecTo(&x, false, b); err != nil {
+		return nil, err
+	}
+
+	el := &Elastic{}
+	for p := range basis {
+		for i := 0; i < 6; i++ {
+			for j := 0; j < 6; j++ {
+				el.C[i][j] += x.AtVec(p) * basis[p][i][j]
+			}
+		}
+	}
+	for i := range el.Residual {
+		el.Residual[i] = x.AtVec(np + i)
+	}
+	if err := el.moduli(); err != nil {
+		return nil, err
+	}
+	return el, nil
+}
+
+// moduli compute compliance and Voigt, Reuss and Hill moduli of C
+func (el *Elastic) moduli() error {
+	cm := mat.NewDense(6, 6, nil)
+	for i := 0; i < 6; i++ {
+		for j := 0; j < 6; j++ {
+			cm.Set(i, j, el.C[i][j])
+		}
+	}
+	var sm mat.Dense
+	if err := sm.Inverse(cm); err != nil {
+		return fmt.Errorf("stiffness is singular: %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		for j := 0; j < 6; j++ {
+			el.S[i][j] = sm.At(i, j)
+		}
+	}
+	c, s := el.C, el.S
+	el.BulkVoigt = (c[0][0] + c[1][1] + c[2][2] + 2*(c[0][1]+c[1][2]+c[0][2])) / 9
+	el.ShearVoigt = (c[0][0] + c[1][1] + c[2][2] - (c[0][1] + c[1][2] + c[0][2]) + 3*(c[3][3]+c[4][4]+c[5][5])) / 15
+	el.BulkReuss = 1 / (s[0][0] + s[1][1] + s[2][2] + 2*(s[0][1]+s[1][2]+s[0][2]))
+	el.ShearReuss = 15 / (4*(s[0][0]+s[1][1]+s[2][2]) - 4*(s[0][1]+s[1][2]+s[0][2]) + 3*(s[3][3]+s[4][4]+s[5][5]))
+	el.BulkHill = (el.BulkVoigt + el.BulkReuss) / 2
+	el.ShearHill = (el.ShearVoigt + el.ShearReuss) / 2
+	k, g := el.BulkHill, el.ShearHill
+	el.Young = 9 * k * g / (3*k + g)
+	el.Poisson = (3*k - 2*g) / (2 * (3*k + g))
+	return nil
+}
+
+// Eigenvalues return eigenvalues of stiffness in increasing order
+func (el *Elastic) Eigenvalues() []float64 {
+	m := mat.NewSymDense(6, nil)
+	for i := 0; i < 6; i++ {
+		for j := i; j < 6; j++ {
+			m.SetSym(i, j, (el.C[i][j]+el.C[j][i])/2)
+		}
+	}
+	var eig mat.EigenSym
+	if !eig.Factorize(m, false) {
+		return nil
+	}
+	return eig.Values(nil)
+}
+
+// Stable return true if crystal meets the Born criteria of mechanical
+// stability, i.e. stiffness is positive definite, see Mouhat and Coudert,
+// Phys. Rev. B 90, 224104 (2014)
+func (el *Elastic) Stable() bool {
+	vals := el.Eigenvalues()
+	if len(vals) != 6 {
+		return false
+	}
+	return vals[0] > 0
+}
@@ -0,0 +1,232 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+)
+
+// stressOf return stress of stiffness c under strain e
+func stressOf(c [6][6]float64, e Strain, residual [6]float64) [3][3]float64 {
+	v := e.Voigt()
+	var s [3][3]float64
+	for i, ij := range voigtIndex {
+		x := residual[i]
+		for j := 0; j < 6; j++ {
+			x += c[i][j] * v[j]
+		}
+		s[ij[0]][ij[1]] = x
+		s[ij[1]][ij[0]] = x
+	}
+	return s
+}
+
+func TestStrain(t *testing.T) {
+	v := [6]float64{0.01, 0.02, 0.03, 0.04, 0.05, 0.06}
+	s := StrainFromVoigt(v)
+	if s[1][2] != 0.02 || s[2][1] != 0.02 || s.Voigt() != v {
+		t.Errorf("voigt round trip fail: %v %v", s, s.Voigt())
+	}
+
+	c, _ := NewCell([]float64{1, 0, 0, 0, 1, 0, 0, 0, 1}, []float64{0.1, 0.2, 0.3}, []int{1}, false)
+	e, _ := c.ApplyStrain(s)
+	if e.Lattice.At(0, 0) != 1.01 || e.Lattice.At(1, 0) != 0.03 || e.Lattice.At(0, 1) != 0.03 || e.Position.At(0, 2) != 0.3 {
+		t.Errorf("engineering strain fail: %v", e.LatticeSlice())
+	}
+	l, err := c.ApplyLagrangianStrain(s)
+	if err != nil {
+		t.Fatalf("lagrangian strain error: %v", err)
+	}
+	// metric of deformed unit lattice is I + 2η
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			var g float64
+			for k := 0; k < 3; k++ {
+				g += l.Lattice.At(i, k) * l.Lattice.At(j, k)
+			}
+			expect := 2 * s[i][j]
+			if i == j {
+				expect++
+			}
+			if math.Abs(g-expect) > 1e-12 {
+				t.Fatalf("expect metric %g at %d%d, got %g", expect, i, j, g)
+			}
+		}
+	}
+	if _, err := c.ApplyStrain(Strain{{0, 0.1, 0}, {0, 0, 0}, {0, 0, 0}}); err == nil {
+		t.Errorf("expect error of asymmetric strain")
+	}
+	if _, err := c.ApplyLagrangianStrain(StrainFromVoigt([6]float64{-0.6})); err == nil {
+		t.Errorf("expect error of I + 2η not positive definite")
+	}
+	if s := StressFromVASP([3][3]float64{{10, 0, 0}}); s[0][0] != -1 {
+		t.Errorf("expect -1 GPa of 10 kBar, got %g", s[0][0])
+	}
+}
+
+func TestFitElastic(t *testing.T) {
+	cu := [6][6]float64{
+		{170, 122, 122, 0, 0, 0},
+		{122, 170, 122, 0, 0, 0},
+		{122, 122, 170, 0, 0, 0},
+		{0, 0, 0, 75, 0, 0},
+		{0, 0, 0, 0, 75, 0},
+		{0, 0, 0, 0, 0, 75},
+	}
+	// trigonal -3 of C11 C12 C13 C14 C15 C33 C44
+	tri := [6][6]float64{
+		{200, 80, 60, 20, 10, 0},
+		{80, 200, 60, -20, -10, 0},
+		{60, 60, 250, 0, 0, 0},
+		{20, -20, 0, 90, 0, -10},
+		{10, -10, 0, 0, 90, 20},
+		{0, 0, 0, -10, 20, 60},
+	}
+	mono := [6][6]float64{
+		{200, 80, 60, 0, 15, 0},
+		{80, 210, 70, 0, -5, 0},
+		{60, 70, 250, 0, 8, 0},
+		{0, 0, 0, 90, 0, 12},
+		{15, -5, 8, 0, 80, 0},
+		{0, 0, 0, 12, 0, 60},
+	}
+	residual := [6]float64{0.5, 0.5, 0.5, 0, 0, 0}
+	for _, cs := range []struct {
+		laue string
+		c    [6][6]float64
+		n    int
+	}{
+		{"m-3m", cu, 4},
+		{"-3", tri, 8},
+		{"2/m", mono, 24},
+		{"-1", mono, 24},
+	} {
+		strains, err := ElasticStrains(cs.laue, nil)
+		if err != nil || len(strains) != cs.n {
+			t.Fatalf("%s: expect %d strains, got %d %v", cs.laue, cs.n, len(strains), err)
+		}
+		stresses := make([][3][3]float64, len(strains))
+		for i, e := range strains {
+			stresses[i] = stressOf(cs.c, e, residual)
+		}
+		el, err := FitElastic(cs.laue, strains, stresses)
+		if err != nil {
+			t.Fatalf("%s: fit error: %v", cs.laue, err)
+		}
+		for i := 0; i < 6; i++ {
+			for j := 0; j < 6; j++ {
+				if math.Abs(el.C[i][j]-cs.c[i][j]) > 1e-6 {
+					t.Fatalf("%s: expect C%d%d = %g, got %g", cs.laue, i+1, j+1, cs.c[i][j], el.C[i][j])
+				}
+			}
+		}
+		if math.Abs(el.Residual[0]-0.5) > 1e-8 || !el.Stable() {
+			t.Errorf("%s: expect residual 0.5 and stable, got %v %v", cs.laue, el.Residual, el.Stable())
+		}
+	}
+
+	strains, _ := ElasticStrains("m-3m", nil)
+	stresses := make([][3][3]float64, len(strains))
+	for i, e := range strains {
+		stresses[i] = stressOf(cu, e, [6]float64{})
+	}
+	el, _ := FitElastic("m-3m", strains, stresses)
+	// cubic bounds of bulk coincide
+	k := (170 + 2*122) / 3.0
+	gv := (170 - 122 + 3*75) / 5.0
+	if math.Abs(el.BulkVoigt-k) > 1e-8 || math.Abs(el.BulkReuss-k) > 1e-8 || math.Abs(el.ShearVoigt-gv) > 1e-8 {
+		t.Errorf("expect K=%g G_V=%g, got %g %g %g", k, gv, el.BulkVoigt, el.BulkReuss, el.ShearVoigt)
+	}
+	if el.ShearReuss > el.ShearVoigt || math.Abs(el.Young-9*el.BulkHill*el.ShearHill/(3*el.BulkHill+el.ShearHill)) > 1e-8 {
+		t.Errorf("inconsistent moduli %+v", el)
+	}
+
+	// C12 > C11 is unstable
+	bad := cu
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if i != j {
+				bad[i][j] = 200
+			}
+		}
+	}
+	for i, e := range strains {
+		stresses[i] = stressOf(bad, e, [6]float64{})
+	}
+	if el, err := FitElastic("m-3m", strains, stresses); err != nil || el.Stable() {
+		t.Errorf("expect unstable crystal, got %v", err)
+	}
+	// cubic patterns do not determine monoclinic constants
+	if _, err := FitElastic("2/m", strains, stresses); err == nil {
+		t.Errorf("expect error of too few strains")
+	}
+}
+
+// cartesianGroup return group of Cartesian rotations generated by gens
+func cartesianGroup(gens ...[3][3]float64) [][3][3]float64 {
+	group := [][3][3]float64{{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}}
+	for k := 0; k < len(group); k++ {
+		for _, g := range gens {
+			var m [3][3]float64
+			for i := 0; i < 3; i++ {
+				for j := 0; j < 3; j++ {
+					for l := 0; l < 3; l++ {
+						m[i][j] += g[i][l] * group[k][l][j]
+					}
+				}
+			}
+			seen := false
+			for _, h := range group {
+				d := 0.0
+				for i := 0; i < 3; i++ {
+					for j := 0; j < 3; j++ {
+						d += math.Abs(h[i][j] - m[i][j])
+					}
+				}
+				if d < 1e-8 {
+					seen = true
+					break
+				}
+			}
+			if !seen {
+				group = append(group, m)
+			}
+		}
+	}
+	return group
+}
+
+func TestElasticSetting(t *testing.T) {
+	s, c := math.Sin(2*math.Pi/3), math.Cos(2*math.Pi/3)
+	three := [3][3]float64{{c, -s, 0}, {s, c, 0}, {0, 0, 1}}
+	six := [3][3]float64{{0.5, -s, 0}, {s, 0.5, 0}, {0, 0, 1}}
+	four := [3][3]float64{{0, -1, 0}, {1, 0, 0}, {0, 0, 1}}
+	cubic := [3][3]float64{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}}
+	inv := [3][3]float64{{-1, 0, 0}, {0, -1, 0}, {0, 0, -1}}
+	twoX := [3][3]float64{{1, 0, 0}, {0, -1, 0}, {0, 0, -1}}
+	twoY := [3][3]float64{{-1, 0, 0}, {0, 1, 0}, {0, 0, -1}}
+	twoZ := [3][3]float64{{-1, 0, 0}, {0, -1, 0}, {0, 0, 1}}
+	for _, cs := range []struct {
+		laue string
+		gens [][3][3]float64
+		ok   bool
+	}{
+		{"m-3m", [][3][3]float64{cubic, four, inv}, true},
+		{"m-3", [][3][3]float64{cubic, twoZ, inv}, true},
+		{"6/mmm", [][3][3]float64{six, twoX, inv}, true},
+		{"6/m", [][3][3]float64{six, inv}, true},
+		{"-3m", [][3][3]float64{three, twoX, inv}, true},
+		{"-3m", [][3][3]float64{three, twoY, inv}, false},
+		{"-3", [][3][3]float64{three, inv}, true},
+		{"4/mmm", [][3][3]float64{four, twoX, inv}, true},
+		{"4/m", [][3][3]float64{four, inv}, true},
+		{"mmm", [][3][3]float64{twoX, twoY, inv}, true},
+		{"2/m", [][3][3]float64{twoY, inv}, true},
+		{"2/m", [][3][3]float64{twoZ, inv}, false},
+		{"-1", [][3][3]float64{inv}, true},
+	} {
+		err := elasticSetting(cs.laue, cartesianGroup(cs.gens...))
+		if (err == nil) != cs.ok {
+			t.Errorf("%s of %d rotations: expect ok %v, got %v", cs.laue, len(cartesianGroup(cs.gens...)), cs.ok, err)
+		}
+	}
+}
@@ -12,7 +12,11 @@ func ExampleGetSpacegroup() {
 		[]int{1},
 		false,
 	)
-  fmt.Println(c.Spacegroup(1e-5))
+  sg, err := c.Spacegroup(1e-5)
+  if err != nil {
+    fmt.Println(err)
+  }
+  fmt.Println(sg)
 
   // Output:
   // P6/mmm (191)
@@ -0,0 +1,397 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Default options of Interfaces
+const (
+	DefaultInterfaceMaxArea   = 200.0
+	DefaultInterfaceStrainTol = 0.02
+	DefaultInterfaceAreaTol   = 0.05
+	DefaultInterfaceDistance  = 3.0
+)
+
+// InterfaceOptions control Interfaces, zero values mean the defaults
+type InterfaceOptions struct {
+	// MaxArea in Å^2 of the interface
+	MaxArea float64
+	// StrainTol is largest principal strain of the top slab
+	StrainTol float64
+	// AreaTol is relative difference of areas of the supercells
+	AreaTol float64
+	// Distance in Å between top layer of bottom slab and bottom layer of
+	// top slab
+	Distance float64
+	// Vacuum in Å between top and bottom of the heterostructure across the
+	// periodic boundary, 0 for a periodic superlattice whose slabs are
+	// Distance apart across the boundary too
+	Vacuum float64
+	// Shift of top slab in fraction of a and b of the interface
+	Shift [2]float64
+}
+
+// Interface is commensurate heterostructure of two slabs
+type Interface struct {
+	// Cell has lattice of bottom supercell in the plane and c along z, atoms
+	// of bottom slab first, then of top slab
+	Cell *Cell
+	// Bottom and Top are in-plane supercell matrices, a and b of the
+	// interface are rows of the matrix times a and b of each slab
+	Bottom [2][2]int
+	Top    [2][2]int
+	// Strain of top slab is U - I of its in-plane stretch U, in xy
+	Strain [2][2]float64
+	// MaxStrain is largest principal strain in absolute value
+	MaxStrain float64
+	// Mismatch is relative difference of areas of top and bottom
+	// supercells before strain
+	Mismatch float64
+	// Area of interface in Å^2
+	Area float64
+}
+
+// interfaceMatch is pair of in-plane supercells
+type interfaceMatch struct {
+	bottom, top [2][2]int
+	f           [2][2]float64
+	strain      [2][2]float64
+	max         float64
+	mismatch    float64
+	area        float64
+}
+
+// Interfaces search supercells of bottom and top slabs whose in-plane
+// lattices match within tolerances, after Zur and McGill, J. Appl. Phys.
+// 55, 378 (1984), strain top slab onto bottom one and stack them along z.
+// Slabs must have a and b in the xy plane as from Slabs, with atoms not
+// crossing the periodic boundary along c. For each pair of supercell
+// sizes the match of least strain is returned, matches which repeat a
+// smaller one are skipped, in increasing area. opt can be nil
+func Interfaces(bottom, top *Cell, opt *InterfaceOptions) ([]*Interface, error) {
+	if opt == nil {
+		opt = &InterfaceOptions{}
+	}
+	maxArea := opt.MaxArea
+	if maxArea <= 0 {
+		maxArea = DefaultInterfaceMaxArea
+	}
+	strainTol := opt.StrainTol
+	if strainTol <= 0 {
+		strainTol = DefaultInterfaceStrainTol
+	}
+	areaTol := opt.AreaTol
+	if areaTol <= 0 {
+		areaTol = DefaultInterfaceAreaTol
+	}
+	for _, s := range []*Cell{bottom, top} {
+		if err := s.checkSlab(); err != nil {
+			return nil, err
+		}
+	}
+
+	b1, b2 := bottom.plane(), top.plane()
+	a1, a2 := cross2(b1[0], b1[1]), cross2(b2[0], b2[1])
+	var unimodular [][2][2]int
+	for _, m := range allInt2(1) {
+		if m[0][0]*m[1][1]-m[0][1]*m[1][0] == 1 {
+			unimodular = append(unimodular, m)
+		}
+	}
+
+	found := make(map[[2]int]*interfaceMatch)
+	for n1 := 1; float64(n1)*a1 <= maxArea; n1++ {
+		for n2 := 1; float64(n2)*a2 <= maxArea; n2++ {
+			mismatch := (float64(n2)*a2 - float64(n1)*a1) / (float64(n1) * a1)
+			if math.Abs(mismatch) > areaTol {
+				continue
+			}
+			g := gcd(n1, n2)
+			if g > 1 && found[[2]int{n1 / g, n2 / g}] != nil {
+				continue
+			}
+			var best *interfaceMatch
+			for _, h1 := range hnfs2(n1) {
+				m1 := reduce2(h1, b1)
+				s1 := mulPlane(m1, b1)
+				for _, h2 := range hnfs2(n2) {
+					r2 := reduce2(h2, b2)
+					for _, u := range unimodular {
+						m2 := mulInt2(u, r2)
+						s2 := mulPlane(m2, b2)
+						f, ok := deformation(s2, s1)
+						if !ok {
+							continue
+						}
+						strain, max := stretch(f)
+						if max > strainTol || (best != nil && max >= best.max) {
+							continue
+						}
+						best = &interfaceMatch{m1, m2, f, strain, max, mismatch, float64(n1) * a1}
+					}
+				}
+			}
+			if best != nil {
+				found[[2]int{n1, n2}] = best
+			}
+		}
+	}
+
+	var matches []*interfaceMatch
+	for _, m := range found {
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if math.Abs(matches[i].area-matches[j].area) > 1e-8 {
+			return matches[i].area < matches[j].area
+		}
+		return matches[i].max < matches[j].max
+	})
+	var ifs []*Interface
+	for _, m := range matches {
+		itf, err := stack(bottom, top, m, opt)
+		if err != nil {
+			return nil, err
+		}
+		ifs = append(ifs, itf)
+	}
+	return ifs, nil
+}
+
+// stack build heterostructure of match
+func stack(bottom, top *Cell, m *interfaceMatch, opt *InterfaceOptions) (*Interface, error) {
+	dist := opt.Distance
+	if dist <= 0 {
+		dist = DefaultInterfaceDistance
+	}
+	if opt.Vacuum < 0 {
+		return nil, fmt.Errorf("vacuum must not be negative, got %g", opt.Vacuum)
+	}
+	sb, err := bottom.SupercellMatrix(block3(m.bottom))
+	if err != nil {
+		return nil, err
+	}
+	st, err := top.SupercellMatrix(block3(m.top))
+	if err != nil {
+		return nil, err
+	}
+	cb, ct := sb.cartesian(), st.cartesian()
+	for i := range ct {
+		x, y := ct[i][0], ct[i][1]
+		ct[i][0] = m.f[0][0]*x + m.f[0][1]*y
+		ct[i][1] = m.f[1][0]*x + m.f[1][1]*y
+	}
+	lo1, hi1 := zRange(cb)
+	lo2, hi2 := zRange(ct)
+	// gap across the periodic boundary, at least dist so that a superlattice
+	// does not put top and bottom layers on the same plane
+	gap := opt.Vacuum
+	if gap < dist {
+		gap = dist
+	}
+	total := hi1 - lo1 + dist + hi2 - lo2 + gap
+
+	s := sb.plane()
+	shift := [2]float64{
+		opt.Shift[0]*s[0][0] + opt.Shift[1]*s[1][0],
+		opt.Shift[0]*s[0][1] + opt.Shift[1]*s[1][1],
+	}
+	var pos []float64
+	var elem []int
+	for _, p := range cb {
+		pos = append(pos, p[0], p[1], p[2]-lo1+gap/2)
+	}
+	elem = append(elem, sb.Elem...)
+	for _, p := range ct {
+		pos = append(pos, p[0]+shift[0], p[1]+shift[1], p[2]-lo2+hi1-lo1+dist+gap/2)
+	}
+	elem = append(elem, st.Elem...)
+	lattice := []float64{s[0][0], s[0][1], 0, s[1][0], s[1][1], 0, 0, 0, total}
+	cell, err := NewCell(lattice, pos, elem, true)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < cell.Natom; i++ {
+		for d := 0; d < 3; d++ {
+			v := cell.Position.At(i, d)
+			v -= math.Floor(v)
+			if v >= 1 {
+				v = 0
+			}
+			cell.Position.Set(i, d, v)
+		}
+	}
+	cell.System = bottom.System + " / " + top.System
+	return &Interface{
+		Cell:      cell,
+		Bottom:    m.bottom,
+		Top:       m.top,
+		Strain:    m.strain,
+		MaxStrain: m.max,
+		Mismatch:  m.mismatch,
+		Area:      m.area,
+	}, nil
+}
+
+// checkSlab return error unless a and b lie in xy plane and c points up
+func (c *Cell) checkSlab() error {
+	const tol = 1e-6
+	if math.Abs(c.Lattice.At(0, 2)) > tol || math.Abs(c.Lattice.At(1, 2)) > tol || c.Lattice.At(2, 2) <= 0 {
+		return fmt.Errorf("expect slab with a and b in xy plane and c up, see Slabs")
+	}
+	if cross2(c.plane()[0], c.plane()[1]) <= 0 {
+		return fmt.Errorf("expect a and b of slab anticlockwise")
+	}
+	return nil
+}
+
+// plane return xy components of a and b
+func (c *Cell) plane() [2][2]float64 {
+	return [2][2]float64{
+		{c.Lattice.At(0, 0), c.Lattice.At(0, 1)},
+		{c.Lattice.At(1, 0), c.Lattice.At(1, 1)},
+	}
+}
+
+// cartesian return cartesian positions of atoms
+func (c *Cell) cartesian() [][3]float64 {
+	r := make([][3]float64, c.Natom)
+	for i := range r {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i][j] += c.Position.At(i, k) * c.Lattice.At(k, j)
+			}
+		}
+	}
+	return r
+}
+
+// hnfs2 return lower triangular Hermite normal forms of determinant n of
+// row lattice vectors in the plane
+func hnfs2(n int) [][2][2]int {
+	var hs [][2][2]int
+	for a := 1; a <= n; a++ {
+		if n%a != 0 {
+			continue
+		}
+		for b := 0; b < a; b++ {
+			hs = append(hs, [2][2]int{{a, 0}, {b, n / a}})
+		}
+	}
+	return hs
+}
+
+// reduce2 return m·h with rows of m·h·basis Gauss reduced and
+// anticlockwise, m unimodular
+func reduce2(h [2][2]int, basis [2][2]float64) [2][2]int {
+	r := h
+	for step := 0; step < maxReduceSteps; step++ {
+		s := mulPlane(r, basis)
+		uu, vv, uv := dot2(s[0], s[0]), dot2(s[1], s[1]), dot2(s[0], s[1])
+		if vv < uu-1e-10 {
+			r[0], r[1] = r[1], r[0]
+			continue
+		}
+		q := int(math.Round(uv / uu))
+		if q == 0 {
+			break
+		}
+		r[1] = [2]int{r[1][0] - q*r[0][0], r[1][1] - q*r[0][1]}
+	}
+	if s := mulPlane(r, basis); cross2(s[0], s[1]) < 0 {
+		r[1] = [2]int{-r[1][0], -r[1][1]}
+	}
+	return r
+}
+
+// deformation return F with F·s2[i] = s1[i] for rows i
+func deformation(s2, s1 [2][2]float64) ([2][2]float64, bool) {
+	var f [2][2]float64
+	det := cross2(s2[0], s2[1])
+	if math.Abs(det) < 1e-12 {
+		return f, false
+	}
+	// F = S1^T (S2^T)^-1
+	inv := [2][2]float64{{s2[1][1] / det, -s2[1][0] / det}, {-s2[0][1] / det, s2[0][0] / det}}
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			f[i][j] = s1[0][i]*inv[0][j] + s1[1][i]*inv[1][j]
+		}
+	}
+	return f, true
+}
+
+// stretch return U - I of polar decomposition F = R·U and largest
+// principal strain in absolute value
+func stretch(f [2][2]float64) ([2][2]float64, float64) {
+	var c [2][2]float64
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			c[i][j] = f[0][i]*f[0][j] + f[1][i]*f[1][j]
+		}
+	}
+	sd := math.Sqrt(c[0][0]*c[1][1] - c[0][1]*c[1][0])
+	t := math.Sqrt(c[0][0] + c[1][1] + 2*sd)
+	u := [2][2]float64{{(c[0][0] + sd) / t, c[0][1] / t}, {c[1][0] / t, (c[1][1] + sd) / t}}
+	mean := (u[0][0] + u[1][1]) / 2
+	r := math.Sqrt((u[0][0]-u[1][1])*(u[0][0]-u[1][1])/4 + u[0][1]*u[1][0])
+	max := math.Max(math.Abs(mean+r-1), math.Abs(mean-r-1))
+	return [2][2]float64{{u[0][0] - 1, u[0][1]}, {u[1][0], u[1][1] - 1}}, max
+}
+
+// allInt2 return 2x2 integer matrices with entries in [-r, r]
+func allInt2(r int) [][2][2]int {
+	var ms [][2][2]int
+	for a := -r; a <= r; a++ {
+		for b := -r; b <= r; b++ {
+			for c := -r; c <= r; c++ {
+				for d := -r; d <= r; d++ {
+					ms = append(ms, [2][2]int{{a, b}, {c, d}})
+				}
+			}
+		}
+	}
+	return ms
+}
+
+func mulInt2(a, b [2][2]int) [2][2]int {
+	return [2][2]int{
+		{a[0][0]*b[0][0] + a[0][1]*b[1][0], a[0][0]*b[0][1] + a[0][1]*b[1][1]},
+		{a[1][0]*b[0][0] + a[1][1]*b[1][0], a[1][0]*b[0][1] + a[1][1]*b[1][1]},
+	}
+}
+
+// mulPlane return rows of m·basis
+func mulPlane(m [2][2]int, basis [2][2]float64) [2][2]float64 {
+	var s [2][2]float64
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			s[i][j] = float64(m[i][0])*basis[0][j] + float64(m[i][1])*basis[1][j]
+		}
+	}
+	return s
+}
+
+// block3 return 3x3 supercell matrix of in-plane matrix m
+func block3(m [2][2]int) [3][3]int {
+	return [3][3]int{{m[0][0], m[0][1], 0}, {m[1][0], m[1][1], 0}, {0, 0, 1}}
+}
+
+func zRange(r [][3]float64) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, p := range r {
+		lo = math.Min(lo, p[2])
+		hi = math.Max(hi, p[2])
+	}
+	return lo, hi
+}
+
+func cross2(u, v [2]float64) float64 {
+	return u[0]*v[1] - u[1]*v[0]
+}
+
+func dot2(u, v [2]float64) float64 {
+	return u[0]*v[0] + u[1]*v[1]
+}
@@ -0,0 +1,88 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInterfaces(t *testing.T) {
+	// square nets, top one rotated by 45° and √2 smaller, match in √2×√2
+	// supercell of top
+	a := 4.0
+	b := a / math.Sqrt2 * 1.001
+	bottom, _ := NewCell([]float64{a, 0, 0, 0, a, 0, 0, 0, 10}, []float64{0, 0, 0.5}, []int{42}, false)
+	top, _ := NewCell([]float64{b, 0, 0, 0, b, 0, 0, 0, 10}, []float64{0, 0, 0.5}, []int{16}, false)
+	ifs, err := Interfaces(bottom, top, &InterfaceOptions{MaxArea: 40, Distance: 3, Vacuum: 12})
+	if err != nil {
+		t.Fatalf("interface error: %v", err)
+	}
+	if len(ifs) != 1 {
+		t.Fatalf("expect 1 interface, got %d", len(ifs))
+	}
+	itf := ifs[0]
+	if math.Abs(itf.Area-a*a) > 1e-8 || itf.Cell.Natom != 3 {
+		t.Errorf("expect area %g of 3 atoms, got %g of %d", a*a, itf.Area, itf.Cell.Natom)
+	}
+	if det := itf.Top[0][0]*itf.Top[1][1] - itf.Top[0][1]*itf.Top[1][0]; det != 2 {
+		t.Errorf("expect top supercell of 2, got %v", itf.Top)
+	}
+	if math.Abs(itf.MaxStrain-0.001/1.001) > 1e-8 || math.Abs(itf.Strain[0][0]+0.001/1.001) > 1e-8 {
+		t.Errorf("expect strain -0.001/1.001, got %g %v", itf.MaxStrain, itf.Strain)
+	}
+	if math.Abs(itf.Mismatch-(1.001*1.001-1)) > 1e-8 {
+		t.Errorf("expect mismatch %g, got %g", 1.001*1.001-1, itf.Mismatch)
+	}
+	l := itf.Cell.LatticeSlice()
+	if math.Abs(l[8]-15) > 1e-8 {
+		t.Errorf("expect c of 15 Å, got %g", l[8])
+	}
+	for i, z := range []float64{6, 9, 9} {
+		if got := itf.Cell.Position.At(i, 2) * l[8]; math.Abs(got-z) > 1e-8 {
+			t.Errorf("expect atom %d at z=%g, got %g", i, z, got)
+		}
+	}
+
+	// superlattice keeps the slabs Distance apart across the boundary
+	ifs, err = Interfaces(bottom, top, &InterfaceOptions{MaxArea: 40, Distance: 3})
+	if err != nil || len(ifs) != 1 {
+		t.Fatalf("superlattice expect 1 interface, got %d, %v", len(ifs), err)
+	}
+	sl := ifs[0].Cell
+	if l := sl.LatticeSlice(); math.Abs(l[8]-6) > 1e-8 {
+		t.Errorf("superlattice expect c of 6 Å, got %g", l[8])
+	}
+	for i, z := range []float64{1.5, 4.5, 4.5} {
+		p := sl.Position.At(i, 2)
+		if p < 0 || p >= 1 || math.Abs(p*6-z) > 1e-8 {
+			t.Errorf("superlattice expect atom %d at z=%g, got %g", i, z, p*6)
+		}
+	}
+
+	// no match of 1% strain within the area
+	if ifs, _ := Interfaces(bottom, top, &InterfaceOptions{MaxArea: 40, StrainTol: 1e-4}); len(ifs) != 0 {
+		t.Errorf("expect no interface, got %d", len(ifs))
+	}
+	tilted, _ := NewCell([]float64{a, 0, 1, 0, a, 0, 0, 0, 10}, []float64{0, 0, 0}, []int{42}, false)
+	if _, err := Interfaces(tilted, top, nil); err == nil {
+		t.Errorf("expect error of slab not in xy plane")
+	}
+}
+
+func TestStretch(t *testing.T) {
+	th := 0.3
+	cs, sn := math.Cos(th), math.Sin(th)
+	// rotation of stretch diag(1.02, 0.99)
+	f := [2][2]float64{{cs * 1.02, -sn * 0.99}, {sn * 1.02, cs * 0.99}}
+	u, max := stretch(f)
+	expect := [2][2]float64{{0.02, 0}, {0, -0.01}}
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			if math.Abs(u[i][j]-expect[i][j]) > 1e-10 {
+				t.Fatalf("expect strain %v, got %v", expect, u)
+			}
+		}
+	}
+	if math.Abs(max-0.02) > 1e-10 {
+		t.Errorf("expect max strain 0.02, got %g", max)
+	}
+}
@@ -0,0 +1,219 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+)
+
+// KMesh is regular grid of k-points in fraction of reciprocal lattice.
+// Point (i, j, k) of the grid is ((i+s0)/n0, (j+s1)/n1, (k+s2)/n2) where
+// n is Mesh and s is Shift, plus half a step along even n of a
+// Monkhorst–Pack grid
+type KMesh struct {
+	// Mesh is number of points along each reciprocal lattice vector
+	Mesh [3]int
+	// Shift in unit of grid step, as the shift line of VASP KPOINTS
+	Shift [3]float64
+	// Gamma is true for Γ-centred grid and false for Monkhorst–Pack grid
+	Gamma bool
+}
+
+// IrreducibleMesh is KMesh reduced by symmetry, as ir_reciprocal_mesh of
+// spglib
+type IrreducibleMesh struct {
+	// Points are all points of the grid, the first index running fastest,
+	// each coordinate in [-0.5, 0.5)
+	Points [][3]float64
+	// Map give for each point index of equivalent irreducible point
+	Map []int
+	// Irreducible is indices of irreducible points in increasing order
+	Irreducible []int
+	// Weights is number of points equivalent to each irreducible point
+	Weights []int
+}
+
+// KMeshFromSpacing return mesh whose step along each reciprocal lattice
+// vector is at most spacing in 1/Å, including 2π, as KSPACING of VASP
+func (c *Cell) KMeshFromSpacing(spacing float64, gamma bool) (*KMesh, error) {
+	if spacing <= 0 {
+		return nil, fmt.Errorf("k-point spacing must be positive, got %g", spacing)
+	}
+	rec := c.ReciprocalLattice()
+	m := &KMesh{Gamma: gamma}
+	for i := 0; i < 3; i++ {
+		n := norm3([3]float64{rec.At(i, 0), rec.At(i, 1), rec.At(i, 2)})
+		m.Mesh[i] = int(math.Max(1, math.Ceil(n/spacing-1e-8)))
+	}
+	return m, nil
+}
+
+// KMeshFromLength return mesh with round(length·|b_i|) points along
+// reciprocal lattice vector b_i without 2π, at least one, as the fully
+// automatic length mode of VASP KPOINTS
+func (c *Cell) KMeshFromLength(length float64, gamma bool) (*KMesh, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("k-point length must be positive, got %g", length)
+	}
+	rec := c.ReciprocalLatticeCrystallographic()
+	m := &KMesh{Gamma: gamma}
+	for i := 0; i < 3; i++ {
+		n := norm3([3]float64{rec.At(i, 0), rec.At(i, 1), rec.At(i, 2)})
+		m.Mesh[i] = int(math.Max(1, math.Floor(length*n+0.5)))
+	}
+	return m, nil
+}
+
+// shift return total shift in unit of grid step, each in [0, 1)
+func (m *KMesh) shift() [3]float64 {
+	var s [3]float64
+	for i := 0; i < 3; i++ {
+		s[i] = m.Shift[i]
+		if !m.Gamma && m.Mesh[i]%2 == 0 {
+			s[i] += 0.5
+		}
+		s[i] -= math.Floor(s[i])
+	}
+	return s
+}
+
+// Points return all points of the grid, see IrreducibleMesh.Points
+func (m *KMesh) Points() ([][3]float64, error) {
+	n := m.Mesh
+	if n[0] < 1 || n[1] < 1 || n[2] < 1 {
+		return nil, fmt.Errorf("invalid k-point mesh %v", n)
+	}
+	s := m.shift()
+	points := make([][3]float64, 0, n[0]*n[1]*n[2])
+	for k := 0; k < n[2]; k++ {
+		for j := 0; j < n[1]; j++ {
+			for i := 0; i < n[0]; i++ {
+				var p [3]float64
+				for d, a := range [3]int{i, j, k} {
+					p[d] = (float64(a) + s[d]) / float64(n[d])
+					p[d] -= math.Floor(p[d] + 0.5)
+				}
+				points = append(points, p)
+			}
+		}
+	}
+	return points, nil
+}
+
+// Reduce map points of the grid to irreducible points by rotations, given
+// in fraction coordinate of the real space lattice as from Cell.Symmetry.
+// With timeReversal k and -k are also equivalent. Rotations which do not
+// map the grid onto itself are skipped, and the shift must be 0 or half a
+// step along each axis. No rotations means P1
+func (m *KMesh) Reduce(rots []Rotation, timeReversal bool) (*IrreducibleMesh, error) {
+	points, err := m.Points()
+	if err != nil {
+		return nil, err
+	}
+	n := m.Mesh
+	s := m.shift()
+	// doubled shift, grid point a has doubled address 2a+h
+	var h [3]int
+	for i := 0; i < 3; i++ {
+		h[i] = int(math.Round(2 * s[i]))
+		if math.Abs(2*s[i]-float64(h[i])) > 1e-8 {
+			return nil, fmt.Errorf("k-point shift must be 0 or 0.5 for symmetry reduction, got %v", m.Shift)
+		}
+	}
+
+	// k transform as W^-T, and W^T run over the same group as W^-T
+	seen := make(map[[3][3]int]bool)
+	var ops [][3][3]int
+	add := func(w [3][3]int) {
+		if !seen[w] {
+			seen[w] = true
+			ops = append(ops, w)
+		}
+	}
+	if len(rots) == 0 {
+		rots = []Rotation{NewRotation([3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}})}
+	}
+	for _, r := range rots {
+		w := r.ints()
+		var t [3][3]int
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				t[i][j] = w[j][i]
+			}
+		}
+		add(t)
+		if timeReversal {
+			add([3][3]int{negate(t[0]), negate(t[1]), negate(t[2])})
+		}
+	}
+
+	// image of doubled address d, in unit of 1/(2n)
+	image := func(w [3][3]int, d [3]int) ([3]int, bool) {
+		var e [3]int
+		for i := 0; i < 3; i++ {
+			var num float64
+			for j := 0; j < 3; j++ {
+				num += float64(w[i][j]*d[j]) / float64(n[j])
+			}
+			x := num * float64(n[i])
+			e[i] = int(math.Round(x))
+			if math.Abs(x-float64(e[i])) > 1e-8 || (e[i]-h[i])%2 != 0 {
+				return e, false
+			}
+		}
+		return e, true
+	}
+	index := func(d [3]int) int {
+		var a [3]int
+		for i := 0; i < 3; i++ {
+			a[i] = (((d[i]-h[i])/2)%n[i] + n[i]) % n[i]
+		}
+		return a[0] + n[0]*(a[1]+n[1]*a[2])
+	}
+	// keep rotations mapping the shifted origin and grid steps onto grid
+	var valid [][3][3]int
+	for _, w := range ops {
+		ok := true
+		for _, d := range [][3]int{h, {h[0] + 2, h[1], h[2]}, {h[0], h[1] + 2, h[2]}, {h[0], h[1], h[2] + 2}} {
+			if _, on := image(w, d); !on {
+				ok = false
+			}
+		}
+		if ok {
+			valid = append(valid, w)
+		}
+	}
+
+	ir := &IrreducibleMesh{Points: points, Map: make([]int, len(points))}
+	for p := range points {
+		a := [3]int{p % n[0], p / n[0] % n[1], p / (n[0] * n[1])}
+		d := [3]int{2*a[0] + h[0], 2*a[1] + h[1], 2*a[2] + h[2]}
+		ir.Map[p] = p
+		for _, w := range valid {
+			e, _ := image(w, d)
+			if q := index(e); q < ir.Map[p] {
+				ir.Map[p] = q
+			}
+		}
+	}
+	weight := make(map[int]int)
+	for p, q := range ir.Map {
+		if p == q {
+			ir.Irreducible = append(ir.Irreducible, p)
+		}
+		weight[q]++
+	}
+	for _, p := range ir.Irreducible {
+		ir.Weights = append(ir.Weights, weight[p])
+	}
+	return ir, nil
+}
+
+// IrreducibleKMesh reduce mesh by symmetry of cell with time reversal, see
+// KMesh.Reduce
+func (c *Cell) IrreducibleKMesh(m *KMesh, symprec float64) (*IrreducibleMesh, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, err
+	}
+	return m.Reduce(ds.Rotations, true)
+}
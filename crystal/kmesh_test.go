@@ -0,0 +1,96 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKMeshFromSpacing(t *testing.T) {
+	c, _ := NewCellFromParameters(4, 5, 20, 90, 90, 90, []float64{0, 0, 0}, []int{1})
+	m, err := c.KMeshFromSpacing(0.2, true)
+	if err != nil {
+		t.Fatalf("kmesh error: %v", err)
+	}
+	// 2π/4/0.2 = 7.85, 2π/5/0.2 = 6.28, 2π/20/0.2 = 1.57
+	if m.Mesh != [3]int{8, 7, 2} || !m.Gamma {
+		t.Errorf("expect Γ-centred 8x7x2, got %+v", m)
+	}
+	m, _ = c.KMeshFromLength(20, false)
+	if m.Mesh != [3]int{5, 4, 1} || m.Gamma {
+		t.Errorf("expect Monkhorst–Pack 5x4x1, got %+v", m)
+	}
+	if _, err := c.KMeshFromSpacing(0, true); err == nil {
+		t.Error("expect error for zero spacing")
+	}
+}
+
+func TestKMeshPoints(t *testing.T) {
+	m := &KMesh{Mesh: [3]int{2, 3, 1}}
+	points, _ := m.Points()
+	// even axis shifted by half a step, odd axis contain Γ
+	expect := [][3]float64{
+		{0.25, 0, 0}, {-0.25, 0, 0},
+		{0.25, 1.0 / 3, 0}, {-0.25, 1.0 / 3, 0},
+		{0.25, -1.0 / 3, 0}, {-0.25, -1.0 / 3, 0},
+	}
+	if len(points) != len(expect) {
+		t.Fatalf("expect %d points, got %d", len(expect), len(points))
+	}
+	for i := range expect {
+		for d := 0; d < 3; d++ {
+			if math.Abs(points[i][d]-expect[i][d]) > 1e-12 {
+				t.Errorf("point %d: expect %v, got %v", i, expect[i], points[i])
+				break
+			}
+		}
+	}
+}
+
+func TestKMeshReduce(t *testing.T) {
+	cubic := rotationGroup("z,x,y", "-y,x,z", "-x,-y,-z")
+	cases := []struct {
+		mesh  KMesh
+		rots  []Rotation
+		nir   int
+		gamma int
+	}{
+		// values 0, 1/4, 1/2 of 0 <= x <= y <= z
+		{KMesh{Mesh: [3]int{4, 4, 4}, Gamma: true}, cubic, 10, 1},
+		// values 1/8, 3/8 of 0 < x <= y <= z
+		{KMesh{Mesh: [3]int{4, 4, 4}}, cubic, 4, 0},
+		// rotations mixing z with x and y do not keep the grid, as 4/mmm
+		{KMesh{Mesh: [3]int{4, 4, 3}, Gamma: true}, cubic, 12, 1},
+		// P1 with time reversal pair k and -k
+		{KMesh{Mesh: [3]int{3, 3, 3}, Gamma: true}, nil, 14, 1},
+		{KMesh{Mesh: [3]int{2, 2, 2}, Gamma: true, Shift: [3]float64{0.5, 0.5, 0.5}}, cubic, 1, 0},
+	}
+	for _, c := range cases {
+		ir, err := c.mesh.Reduce(c.rots, true)
+		if err != nil {
+			t.Fatalf("%+v: reduce error: %v", c.mesh, err)
+		}
+		if len(ir.Irreducible) != c.nir {
+			t.Errorf("%+v: expect %d irreducible points, got %d", c.mesh, c.nir, len(ir.Irreducible))
+		}
+		total := 0
+		for _, w := range ir.Weights {
+			total += w
+		}
+		if total != len(ir.Points) {
+			t.Errorf("%+v: expect weights sum to %d, got %d", c.mesh, len(ir.Points), total)
+		}
+		if c.gamma == 1 && (ir.Irreducible[0] != 0 || ir.Weights[0] != 1) {
+			t.Errorf("%+v: expect Γ irreducible with weight 1", c.mesh)
+		}
+		for p, q := range ir.Map {
+			if ir.Map[q] != q {
+				t.Errorf("%+v: point %d map to %d, which is not irreducible", c.mesh, p, q)
+				break
+			}
+		}
+	}
+
+	if _, err := (&KMesh{Mesh: [3]int{4, 4, 4}, Shift: [3]float64{0.25, 0, 0}}).Reduce(cubic, true); err == nil {
+		t.Error("expect error for quarter step shift")
+	}
+}
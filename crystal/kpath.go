@@ -0,0 +1,514 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// KPath is band structure path through high symmetry points of the
+// Brillouin zone in the convention of Setyawan and Curtarolo, Comput. Mater.
+// Sci. 49, 299 (2010)
+type KPath struct {
+	// Lattice is type of lattice, e.g. FCC, BCT1 or MCLC3
+	Lattice string
+	// Cell is primitive cell of the convention, Points are in fraction of
+	// its reciprocal lattice
+	Cell *Cell
+	// Matrix relate the primitive cell to the input cell, lattice rows of
+	// Cell are Matrix·Lattice of the input up to a rotation
+	Matrix *mat.Dense
+	// Points are high symmetry points by label, Γ for the zone center
+	Points map[string][3]float64
+	// Path is list of continuous segments given by labels
+	Path [][]string
+}
+
+// KPoint is point of densified path
+type KPoint struct {
+	// Frac is coordinate in fraction of reciprocal lattice
+	Frac [3]float64
+	// Label of high symmetry point, empty for points in between
+	Label string
+	// Distance along path in 1/Å, including 2π, not increased across a
+	// break between segments
+	Distance float64
+}
+
+// kpathTol is relative tolerance to decide between lattice types
+const kpathTol = 1e-5
+
+// KPath return band structure path of cell
+func (c *Cell) KPath(symprec float64) (*KPath, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, err
+	}
+	conv, tr, err := c.Refine(symprec)
+	if err != nil {
+		return nil, err
+	}
+	kp, p, err := setyawanCurtarolo(conv, ds.SpaceNumber, ds.SpaceSymbol)
+	if err != nil {
+		return nil, err
+	}
+	// rows of conventional lattice are M^-T·Lattice
+	var inv mat.Dense
+	if err := inv.Inverse(tr.Matrix); err != nil {
+		return nil, err
+	}
+	kp.Matrix = mat.NewDense(3, 3, nil)
+	kp.Matrix.Mul(p, inv.T())
+	return kp, nil
+}
+
+// Kpoints return points along path with spacing at most spacing in 1/Å,
+// including 2π. Both ends of a break between segments are included
+func (p *KPath) Kpoints(spacing float64) ([]KPoint, error) {
+	if spacing <= 0 {
+		return nil, fmt.Errorf("k-point spacing must be positive, got %g", spacing)
+	}
+	rec := p.Cell.ReciprocalLattice()
+	var kps []KPoint
+	var dist float64
+	for _, seg := range p.Path {
+		for i, label := range seg {
+			k, ok := p.Points[label]
+			if !ok {
+				return nil, fmt.Errorf("unknown k-point label %q", label)
+			}
+			if i == 0 {
+				kps = append(kps, KPoint{Frac: k, Label: label, Distance: dist})
+				continue
+			}
+			prev := p.Points[seg[i-1]]
+			var d [3]float64
+			for j := 0; j < 3; j++ {
+				diff := k[j] - prev[j]
+				for m := 0; m < 3; m++ {
+					d[m] += diff * rec.At(j, m)
+				}
+			}
+			length := math.Sqrt(d[0]*d[0] + d[1]*d[1] + d[2]*d[2])
+			n := int(math.Max(1, math.Ceil(length/spacing-1e-8)))
+			for s := 1; s <= n; s++ {
+				f := float64(s) / float64(n)
+				var kf [3]float64
+				for j := 0; j < 3; j++ {
+					kf[j] = prev[j] + f*(k[j]-prev[j])
+				}
+				kp := KPoint{Frac: kf, Distance: dist + f*length}
+				if s == n {
+					kp.Label = label
+				}
+				kps = append(kps, kp)
+			}
+			dist += length
+		}
+	}
+	return kps, nil
+}
+
+// setyawanCurtarolo return path of conventional standardized cell of space
+// group number with Hermann–Mauguin symbol, and matrix p with lattice rows
+// of primitive cell p·conv.Lattice
+func setyawanCurtarolo(conv *Cell, number int, symbol string) (*KPath, *mat.Dense, error) {
+	if symbol == "" {
+		return nil, nil, fmt.Errorf("kpath: empty space group symbol")
+	}
+	centering := symbol[0]
+	lv := func(i int) [3]float64 {
+		return [3]float64{conv.Lattice.At(i, 0), conv.Lattice.At(i, 1), conv.Lattice.At(i, 2)}
+	}
+	// rows of conventional lattice of the convention in terms of rows of
+	// conv, a permutation with signs
+	r := identityF3()
+	order := func(idx [3]int) {
+		r = [3][3]float64{}
+		for i, j := range idx {
+			r[i][j] = 1
+		}
+	}
+	byLength := func(idx []int) {
+		sort.SliceStable(idx, func(i, j int) bool { return norm3(lv(idx[i])) < norm3(lv(idx[j]))-kpathTol })
+	}
+	prim := identityF3()
+	var name string
+
+	switch {
+	case number >= 195:
+		switch centering {
+		case 'F':
+			name, prim = "FCC", faceCentred
+		case 'I':
+			name, prim = "BCC", bodyCentred
+		default:
+			name = "CUB"
+		}
+	case number >= 168 || (number >= 143 && centering != 'R'):
+		name = "HEX"
+	case number >= 143:
+		// rhombohedral lattice of obverse hexagonal setting
+		prim = [3][3]float64{{2.0 / 3, 1.0 / 3, 1.0 / 3}, {-1.0 / 3, 1.0 / 3, 1.0 / 3}, {-1.0 / 3, -2.0 / 3, 1.0 / 3}}
+		name = "RHL"
+	case number >= 75:
+		if centering == 'I' {
+			a, c := norm3(lv(0)), norm3(lv(2))
+			name, prim = "BCT2", bodyCentred
+			if c < a {
+				name = "BCT1"
+			}
+		} else {
+			name = "TET"
+		}
+	case number >= 16:
+		idx := []int{0, 1, 2}
+		switch centering {
+		case 'C':
+			byLength(idx[:2])
+		case 'A':
+			idx = []int{1, 2, 0}
+			byLength(idx[:2])
+		default:
+			byLength(idx)
+		}
+		order([3]int{idx[0], idx[1], idx[2]})
+		switch centering {
+		case 'F':
+			name, prim = "ORCF", faceCentred
+		case 'I':
+			name, prim = "ORCI", bodyCentred
+		case 'C', 'A':
+			name = "ORCC"
+			prim = [3][3]float64{{0.5, -0.5, 0}, {0.5, 0.5, 0}, {0, 0, 1}}
+		default:
+			name = "ORC"
+		}
+	case number >= 3:
+		// unique axis b of spglib become a, b is centring partner
+		if centering == 'C' {
+			name = "MCLC"
+			order([3]int{1, 0, 2})
+			prim = [3][3]float64{{0.5, 0.5, 0}, {-0.5, 0.5, 0}, {0, 0, 1}}
+		} else {
+			name = "MCL"
+			idx := []int{0, 2}
+			byLength(idx)
+			order([3]int{1, idx[0], idx[1]})
+		}
+		// monoclinic angle between b and c less than 90°
+		l := mulF3(r, conv)
+		if dot3(l[1], l[2]) < 0 {
+			for j := 0; j < 3; j++ {
+				r[2][j] = -r[2][j]
+			}
+		}
+	default:
+		name = "TRI"
+	}
+
+	// right-handed lattice, flip a which is perpendicular to b and c for
+	// all but triclinic lattices
+	if det3(mulF3(r, conv)) < 0 {
+		for j := 0; j < 3; j++ {
+			r[0][j] = -r[0][j]
+		}
+	}
+	if name == "TRI" {
+		tri, err := reciprocalNiggli(conv)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = tri
+	}
+
+	p := mat.NewDense(3, 3, nil)
+	p.Mul(f3ToDense(prim), f3ToDense(r))
+	cell, err := conv.changeBasis(p, 1e-5)
+	if err != nil {
+		return nil, nil, fmt.Errorf("kpath: %v", err)
+	}
+
+	l := mulF3(r, conv)
+	a, b, c := norm3(l[0]), norm3(l[1]), norm3(l[2])
+	alpha := math.Acos(dot3(l[1], l[2]) / (b * c))
+	kp, err := scPath(name, a, b, c, alpha, cell)
+	if err != nil {
+		return nil, nil, err
+	}
+	kp.Cell = cell
+	return kp, p, nil
+}
+
+var (
+	faceCentred = [3][3]float64{{0, 0.5, 0.5}, {0.5, 0, 0.5}, {0.5, 0.5, 0}}
+	bodyCentred = [3][3]float64{{-0.5, 0.5, 0.5}, {0.5, -0.5, 0.5}, {0.5, 0.5, -0.5}}
+)
+
+// scPath return points and path of lattice type name with conventional
+// parameters a, b, c and α in radian, prim is primitive cell of the
+// convention
+func scPath(name string, a, b, c, alpha float64, prim *Cell) (*KPath, error) {
+	eq := func(x, y float64) bool { return math.Abs(x-y) <= kpathTol*math.Max(1, math.Abs(y)) }
+	recAngles := func() [3]float64 {
+		rc := &Cell{Lattice: prim.ReciprocalLatticeCrystallographic()}
+		return rc.Angles()
+	}
+	cosa, sina := math.Cos(alpha), math.Sin(alpha)
+	p := &KPath{Lattice: name}
+	g := [3]float64{0, 0, 0}
+
+	switch name {
+	case "CUB":
+		p.Points = map[string][3]float64{"Γ": g, "M": {0.5, 0.5, 0}, "R": {0.5, 0.5, 0.5}, "X": {0, 0.5, 0}}
+		p.Path = [][]string{{"Γ", "X", "M", "Γ", "R", "X"}, {"M", "R"}}
+	case "FCC":
+		p.Points = map[string][3]float64{"Γ": g, "K": {3.0 / 8, 3.0 / 8, 3.0 / 4}, "L": {0.5, 0.5, 0.5},
+			"U": {5.0 / 8, 1.0 / 4, 5.0 / 8}, "W": {0.5, 0.25, 0.75}, "X": {0.5, 0, 0.5}}
+		p.Path = [][]string{{"Γ", "X", "W", "K", "Γ", "L", "U", "W", "L", "K"}, {"U", "X"}}
+	case "BCC":
+		p.Points = map[string][3]float64{"Γ": g, "H": {0.5, -0.5, 0.5}, "P": {0.25, 0.25, 0.25}, "N": {0, 0, 0.5}}
+		p.Path = [][]string{{"Γ", "H", "N", "Γ", "P", "H"}, {"P", "N"}}
+	case "TET":
+		p.Points = map[string][3]float64{"Γ": g, "A": {0.5, 0.5, 0.5}, "M": {0.5, 0.5, 0}, "R": {0, 0.5, 0.5},
+			"X": {0, 0.5, 0}, "Z": {0, 0, 0.5}}
+		p.Path = [][]string{{"Γ", "X", "M", "Γ", "Z", "R", "A", "Z"}, {"X", "R"}, {"M", "A"}}
+	case "BCT1":
+		eta := (1 + c*c/(a*a)) / 4
+		p.Points = map[string][3]float64{"Γ": g, "M": {-0.5, 0.5, 0.5}, "N": {0, 0.5, 0}, "P": {0.25, 0.25, 0.25},
+			"X": {0, 0, 0.5}, "Z": {eta, eta, -eta}, "Z1": {-eta, 1 - eta, eta}}
+		p.Path = [][]string{{"Γ", "X", "M", "Γ", "Z", "P", "N", "Z1", "M"}, {"X", "P"}}
+	case "BCT2":
+		eta := (1 + a*a/(c*c)) / 4
+		zeta := a * a / (2 * c * c)
+		p.Points = map[string][3]float64{"Γ": g, "N": {0, 0.5, 0}, "P": {0.25, 0.25, 0.25},
+			"Σ": {-eta, eta, eta}, "Σ1": {eta, 1 - eta, -eta}, "X": {0, 0, 0.5},
+			"Y": {-zeta, zeta, 0.5}, "Y1": {0.5, 0.5, -zeta}, "Z": {0.5, 0.5, -0.5}}
+		p.Path = [][]string{{"Γ", "X", "Y", "Σ", "Γ", "Z", "Σ1", "N", "P", "Y1", "Z"}, {"X", "P"}}
+	case "ORC":
+		p.Points = map[string][3]float64{"Γ": g, "R": {0.5, 0.5, 0.5}, "S": {0.5, 0.5, 0}, "T": {0, 0.5, 0.5},
+			"U": {0.5, 0, 0.5}, "X": {0.5, 0, 0}, "Y": {0, 0.5, 0}, "Z": {0, 0, 0.5}}
+		p.Path = [][]string{{"Γ", "X", "S", "Y", "Γ", "Z", "U", "R", "T", "Z"}, {"Y", "T"}, {"U", "X"}, {"S", "R"}}
+	case "ORCF":
+		switch x, y := 1/(a*a), 1/(b*b)+1/(c*c); {
+		case eq(x, y):
+			p.Lattice = "ORCF3"
+		case x > y:
+			p.Lattice = "ORCF1"
+		default:
+			p.Lattice = "ORCF2"
+		}
+		if p.Lattice == "ORCF2" {
+			phi := (1 + c*c/(b*b) - c*c/(a*a)) / 4
+			eta := (1 + a*a/(b*b) - a*a/(c*c)) / 4
+			delta := (1 + b*b/(a*a) - b*b/(c*c)) / 4
+			p.Points = map[string][3]float64{"Γ": g, "C": {0.5, 0.5 - eta, 1 - eta}, "C1": {0.5, 0.5 + eta, eta},
+				"D": {0.5 - delta, 0.5, 1 - delta}, "D1": {0.5 + delta, 0.5, delta}, "L": {0.5, 0.5, 0.5},
+				"H": {1 - phi, 0.5 - phi, 0.5}, "H1": {phi, 0.5 + phi, 0.5},
+				"X": {0, 0.5, 0.5}, "Y": {0.5, 0, 0.5}, "Z": {0.5, 0.5, 0}}
+			p.Path = [][]string{{"Γ", "Y", "C", "D", "X", "Γ", "Z", "D1", "H", "C"}, {"C1", "Z"}, {"X", "H1"},
+				{"H", "Y"}, {"L", "Γ"}}
+			break
+		}
+		zeta := (1 + a*a/(b*b) - a*a/(c*c)) / 4
+		eta := (1 + a*a/(b*b) + a*a/(c*c)) / 4
+		p.Points = map[string][3]float64{"Γ": g, "A": {0.5, 0.5 + zeta, zeta}, "A1": {0.5, 0.5 - zeta, 1 - zeta},
+			"L": {0.5, 0.5, 0.5}, "T": {1, 0.5, 0.5}, "X": {0, eta, eta}, "X1": {1, 1 - eta, 1 - eta},
+			"Y": {0.5, 0, 0.5}, "Z": {0.5, 0.5, 0}}
+		if p.Lattice == "ORCF1" {
+			p.Path = [][]string{{"Γ", "Y", "T", "Z", "Γ", "X", "A1", "Y"}, {"T", "X1"}, {"X", "A", "Z"}, {"L", "Γ"}}
+		} else {
+			p.Path = [][]string{{"Γ", "Y", "T", "Z", "Γ", "X", "A1", "Y"}, {"X", "A", "Z"}, {"L", "Γ"}}
+		}
+	case "ORCI":
+		zeta := (1 + a*a/(c*c)) / 4
+		eta := (1 + b*b/(c*c)) / 4
+		delta := (b*b - a*a) / (4 * c * c)
+		mu := (a*a + b*b) / (4 * c * c)
+		p.Points = map[string][3]float64{"Γ": g, "L": {-mu, mu, 0.5 - delta}, "L1": {mu, -mu, 0.5 + delta},
+			"L2": {0.5 - delta, 0.5 + delta, -mu}, "R": {0, 0.5, 0}, "S": {0.5, 0, 0}, "T": {0, 0, 0.5},
+			"W": {0.25, 0.25, 0.25}, "X": {-zeta, zeta, zeta}, "X1": {zeta, 1 - zeta, -zeta},
+			"Y": {eta, -eta, eta}, "Y1": {1 - eta, eta, -eta}, "Z": {0.5, 0.5, -0.5}}
+		p.Path = [][]string{{"Γ", "X", "L", "T", "W", "R", "X1", "Z", "Γ", "Y", "S", "W"}, {"L1", "Y"}, {"Y1", "Z"}}
+	case "ORCC":
+		zeta := (1 + a*a/(b*b)) / 4
+		p.Points = map[string][3]float64{"Γ": g, "A": {zeta, zeta, 0.5}, "A1": {-zeta, 1 - zeta, 0.5},
+			"R": {0, 0.5, 0.5}, "S": {0, 0.5, 0}, "T": {-0.5, 0.5, 0.5}, "X": {zeta, zeta, 0},
+			"X1": {-zeta, 1 - zeta, 0}, "Y": {-0.5, 0.5, 0}, "Z": {0, 0, 0.5}}
+		p.Path = [][]string{{"Γ", "X", "S", "R", "A", "Z", "Γ", "Y", "X1", "A1", "T", "Y"}, {"Z", "T"}}
+	case "HEX":
+		p.Points = map[string][3]float64{"Γ": g, "A": {0, 0, 0.5}, "H": {1.0 / 3, 1.0 / 3, 0.5},
+			"K": {1.0 / 3, 1.0 / 3, 0}, "L": {0.5, 0, 0.5}, "M": {0.5, 0, 0}}
+		p.Path = [][]string{{"Γ", "M", "K", "Γ", "A", "L", "H", "A"}, {"L", "M"}, {"K", "H"}}
+	case "RHL":
+		an := prim.Angles()
+		ra := an[0] * math.Pi / 180
+		if an[0] < 90 {
+			p.Lattice = "RHL1"
+			eta := (1 + 4*math.Cos(ra)) / (2 + 4*math.Cos(ra))
+			nu := 0.75 - eta/2
+			p.Points = map[string][3]float64{"Γ": g, "B": {eta, 0.5, 1 - eta}, "B1": {0.5, 1 - eta, eta - 1},
+				"F": {0.5, 0.5, 0}, "L": {0.5, 0, 0}, "L1": {0, 0, -0.5}, "P": {eta, nu, nu},
+				"P1": {1 - nu, 1 - nu, 1 - eta}, "P2": {nu, nu, eta - 1}, "Q": {1 - nu, nu, 0},
+				"X": {nu, 0, -nu}, "Z": {0.5, 0.5, 0.5}}
+			p.Path = [][]string{{"Γ", "L", "B1"}, {"B", "Z", "Γ", "X"}, {"Q", "F", "P1", "Z"}, {"L", "P"}}
+		} else {
+			p.Lattice = "RHL2"
+			eta := 1 / (2 * math.Pow(math.Tan(ra/2), 2))
+			nu := 0.75 - eta/2
+			p.Points = map[string][3]float64{"Γ": g, "F": {0.5, -0.5, 0}, "L": {0.5, 0, 0},
+				"P": {1 - nu, -nu, 1 - nu}, "P1": {nu, nu - 1, nu - 1}, "Q": {eta, eta, eta},
+				"Q1": {1 - eta, -eta, -eta}, "Z": {0.5, -0.5, 0.5}}
+			p.Path = [][]string{{"Γ", "P", "Z", "Q", "Γ", "F", "P1", "Q1", "L", "Z"}}
+		}
+	case "MCL":
+		eta := (1 - b*cosa/c) / (2 * sina * sina)
+		nu := 0.5 - eta*c*cosa/b
+		p.Points = map[string][3]float64{"Γ": g, "A": {0.5, 0.5, 0}, "C": {0, 0.5, 0.5}, "D": {0.5, 0, 0.5},
+			"D1": {0.5, 0, -0.5}, "E": {0.5, 0.5, 0.5}, "H": {0, eta, 1 - nu}, "H1": {0, 1 - eta, nu},
+			"H2": {0, eta, -nu}, "M": {0.5, eta, 1 - nu}, "M1": {0.5, 1 - eta, nu}, "M2": {0.5, eta, -nu},
+			"X": {0, 0.5, 0}, "Y": {0, 0, 0.5}, "Y1": {0, 0, -0.5}, "Z": {0.5, 0, 0}}
+		p.Path = [][]string{{"Γ", "Y", "H", "C", "E", "M1", "A", "X", "H1"}, {"M", "D", "Z"}, {"Y", "D"}}
+	case "MCLC":
+		kg := recAngles()[2]
+		switch {
+		case eq(kg, 90) || kg > 90:
+			zeta := (2 - b*cosa/c) / (4 * sina * sina)
+			eta := 0.5 + 2*zeta*c*cosa/b
+			psi := 0.75 - a*a/(4*b*b*sina*sina)
+			phi := psi + (0.75-psi)*b*cosa/c
+			p.Points = map[string][3]float64{"Γ": g, "N": {0.5, 0, 0}, "N1": {0, -0.5, 0},
+				"F": {1 - zeta, 1 - zeta, 1 - eta}, "F1": {zeta, zeta, eta}, "F2": {-zeta, -zeta, 1 - eta},
+				"I": {phi, 1 - phi, 0.5}, "I1": {1 - phi, phi - 1, 0.5}, "L": {0.5, 0.5, 0.5},
+				"M": {0.5, 0, 0.5}, "X": {1 - psi, psi - 1, 0}, "X1": {psi, 1 - psi, 0},
+				"X2": {psi - 1, -psi, 0}, "Y": {0.5, 0.5, 0}, "Y1": {-0.5, -0.5, 0}, "Z": {0, 0, 0.5}}
+			if eq(kg, 90) {
+				p.Lattice = "MCLC2"
+				p.Path = [][]string{{"Γ", "Y", "F", "L", "I"}, {"I1", "Z", "F1"}, {"N", "Γ", "M"}}
+			} else {
+				p.Lattice = "MCLC1"
+				p.Path = [][]string{{"Γ", "Y", "F", "L", "I"}, {"I1", "Z", "F1"}, {"Y", "X1"}, {"X", "Γ", "N"}, {"M", "Γ"}}
+			}
+		default:
+			x := b*cosa/c + b*b*sina*sina/(a*a)
+			if eq(x, 1) || x < 1 {
+				mu := (1 + b*b/(a*a)) / 4
+				delta := b * c * cosa / (2 * a * a)
+				zeta := mu - 0.25 + (1-b*cosa/c)/(4*sina*sina)
+				eta := 0.5 + 2*zeta*c*cosa/b
+				phi := 1 + zeta - 2*mu
+				psi := eta - 2*delta
+				p.Points = map[string][3]float64{"Γ": g, "F": {1 - phi, 1 - phi, 1 - psi}, "F1": {phi, phi - 1, psi},
+					"F2": {1 - phi, -phi, 1 - psi}, "H": {zeta, zeta, eta}, "H1": {1 - zeta, -zeta, 1 - eta},
+					"H2": {-zeta, -zeta, 1 - eta}, "I": {0.5, -0.5, 0.5}, "M": {0.5, 0, 0.5}, "N": {0.5, 0, 0},
+					"N1": {0, -0.5, 0}, "X": {0.5, -0.5, 0}, "Y": {mu, mu, delta}, "Y1": {1 - mu, -mu, -delta},
+					"Y2": {-mu, -mu, -delta}, "Y3": {mu, mu - 1, delta}, "Z": {0, 0, 0.5}}
+				if eq(x, 1) {
+					p.Lattice = "MCLC4"
+					p.Path = [][]string{{"Γ", "Y", "F", "H", "Z", "I"}, {"H1", "Y1", "X", "Γ", "N"}, {"M", "Γ"}}
+				} else {
+					p.Lattice = "MCLC3"
+					p.Path = [][]string{{"Γ", "Y", "F", "H", "Z", "I", "F1"}, {"H1", "Y1", "X", "Γ", "N"}, {"M", "Γ"}}
+				}
+				break
+			}
+			p.Lattice = "MCLC5"
+			zeta := (b*b/(a*a) + (1-b*cosa/c)/(sina*sina)) / 4
+			eta := 0.5 + 2*zeta*c*cosa/b
+			mu := eta/2 + b*b/(4*a*a) - b*c*cosa/(2*a*a)
+			nu := 2*mu - zeta
+			rho := 1 - zeta*a*a/(b*b)
+			omega := (4*nu - 1 - b*b*sina*sina/(a*a)) * c / (2 * b * cosa)
+			delta := zeta*c*cosa/b + omega/2 - 0.25
+			p.Points = map[string][3]float64{"Γ": g, "F": {nu, nu, omega}, "F1": {1 - nu, 1 - nu, 1 - omega},
+				"F2": {nu, nu - 1, omega}, "H": {zeta, zeta, eta}, "H1": {1 - zeta, -zeta, 1 - eta},
+				"H2": {-zeta, -zeta, 1 - eta}, "I": {rho, 1 - rho, 0.5}, "I1": {1 - rho, rho - 1, 0.5},
+				"L": {0.5, 0.5, 0.5}, "M": {0.5, 0, 0.5}, "N": {0.5, 0, 0}, "N1": {0, -0.5, 0},
+				"X": {0.5, -0.5, 0}, "Y": {mu, mu, delta}, "Y1": {1 - mu, -mu, -delta},
+				"Y2": {-mu, -mu, -delta}, "Y3": {mu, mu - 1, delta}, "Z": {0, 0, 0.5}}
+			p.Path = [][]string{{"Γ", "Y", "F", "L", "I"}, {"I1", "Z", "H", "F1"}, {"H1", "Y1", "X", "Γ", "N"}, {"M", "Γ"}}
+		}
+	case "TRI":
+		// reciprocal angles are all acute or all non-acute
+		ka := recAngles()
+		if !(ka[0] < 90 && ka[1] < 90) {
+			p.Lattice = "TRI1a"
+			if eq(ka[2], 90) {
+				p.Lattice = "TRI2a"
+			}
+			p.Points = map[string][3]float64{"Γ": g, "L": {0.5, 0.5, 0}, "M": {0, 0.5, 0.5}, "N": {0.5, 0, 0.5},
+				"R": {0.5, 0.5, 0.5}, "X": {0.5, 0, 0}, "Y": {0, 0.5, 0}, "Z": {0, 0, 0.5}}
+		} else {
+			p.Lattice = "TRI1b"
+			if eq(ka[2], 90) {
+				p.Lattice = "TRI2b"
+			}
+			p.Points = map[string][3]float64{"Γ": g, "L": {0.5, -0.5, 0}, "M": {0, 0, 0.5}, "N": {-0.5, -0.5, 0.5},
+				"R": {0, -0.5, 0.5}, "X": {0, -0.5, 0}, "Y": {0.5, 0, 0}, "Z": {-0.5, 0, 0.5}}
+		}
+		p.Path = [][]string{{"X", "Γ", "Y"}, {"L", "Γ", "Z"}, {"N", "Γ", "M"}, {"R", "Γ"}}
+	default:
+		return nil, fmt.Errorf("kpath: unknown lattice type %s", name)
+	}
+	return p, nil
+}
+
+// reciprocalNiggli return matrix r with rows of lattice r·Lattice whose
+// reciprocal lattice is Niggli reduced, so reciprocal angles are all acute
+// or all non-acute
+func reciprocalNiggli(c *Cell) ([3][3]float64, error) {
+	rec := &Cell{Lattice: c.ReciprocalLatticeCrystallographic(), Position: mat.NewDense(1, 3, nil), Elem: []int{0}, Natom: 1}
+	_, m, err := rec.Niggli(0)
+	if err != nil {
+		return [3][3]float64{}, err
+	}
+	// reciprocal rows m·B give real rows m^-T·L
+	inv, err := inverseUnimodular(m)
+	if err != nil {
+		return [3][3]float64{}, err
+	}
+	var r [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[i][j] = float64(inv[j][i])
+		}
+	}
+	return r, nil
+}
+
+func identityF3() [3][3]float64 {
+	return [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+}
+
+func f3ToDense(m [3][3]float64) *mat.Dense {
+	return mat.NewDense(3, 3, []float64{m[0][0], m[0][1], m[0][2], m[1][0], m[1][1], m[1][2], m[2][0], m[2][1], m[2][2]})
+}
+
+// mulF3 return rows of r·c.Lattice
+func mulF3(r [3][3]float64, c *Cell) [3][3]float64 {
+	var l [3][3]float64
+	for i := 0; i < 3; i++ {
+		for k := 0; k < 3; k++ {
+			for j := 0; j < 3; j++ {
+				l[i][j] += r[i][k] * c.Lattice.At(k, j)
+			}
+		}
+	}
+	return l
+}
+
+func norm3(v [3]float64) float64 {
+	return math.Sqrt(dot3(v, v))
+}
+
+func dot3(u, v [3]float64) float64 {
+	return u[0]*v[0] + u[1]*v[1] + u[2]*v[2]
+}
+
+func det3(l [3][3]float64) float64 {
+	return l[0][0]*(l[1][1]*l[2][2]-l[1][2]*l[2][1]) -
+		l[0][1]*(l[1][0]*l[2][2]-l[1][2]*l[2][0]) +
+		l[0][2]*(l[1][0]*l[2][1]-l[1][1]*l[2][0])
+}
@@ -0,0 +1,127 @@
+package crystal
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSetyawanCurtarolo(t *testing.T) {
+	s3 := math.Sqrt(3)
+	beta := 100 * math.Pi / 180
+	cases := []struct {
+		lattice []float64
+		pos     []float64
+		number  int
+		symbol  string
+		name    string
+		natom   int
+	}{
+		{[]float64{3.61, 0, 0, 0, 3.61, 0, 0, 0, 3.61},
+			[]float64{0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0}, 225, "Fm-3m", "FCC", 1},
+		{[]float64{3, 0, 0, 0, 3, 0, 0, 0, 3}, []float64{0, 0, 0, 0.5, 0.5, 0.5}, 229, "Im-3m", "BCC", 1},
+		{[]float64{3, 0, 0, 0, 3, 0, 0, 0, 5}, []float64{0, 0, 0, 0.5, 0.5, 0.5}, 139, "I4/mmm", "BCT2", 1},
+		{[]float64{5, 0, 0, 0, 5, 0, 0, 0, 3}, []float64{0, 0, 0, 0.5, 0.5, 0.5}, 139, "I4/mmm", "BCT1", 1},
+		{[]float64{4, 0, 0, 0, 3, 0, 0, 0, 5}, []float64{0.1, 0.2, 0.3}, 47, "Pmmm", "ORC", 1},
+		{[]float64{5, 0, 0, 0, 3, 0, 0, 0, 4}, []float64{0, 0, 0, 0.5, 0.5, 0}, 65, "Cmmm", "ORCC", 1},
+		{[]float64{4, 0, 0, 0, 6, 0, 0, 0, 5},
+			[]float64{0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0}, 69, "Fmmm", "ORCF2", 1},
+		{[]float64{3, 0, 0, 0, 4, 0, 0, 0, 5}, []float64{0, 0, 0, 0.5, 0.5, 0.5}, 71, "Immm", "ORCI", 1},
+		{[]float64{3, 0, 0, -1.5, 1.5 * s3, 0, 0, 0, 5}, []float64{0, 0, 0}, 191, "P6/mmm", "HEX", 1},
+		{[]float64{3, 0, 0, -1.5, 1.5 * s3, 0, 0, 0, 20},
+			[]float64{0, 0, 0, 2.0 / 3, 1.0 / 3, 1.0 / 3, 1.0 / 3, 2.0 / 3, 2.0 / 3}, 166, "R-3m", "RHL1", 1},
+		{[]float64{3, 0, 0, -1.5, 1.5 * s3, 0, 0, 0, 2},
+			[]float64{0, 0, 0, 2.0 / 3, 1.0 / 3, 1.0 / 3, 1.0 / 3, 2.0 / 3, 2.0 / 3}, 166, "R-3m", "RHL2", 1},
+		{[]float64{4, 0, 0, 0, 3, 0, 6 * math.Cos(beta), 0, 6 * math.Sin(beta)}, []float64{0.1, 0.2, 0.3},
+			10, "P2/m", "MCL", 1},
+		{[]float64{6, 0, 0, 0, 3, 0, 5 * math.Cos(beta), 0, 5 * math.Sin(beta)},
+			[]float64{0, 0, 0, 0.5, 0.5, 0}, 12, "C2/m", "MCLC", 1},
+		{[]float64{3, 0, 0, 0.5, 3.2, 0, 0.7, 0.4, 4}, []float64{0, 0, 0}, 1, "P1", "TRI", 1},
+	}
+	for _, c := range cases {
+		elem := make([]int, len(c.pos)/3)
+		conv, err := NewCell(c.lattice, c.pos, elem, false)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		kp, p, err := setyawanCurtarolo(conv, c.number, c.symbol)
+		if err != nil {
+			t.Fatalf("%s: kpath error: %v", c.name, err)
+		}
+		if !strings.HasPrefix(kp.Lattice, c.name) {
+			t.Errorf("expect lattice %s, got %s", c.name, kp.Lattice)
+		}
+		if kp.Cell.Natom != c.natom {
+			t.Errorf("%s: expect primitive cell of %d atoms, got %d", c.name, c.natom, kp.Cell.Natom)
+		}
+		if mat.Det(kp.Cell.Lattice) <= 0 {
+			t.Errorf("%s: expect right-handed primitive lattice", c.name)
+		}
+		var l mat.Dense
+		l.Mul(p, conv.Lattice)
+		if !mat.EqualApprox(&l, kp.Cell.Lattice, 1e-8) {
+			t.Errorf("%s: primitive lattice is not p·conv", c.name)
+		}
+		if kp.Points["Γ"] != [3]float64{} {
+			t.Errorf("%s: expect Γ at origin", c.name)
+		}
+		if _, err := kp.Kpoints(0.05); err != nil {
+			t.Errorf("%s: kpoints error: %v", c.name, err)
+		}
+		if c.name == "TRI" {
+			rc := &Cell{Lattice: kp.Cell.ReciprocalLatticeCrystallographic()}
+			an := rc.Angles()
+			if !(an[0] < 90 && an[1] < 90 && an[2] < 90) && !(an[0] >= 90 && an[1] >= 90 && an[2] >= 90) {
+				t.Errorf("TRI: expect reciprocal angles all acute or all obtuse, got %v", an)
+			}
+		}
+	}
+}
+
+func TestKpoints(t *testing.T) {
+	a := 3.61
+	conv, _ := NewCell(
+		[]float64{a, 0, 0, 0, a, 0, 0, 0, a},
+		[]float64{0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0},
+		[]int{29, 29, 29, 29},
+		false,
+	)
+	kp, _, _ := setyawanCurtarolo(conv, 225, "Fm-3m")
+	kp.Path = [][]string{{"Γ", "X"}, {"L", "Γ"}}
+	gx := 2 * math.Pi / a
+	gl := math.Sqrt(3) * math.Pi / a
+	kps, err := kp.Kpoints(gx / 10)
+	if err != nil {
+		t.Fatalf("kpoints error: %v", err)
+	}
+	// 11 points from Γ to X, 1 + 9 points from L to Γ
+	if len(kps) != 11+10 {
+		t.Fatalf("expect 21 k-points, got %d", len(kps))
+	}
+	if kps[0].Label != "Γ" || kps[10].Label != "X" || kps[11].Label != "L" || kps[20].Label != "Γ" || kps[5].Label != "" {
+		t.Errorf("unexpected labels %v", kps)
+	}
+	if math.Abs(kps[10].Distance-gx) > 1e-8 || kps[11].Distance != kps[10].Distance ||
+		math.Abs(kps[20].Distance-gx-gl) > 1e-8 {
+		t.Errorf("expect distances %v at X and %v at end, got %v and %v", gx, gx+gl, kps[10].Distance, kps[20].Distance)
+	}
+	if _, err := kp.Kpoints(0); err == nil {
+		t.Error("expect error for zero spacing")
+	}
+}
+
+func TestCellKPath(t *testing.T) {
+	c, _ := NewCell([]float64{0, 1.8, 1.8, 1.8, 0, 1.8, 1.8, 1.8, 0}, []float64{0, 0, 0}, []int{29}, false)
+	kp, err := c.KPath(1e-5)
+	if err != nil {
+		t.Fatalf("kpath error: %v", err)
+	}
+	if kp.Lattice != "FCC" {
+		t.Errorf("expect FCC, got %s", kp.Lattice)
+	}
+	if math.Abs(math.Abs(mat.Det(kp.Matrix))-1) > 1e-8 {
+		t.Errorf("expect unimodular matrix to primitive cell, got %v", mat.Formatted(kp.Matrix))
+	}
+}
@@ -0,0 +1,114 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// amuPerA3ToGPerCm3 convert density in amu/Å^3 to g/cm^3
+const amuPerA3ToGPerCm3 = 1.66053906660
+
+// LatticeFromParameters return lattice row vectors of lengths a, b, c and
+// angles alpha, beta, gamma in degree, with a along x and b in xy plane
+func LatticeFromParameters(a, b, c, alpha, beta, gamma float64) ([]float64, error) {
+	ca := math.Cos(alpha * math.Pi / 180)
+	cb := math.Cos(beta * math.Pi / 180)
+	cg := math.Cos(gamma * math.Pi / 180)
+	sg := math.Sin(gamma * math.Pi / 180)
+	if a <= 0 || b <= 0 || c <= 0 || math.Abs(sg) < 1e-8 {
+		return nil, fmt.Errorf("invalid cell parameters %g %g %g %g %g %g", a, b, c, alpha, beta, gamma)
+	}
+	cx := c * cb
+	cy := c * (ca - cb*cg) / sg
+	cz2 := c*c - cx*cx - cy*cy
+	if cz2 <= 0 {
+		return nil, fmt.Errorf("invalid cell parameters %g %g %g %g %g %g", a, b, c, alpha, beta, gamma)
+	}
+	lattice := []float64{
+		a, 0, 0,
+		b * cg, b * sg, 0,
+		cx, cy, math.Sqrt(cz2),
+	}
+	return lattice, nil
+}
+
+// NewCellFromParameters create cell from lattice parameters, see
+// LatticeFromParameters, position is in fraction coordinate
+func NewCellFromParameters(
+	a, b, c, alpha, beta, gamma float64,
+	position []float64,
+	types []int) (*Cell, error) {
+
+	lattice, err := LatticeFromParameters(a, b, c, alpha, beta, gamma)
+	if err != nil {
+		return nil, err
+	}
+	return NewCell(lattice, position, types, false)
+}
+
+// Lengths return lengths of lattice vectors a, b, c
+func (c *Cell) Lengths() [3]float64 {
+	var l [3]float64
+	for i := 0; i < 3; i++ {
+		l[i] = mat.Norm(c.Lattice.RowView(i), 2)
+	}
+	return l
+}
+
+// Angles return lattice angles alpha, beta, gamma in degree
+func (c *Cell) Angles() [3]float64 {
+	l := c.Lengths()
+	angle := func(i, j int) float64 {
+		cos := mat.Dot(c.Lattice.RowView(i), c.Lattice.RowView(j)) / (l[i] * l[j])
+		return math.Acos(math.Max(-1, math.Min(1, cos))) * 180 / math.Pi
+	}
+	return [3]float64{angle(1, 2), angle(0, 2), angle(0, 1)}
+}
+
+// Parameters return lattice lengths and angles in degree
+func (c *Cell) Parameters() (a, b, cc, alpha, beta, gamma float64) {
+	l := c.Lengths()
+	an := c.Angles()
+	return l[0], l[1], l[2], an[0], an[1], an[2]
+}
+
+// Volume return volume of cell
+func (c *Cell) Volume() float64 {
+	return math.Abs(mat.Det(c.Lattice))
+}
+
+// ReciprocalLattice return reciprocal lattice row vectors b_i with
+// a_i.b_j = 2π δ_ij
+func (c *Cell) ReciprocalLattice() *mat.Dense {
+	r := c.ReciprocalLatticeCrystallographic()
+	r.Scale(2*math.Pi, r)
+	return r
+}
+
+// ReciprocalLatticeCrystallographic return reciprocal lattice row vectors
+// b_i with a_i.b_j = δ_ij, i.e. without factor 2π
+func (c *Cell) ReciprocalLatticeCrystallographic() *mat.Dense {
+	var inv mat.Dense
+	inv.Inverse(c.Lattice)
+	return mat.DenseCopyOf(inv.T())
+}
+
+// NumberDensity return number of atoms per Å^3
+func (c *Cell) NumberDensity() float64 {
+	return float64(c.Natom) / c.Volume()
+}
+
+// Density return mass density in g/cm^3
+func (c *Cell) Density() (float64, error) {
+	var m float64
+	for i := 0; i < c.Natom; i++ {
+		am := NumToMass(c.Elem[i])
+		if am == 0 {
+			return 0, fmt.Errorf("unknown mass of atomic number %d", c.Elem[i])
+		}
+		m += am
+	}
+	return m / c.Volume() * amuPerA3ToGPerCm3, nil
+}
@@ -0,0 +1,80 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNewCellFromParameters(t *testing.T) {
+	c, err := NewCellFromParameters(3, 4, 5, 80, 95, 110, []float64{0, 0, 0}, []int{1})
+	if err != nil {
+		t.Fatalf("NewCellFromParameters error: %v", err)
+	}
+	a, b, cc, alpha, beta, gamma := c.Parameters()
+	got := []float64{a, b, cc, alpha, beta, gamma}
+	expected := []float64{3, 4, 5, 80, 95, 110}
+	for i := range expected {
+		if math.Abs(got[i]-expected[i]) > 1e-8 {
+			t.Errorf("parameters expected %v, got %v", expected, got)
+			break
+		}
+	}
+	if c.Lattice.At(0, 1) != 0 || c.Lattice.At(0, 2) != 0 || c.Lattice.At(1, 2) != 0 {
+		t.Errorf("expect a along x and b in xy plane, got %v", c)
+	}
+
+	_, err = NewCellFromParameters(3, 4, 5, 10, 10, 100, []float64{0, 0, 0}, []int{1})
+	if err == nil {
+		t.Error("expect error for impossible angles")
+	}
+}
+
+func TestVolumeDensity(t *testing.T) {
+	// rocksalt NaCl
+	c, _ := NewCell(
+		[]float64{5.64, 0, 0, 0, 5.64, 0, 0, 0, 5.64},
+		[]float64{
+			0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0,
+			0.5, 0.5, 0.5, 0.5, 0, 0, 0, 0.5, 0, 0, 0, 0.5,
+		},
+		[]int{11, 11, 11, 11, 17, 17, 17, 17},
+		false,
+	)
+	vol := 5.64 * 5.64 * 5.64
+	if math.Abs(c.Volume()-vol) > 1e-8 {
+		t.Errorf("volume expected %v, got %v", vol, c.Volume())
+	}
+	if math.Abs(c.NumberDensity()-8/vol) > 1e-8 {
+		t.Errorf("number density expected %v, got %v", 8/vol, c.NumberDensity())
+	}
+	d, err := c.Density()
+	if err != nil || math.Abs(d-2.1636) > 1e-3 {
+		t.Errorf("density expected 2.1636, got %v, %v", d, err)
+	}
+
+	c.Elem[0] = 118
+	if _, err := c.Density(); err == nil {
+		t.Error("expect error for unknown mass")
+	}
+}
+
+func TestReciprocalLattice(t *testing.T) {
+	c, _ := NewCell(
+		[]float64{-2, 2, 2, 2, -2, 2, 2, 2, -2},
+		[]float64{0, 0, 0},
+		[]int{1},
+		false,
+	)
+	var p mat.Dense
+	p.Mul(c.Lattice, c.ReciprocalLattice().T())
+	expected := mat.NewDense(3, 3, []float64{2 * math.Pi, 0, 0, 0, 2 * math.Pi, 0, 0, 0, 2 * math.Pi})
+	if !mat.EqualApprox(&p, expected, 1e-8) {
+		t.Errorf("a.b expected 2π δ, got %v", mat.Formatted(&p))
+	}
+	p.Mul(c.Lattice, c.ReciprocalLatticeCrystallographic().T())
+	if !mat.EqualApprox(&p, mat.NewDiagDense(3, []float64{1, 1, 1}), 1e-8) {
+		t.Errorf("a.b expected δ, got %v", mat.Formatted(&p))
+	}
+}
@@ -0,0 +1,387 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Default tolerances of StructureMatcher
+const (
+	DefaultMatchLengthTol = 0.2
+	DefaultMatchAngleTol  = 5.0
+	DefaultMatchSiteTol   = 0.3
+)
+
+// StructureMatcher decide whether two cells are the same crystal up to
+// choice of lattice, origin, order of atoms and supercell. Zero values of
+// tolerances mean the defaults
+type StructureMatcher struct {
+	// LengthTol is relative tolerance of lattice vector lengths
+	LengthTol float64
+	// AngleTol is tolerance of lattice angles in degree
+	AngleTol float64
+	// SiteTol is tolerance of site displacement in unit of (V/N)^(1/3)
+	SiteTol float64
+	// Scale cells to the same volume per atom before comparison
+	Scale bool
+	// Anonymous match atoms of any species, as long as species of one cell
+	// map one-to-one to species of the other
+	Anonymous bool
+}
+
+// Match is result of StructureMatcher.Match. The smaller cell is the one
+// with fewer atoms, or the first cell if both have the same number
+type Match struct {
+	// Matrix m of supercell of the smaller cell, whose lattice m·Lattice is
+	// the lattice of the larger cell within tolerance
+	Matrix [3][3]int
+	// Mapping give for each atom of the larger cell the atom of the smaller
+	// cell on the same site
+	Mapping []int
+	// Species map atomic number of the smaller cell to that of the larger
+	// cell, identity unless Anonymous
+	Species map[int]int
+	// RMS and Max are root mean square and largest displacement of sites,
+	// after removing the average, in unit of (V/N)^(1/3)
+	RMS float64
+	Max float64
+}
+
+// Fit return true if a and b are the same crystal
+func (sm *StructureMatcher) Fit(a, b *Cell) (bool, error) {
+	m, err := sm.Match(a, b)
+	return m != nil, err
+}
+
+// Match compare a and b and return the best match, or nil if they are
+// different crystals. The cell with more atoms must be a supercell of the
+// other
+func (sm *StructureMatcher) Match(a, b *Cell) (*Match, error) {
+	s, l := a, b
+	if a.Natom > b.Natom {
+		s, l = b, a
+	}
+	if s.Natom == 0 || l.Natom%s.Natom != 0 {
+		return nil, nil
+	}
+	k := l.Natom / s.Natom
+
+	species := sm.speciesMaps(s, l, k)
+	if len(species) == 0 {
+		return nil, nil
+	}
+
+	s = CellCopyOf(s)
+	if sm.Scale {
+		f := math.Cbrt(l.Volume() / float64(k) / s.Volume())
+		s.Lattice.Scale(f, s.Lattice)
+	}
+	sr, ms, err := s.Niggli(0)
+	if err != nil {
+		return nil, err
+	}
+	lr, ml, err := l.Niggli(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *Match
+	for _, m := range sm.supercellMatrices(sr, lr, k) {
+		sup, err := sr.SupercellMatrix(m)
+		if err != nil {
+			return nil, err
+		}
+		for _, sp := range species {
+			mapping, rms, max := sm.matchSites(sup, lr, sp)
+			if mapping == nil || (best != nil && rms >= best.RMS) {
+				continue
+			}
+			for i := range mapping {
+				// images of an atom are consecutive in supercell
+				mapping[i] /= k
+			}
+			best = &Match{Matrix: m, Mapping: mapping, Species: sp, RMS: rms, Max: max}
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	// relate to lattices of the input cells, L_l = Ml^-1 m Ms L_s
+	inv, err := inverseUnimodular(ml)
+	if err != nil {
+		return nil, err
+	}
+	best.Matrix = mulInt3(inv, mulInt3(best.Matrix, ms))
+	return best, nil
+}
+
+// Group partition cells into groups of the same crystal, each group is
+// list of indices in order, groups are ordered by their first index
+func (sm *StructureMatcher) Group(cells []*Cell) ([][]int, error) {
+	var groups [][]int
+	for i, c := range cells {
+		found := false
+		for g := range groups {
+			ok, err := sm.Fit(cells[groups[g][0]], c)
+			if err != nil {
+				return nil, fmt.Errorf("compare cell %d and %d: %v", groups[g][0], i, err)
+			}
+			if ok {
+				groups[g] = append(groups[g], i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, []int{i})
+		}
+	}
+	return groups, nil
+}
+
+func (sm *StructureMatcher) tolerances() (ltol, atol, stol float64) {
+	ltol, atol, stol = sm.LengthTol, sm.AngleTol, sm.SiteTol
+	if ltol <= 0 {
+		ltol = DefaultMatchLengthTol
+	}
+	if atol <= 0 {
+		atol = DefaultMatchAngleTol
+	}
+	if stol <= 0 {
+		stol = DefaultMatchSiteTol
+	}
+	return ltol, atol, stol
+}
+
+// speciesMaps return possible maps of species of s to species of l whose
+// numbers of atoms differ by factor k
+func (sm *StructureMatcher) speciesMaps(s, l *Cell, k int) []map[int]int {
+	cs, cl := countSpecies(s), countSpecies(l)
+	if len(cs) != len(cl) {
+		return nil
+	}
+	if !sm.Anonymous {
+		sp := make(map[int]int)
+		for e, n := range cs {
+			if cl[e] != n*k {
+				return nil
+			}
+			sp[e] = e
+		}
+		return []map[int]int{sp}
+	}
+
+	var es, el []int
+	for e := range cs {
+		es = append(es, e)
+	}
+	for e := range cl {
+		el = append(el, e)
+	}
+	sort.Ints(es)
+	sort.Ints(el)
+	var maps []map[int]int
+	used := make([]bool, len(el))
+	cur := make(map[int]int)
+	var assign func(i int)
+	assign = func(i int) {
+		if i == len(es) {
+			sp := make(map[int]int)
+			for e, f := range cur {
+				sp[e] = f
+			}
+			maps = append(maps, sp)
+			return
+		}
+		for j, f := range el {
+			if !used[j] && cl[f] == cs[es[i]]*k {
+				used[j] = true
+				cur[es[i]] = f
+				assign(i + 1)
+				used[j] = false
+			}
+		}
+	}
+	assign(0)
+	return maps
+}
+
+// supercellMatrices return integer matrices m with det k such that lattice
+// m·s.Lattice has lengths and angles of l.Lattice within tolerance
+func (sm *StructureMatcher) supercellMatrices(s, l *Cell, k int) [][3][3]int {
+	ltol, atol, _ := sm.tolerances()
+	target := l.Lengths()
+	angles := l.Angles()
+	h := s.heights()
+	var maxLen float64
+	for _, v := range target {
+		maxLen = math.Max(maxLen, v*(1+ltol))
+	}
+	var reach [3]int
+	for i := 0; i < 3; i++ {
+		reach[i] = int(math.Ceil(maxLen / h[i]))
+	}
+
+	// lattice vectors of s with length of each target vector
+	var cand [3][][3]int
+	for x := -reach[0]; x <= reach[0]; x++ {
+		for y := -reach[1]; y <= reach[1]; y++ {
+			for z := -reach[2]; z <= reach[2]; z++ {
+				v := [3]int{x, y, z}
+				n := math.Sqrt(s.dot(v, v))
+				for i := 0; i < 3; i++ {
+					if math.Abs(n-target[i]) <= ltol*target[i] {
+						cand[i] = append(cand[i], v)
+					}
+				}
+			}
+		}
+	}
+
+	angle := func(u, v [3]int) float64 {
+		cos := s.dot(u, v) / math.Sqrt(s.dot(u, u)*s.dot(v, v))
+		return math.Acos(math.Max(-1, math.Min(1, cos))) * 180 / math.Pi
+	}
+	var ms [][3][3]int
+	for _, u := range cand[0] {
+		for _, v := range cand[1] {
+			if math.Abs(angle(u, v)-angles[2]) > atol {
+				continue
+			}
+			for _, w := range cand[2] {
+				m := [3][3]int{u, v, w}
+				if detInt3(m) != k {
+					continue
+				}
+				if math.Abs(angle(v, w)-angles[0]) > atol || math.Abs(angle(u, w)-angles[1]) > atol {
+					continue
+				}
+				ms = append(ms, m)
+			}
+		}
+	}
+	return ms
+}
+
+// matchSites find origin shift and one-to-one map of atoms of l to atoms
+// of s of mapped species with smallest RMS displacement within tolerance.
+// Fraction coordinates of s are taken in lattice of l. mapping is nil if
+// no such map is found
+func (sm *StructureMatcher) matchSites(s, l *Cell, species map[int]int) (mapping []int, rms, max float64) {
+	_, _, stol := sm.tolerances()
+	unit := math.Cbrt(l.Volume() / float64(l.Natom))
+
+	// anchor atom of s of the rarest species
+	count := countSpecies(s)
+	anchor := 0
+	for i := 0; i < s.Natom; i++ {
+		if count[s.Elem[i]] < count[s.Elem[anchor]] {
+			anchor = i
+		}
+	}
+
+	rms = math.Inf(1)
+	for q := 0; q < l.Natom; q++ {
+		if l.Elem[q] != species[s.Elem[anchor]] {
+			continue
+		}
+		var shift [3]float64
+		for d := 0; d < 3; d++ {
+			shift[d] = l.Position.At(q, d) - s.Position.At(anchor, d)
+		}
+		m, disp := nearestSites(s, l, species, shift, stol*unit)
+		if m == nil {
+			continue
+		}
+		// remove average displacement
+		var mean [3]float64
+		for _, v := range disp {
+			for d := 0; d < 3; d++ {
+				mean[d] += v[d] / float64(len(disp))
+			}
+		}
+		var sum, mx float64
+		for _, v := range disp {
+			var n float64
+			for d := 0; d < 3; d++ {
+				n += (v[d] - mean[d]) * (v[d] - mean[d])
+			}
+			sum += n
+			mx = math.Max(mx, math.Sqrt(n))
+		}
+		r := math.Sqrt(sum/float64(len(disp))) / unit
+		if mx/unit <= stol && r < rms {
+			mapping, rms, max = m, r, mx/unit
+		}
+	}
+	if mapping == nil {
+		return nil, 0, 0
+	}
+	return mapping, rms, max
+}
+
+// nearestSites map each atom of l to nearest atom of s shifted by shift,
+// and return cartesian displacements. mapping is nil if an atom has no
+// atom of s within tol or two atoms map to the same atom
+func nearestSites(s, l *Cell, species map[int]int, shift [3]float64, tol float64) ([]int, [][3]float64) {
+	mapping := make([]int, l.Natom)
+	disp := make([][3]float64, l.Natom)
+	used := make([]bool, s.Natom)
+	for j := 0; j < l.Natom; j++ {
+		mapping[j] = -1
+		best := tol
+		for i := 0; i < s.Natom; i++ {
+			if species[s.Elem[i]] != l.Elem[j] {
+				continue
+			}
+			var d [3]float64
+			for k := 0; k < 3; k++ {
+				d[k] = l.Position.At(j, k) - s.Position.At(i, k) - shift[k]
+				d[k] -= math.Floor(d[k] + 0.5)
+			}
+			n, _ := l.neighbor(j, [3]int{}, d, math.Inf(1))
+			if n.Distance <= best {
+				best = n.Distance
+				mapping[j] = i
+				disp[j] = n.Vector
+			}
+		}
+		if mapping[j] < 0 || used[mapping[j]] {
+			return nil, nil
+		}
+		used[mapping[j]] = true
+	}
+	return mapping, disp
+}
+
+func countSpecies(c *Cell) map[int]int {
+	count := make(map[int]int)
+	for _, e := range c.Elem {
+		count[e]++
+	}
+	return count
+}
+
+// inverseUnimodular return inverse of integer matrix with det ±1
+func inverseUnimodular(m [3][3]int) ([3][3]int, error) {
+	var inv [3][3]int
+	a := mat.NewDense(3, 3, nil)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			a.Set(i, j, float64(m[i][j]))
+		}
+	}
+	var b mat.Dense
+	if err := b.Inverse(a); err != nil {
+		return inv, err
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			inv[i][j] = int(math.Round(b.At(i, j)))
+		}
+	}
+	return inv, nil
+}
@@ -0,0 +1,112 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+)
+
+func rocksalt(a float64, cation, anion int) *Cell {
+	c, _ := NewCell(
+		[]float64{a, 0, 0, 0, a, 0, 0, 0, a},
+		[]float64{
+			0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0,
+			0.5, 0.5, 0.5, 0.5, 0, 0, 0, 0.5, 0, 0, 0, 0.5,
+		},
+		[]int{cation, cation, cation, cation, anion, anion, anion, anion},
+		false,
+	)
+	return c
+}
+
+func TestStructureMatcher(t *testing.T) {
+	conv := rocksalt(5.64, 11, 17)
+	// primitive cell, rotated about z by 30°, shifted origin and atoms
+	// swapped
+	h := 5.64 / 2
+	lattice := []float64{0, h, h, h, 0, h, h, h, 0}
+	cs, sn := math.Cos(math.Pi/6), math.Sin(math.Pi/6)
+	for i := 0; i < 3; i++ {
+		x, y := lattice[i*3], lattice[i*3+1]
+		lattice[i*3], lattice[i*3+1] = cs*x-sn*y, sn*x+cs*y
+	}
+	prim, _ := NewCell(lattice, []float64{0.6, 0.6, 0.6, 0.1, 0.1, 0.1}, []int{17, 11}, false)
+
+	sm := &StructureMatcher{}
+	m, err := sm.Match(prim, conv)
+	if err != nil || m == nil {
+		t.Fatalf("expect primitive and conventional rocksalt to match, got %v %v", m, err)
+	}
+	if m.RMS > 1e-8 || m.Max > 1e-8 {
+		t.Errorf("expect zero displacement, got rms %v max %v", m.RMS, m.Max)
+	}
+	for j, i := range m.Mapping {
+		if prim.Elem[i] != conv.Elem[j] {
+			t.Errorf("atom %d of conventional cell mapped to atom %d of another species", j, i)
+		}
+	}
+	sup, err := prim.SupercellMatrix(m.Matrix)
+	if err != nil {
+		t.Fatalf("supercell of match matrix %v: %v", m.Matrix, err)
+	}
+	a, b, c, alpha, beta, gamma := sup.Parameters()
+	for i, v := range []float64{a, b, c, alpha, beta, gamma} {
+		if expected := []float64{5.64, 5.64, 5.64, 90, 90, 90}[i]; math.Abs(v-expected) > 1e-6 {
+			t.Errorf("expect match matrix %v to give conventional cell, got %v", m.Matrix, sup)
+			break
+		}
+	}
+	// both orders give the same match
+	if ok, _ := sm.Fit(conv, prim); !ok {
+		t.Error("expect match with cells swapped")
+	}
+
+	// small displacement matches with nonzero rms, large does not
+	moved := CellCopyOf(conv)
+	moved.Position.Set(0, 0, 0.02)
+	m, _ = sm.Match(conv, moved)
+	if m == nil || m.RMS <= 0 {
+		t.Errorf("expect match with nonzero rms for small displacement, got %v", m)
+	}
+	moved.Position.Set(0, 0, 0.25)
+	if ok, _ := sm.Fit(conv, moved); ok {
+		t.Error("expect no match for large displacement")
+	}
+
+	// CsCl structure with the same species is different
+	cscl, _ := NewCell([]float64{4, 0, 0, 0, 4, 0, 0, 0, 4}, []float64{0, 0, 0, 0.5, 0.5, 0.5}, []int{11, 17}, false)
+	if ok, _ := (&StructureMatcher{Scale: true}).Fit(prim, cscl); ok {
+		t.Error("expect rocksalt and CsCl not to match")
+	}
+}
+
+func TestStructureMatcherAnonymous(t *testing.T) {
+	nacl := rocksalt(5.64, 11, 17)
+	mgo := rocksalt(4.21, 12, 8)
+	if ok, _ := (&StructureMatcher{Scale: true}).Fit(nacl, mgo); ok {
+		t.Error("expect no match of different species")
+	}
+	m, _ := (&StructureMatcher{Scale: true, Anonymous: true}).Match(nacl, mgo)
+	if m == nil {
+		t.Fatal("expect anonymous match of NaCl and MgO")
+	}
+	if len(m.Species) != 2 || m.Species[11] == 0 || m.Species[17] == 0 {
+		t.Errorf("expect species map of Na and Cl, got %v", m.Species)
+	}
+	if ok, _ := (&StructureMatcher{Anonymous: true}).Fit(nacl, mgo); ok {
+		t.Error("expect no match without scaling")
+	}
+}
+
+func TestStructureMatcherGroup(t *testing.T) {
+	nacl := rocksalt(5.64, 11, 17)
+	super, _ := nacl.Supercell(1, 1, 2)
+	reduced, _, _ := super.Niggli(0)
+	cscl, _ := NewCell([]float64{4, 0, 0, 0, 4, 0, 0, 0, 4}, []float64{0, 0, 0, 0.5, 0.5, 0.5}, []int{11, 17}, false)
+	groups, err := (&StructureMatcher{}).Group([]*Cell{nacl, cscl, super, reduced})
+	if err != nil {
+		t.Fatalf("group error: %v", err)
+	}
+	if len(groups) != 2 || len(groups[0]) != 3 || groups[1][0] != 1 {
+		t.Errorf("expect groups [[0 2 3] [1]], got %v", groups)
+	}
+}
@@ -0,0 +1,244 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Neighbor is a periodic image of an atom within cutoff of another atom
+type Neighbor struct {
+	// Index of neighbor atom
+	Index int
+	// Image is lattice translation of neighbor, neighbor position is
+	// Position[Index] + Image in fraction coordinate
+	Image [3]int
+	// Distance in cartesian
+	Distance float64
+	// Vector from atom to neighbor in cartesian
+	Vector [3]float64
+}
+
+// minimumImageAtoms is the largest cell for which Neighbors check all
+// pairs by minimum image instead of binning atoms
+const minimumImageAtoms = 32
+
+// Neighbors return, for each atom, neighbors within cutoff sorted by
+// distance. Periodic images of the atom itself are included. Minimum image
+// is used for small cells when cutoff is less than half of every cell
+// height, otherwise atoms are binned so large and skewed cells are handled
+// in linear time
+func (c *Cell) Neighbors(cutoff float64) ([][]Neighbor, error) {
+	if cutoff <= 0 {
+		return nil, fmt.Errorf("cutoff must be positive, got %g", cutoff)
+	}
+	h := c.heights()
+	frac, floor := c.wrappedPositions()
+	var nbs [][]Neighbor
+	if c.Natom <= minimumImageAtoms && cutoff < math.Min(h[0], math.Min(h[1], h[2]))/2 {
+		nbs = c.minimumImageNeighbors(cutoff, frac)
+	} else {
+		nbs = c.binnedNeighbors(cutoff, frac, h)
+	}
+	for i := range nbs {
+		for k := range nbs[i] {
+			// image relative to unwrapped positions
+			n := &nbs[i][k]
+			for d := 0; d < 3; d++ {
+				n.Image[d] += floor[i][d] - floor[n.Index][d]
+			}
+		}
+		sortNeighbors(nbs[i])
+	}
+	return nbs, nil
+}
+
+// minimumImageNeighbors check every pair once with nearest fraction image,
+// valid when cutoff is less than half of the smallest cell height
+func (c *Cell) minimumImageNeighbors(cutoff float64, frac [][3]float64) [][]Neighbor {
+	nbs := make([][]Neighbor, c.Natom)
+	for i := 0; i < c.Natom; i++ {
+		for j := 0; j < c.Natom; j++ {
+			if i == j {
+				continue
+			}
+			var img [3]int
+			var d [3]float64
+			for k := 0; k < 3; k++ {
+				d[k] = frac[j][k] - frac[i][k]
+				img[k] = -int(math.Floor(d[k] + 0.5))
+			}
+			if n, ok := c.neighbor(j, img, d, cutoff); ok {
+				nbs[i] = append(nbs[i], n)
+			}
+		}
+	}
+	return nbs
+}
+
+// binnedNeighbors sort atoms into bins whose height is at least cutoff,
+// then search bins around each atom including periodic images
+func (c *Cell) binnedNeighbors(cutoff float64, frac [][3]float64, h [3]float64) [][]Neighbor {
+	var nb, reach [3]int
+	for d := 0; d < 3; d++ {
+		nb[d] = int(math.Max(1, math.Floor(h[d]/cutoff)))
+		// bins to search on each side, more than one if cell is thinner
+		// than cutoff
+		reach[d] = int(math.Ceil(cutoff * float64(nb[d]) / h[d]))
+	}
+	binOf := func(f [3]float64) [3]int {
+		var b [3]int
+		for d := 0; d < 3; d++ {
+			b[d] = int(f[d] * float64(nb[d]))
+			if b[d] >= nb[d] {
+				b[d] = nb[d] - 1
+			}
+		}
+		return b
+	}
+	bins := make(map[[3]int][]int)
+	for i := 0; i < c.Natom; i++ {
+		b := binOf(frac[i])
+		bins[b] = append(bins[b], i)
+	}
+
+	nbs := make([][]Neighbor, c.Natom)
+	for i := 0; i < c.Natom; i++ {
+		b := binOf(frac[i])
+		for x := -reach[0]; x <= reach[0]; x++ {
+			for y := -reach[1]; y <= reach[1]; y++ {
+				for z := -reach[2]; z <= reach[2]; z++ {
+					off := [3]int{b[0] + x, b[1] + y, b[2] + z}
+					var key, img [3]int
+					for d := 0; d < 3; d++ {
+						img[d] = floorDiv(off[d], nb[d])
+						key[d] = off[d] - img[d]*nb[d]
+					}
+					for _, j := range bins[key] {
+						if j == i && img == [3]int{} {
+							continue
+						}
+						var d [3]float64
+						for k := 0; k < 3; k++ {
+							d[k] = frac[j][k] - frac[i][k]
+						}
+						if n, ok := c.neighbor(j, img, d, cutoff); ok {
+							nbs[i] = append(nbs[i], n)
+						}
+					}
+				}
+			}
+		}
+	}
+	return nbs
+}
+
+// neighbor build Neighbor of atom j at image img, d is fraction difference
+// of wrapped positions, ok is false if it is beyond cutoff
+func (c *Cell) neighbor(j int, img [3]int, d [3]float64, cutoff float64) (Neighbor, bool) {
+	var v [3]float64
+	for k := 0; k < 3; k++ {
+		f := d[k] + float64(img[k])
+		for m := 0; m < 3; m++ {
+			v[m] += f * c.Lattice.At(k, m)
+		}
+	}
+	dist := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+	if dist > cutoff {
+		return Neighbor{}, false
+	}
+	return Neighbor{Index: j, Image: img, Distance: dist, Vector: v}, true
+}
+
+// Distance return shortest distance between atom i and any periodic image
+// of atom j
+func (c *Cell) Distance(i, j int) float64 {
+	h := c.heights()
+	var d [3]float64
+	for k := 0; k < 3; k++ {
+		d[k] = c.Position.At(j, k) - c.Position.At(i, k)
+		d[k] -= math.Floor(d[k] + 0.5)
+	}
+	n, _ := c.neighbor(j, [3]int{}, d, math.Inf(1))
+	best := n.Distance
+	// nearest fraction image bounds the search, images farther along an
+	// axis than best/height cannot be closer
+	var reach [3]int
+	for k := 0; k < 3; k++ {
+		reach[k] = int(math.Ceil(best/h[k] + 0.5))
+	}
+	for x := -reach[0]; x <= reach[0]; x++ {
+		for y := -reach[1]; y <= reach[1]; y++ {
+			for z := -reach[2]; z <= reach[2]; z++ {
+				n, _ := c.neighbor(j, [3]int{x, y, z}, d, math.Inf(1))
+				best = math.Min(best, n.Distance)
+			}
+		}
+	}
+	return best
+}
+
+// DistanceMatrix return shortest periodic distances between all atoms
+func (c *Cell) DistanceMatrix() *mat.SymDense {
+	m := mat.NewSymDense(c.Natom, nil)
+	for i := 0; i < c.Natom; i++ {
+		for j := i + 1; j < c.Natom; j++ {
+			m.SetSym(i, j, c.Distance(i, j))
+		}
+	}
+	return m
+}
+
+// heights return distances between opposite faces of cell
+func (c *Cell) heights() [3]float64 {
+	r := c.ReciprocalLatticeCrystallographic()
+	var h [3]float64
+	for i := 0; i < 3; i++ {
+		h[i] = 1 / mat.Norm(r.RowView(i), 2)
+	}
+	return h
+}
+
+// wrappedPositions return positions wrapped into [0, 1) and the lattice
+// translation removed by wrapping
+func (c *Cell) wrappedPositions() ([][3]float64, [][3]int) {
+	frac := make([][3]float64, c.Natom)
+	floor := make([][3]int, c.Natom)
+	for i := 0; i < c.Natom; i++ {
+		for k := 0; k < 3; k++ {
+			v := c.Position.At(i, k)
+			fl := math.Floor(v)
+			frac[i][k] = v - fl
+			floor[i][k] = int(fl)
+		}
+	}
+	return frac, floor
+}
+
+func sortNeighbors(ns []Neighbor) {
+	sort.Slice(ns, func(a, b int) bool {
+		if ns[a].Distance != ns[b].Distance {
+			return ns[a].Distance < ns[b].Distance
+		}
+		if ns[a].Index != ns[b].Index {
+			return ns[a].Index < ns[b].Index
+		}
+		for d := 0; d < 3; d++ {
+			if ns[a].Image[d] != ns[b].Image[d] {
+				return ns[a].Image[d] < ns[b].Image[d]
+			}
+		}
+		return false
+	})
+}
+
+// floorDiv return floor(a/b) for b > 0
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
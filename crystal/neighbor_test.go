@@ -0,0 +1,156 @@
+package crystal
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestNeighborsSimpleCubic(t *testing.T) {
+	c, _ := NewCell(
+		[]float64{3, 0, 0, 0, 3, 0, 0, 0, 3},
+		[]float64{0, 0, 0},
+		[]int{29},
+		false,
+	)
+	for _, tc := range []struct {
+		cutoff float64
+		n      int
+	}{
+		{2.9, 0},
+		{3.1, 6},
+		{4.3, 18},
+		{5.3, 26},
+	} {
+		nbs, err := c.Neighbors(tc.cutoff)
+		if err != nil {
+			t.Fatalf("neighbors error: %v", err)
+		}
+		if len(nbs[0]) != tc.n {
+			t.Errorf("cutoff %v: expect %d neighbors, got %d", tc.cutoff, tc.n, len(nbs[0]))
+		}
+	}
+	if _, err := c.Neighbors(0); err == nil {
+		t.Error("expect error for zero cutoff")
+	}
+}
+
+// bruteNeighbors count images of all atoms within cutoff
+func bruteNeighbors(c *Cell, cutoff float64, n int) [][]Neighbor {
+	nbs := make([][]Neighbor, c.Natom)
+	for i := 0; i < c.Natom; i++ {
+		for j := 0; j < c.Natom; j++ {
+			var d [3]float64
+			for k := 0; k < 3; k++ {
+				d[k] = c.Position.At(j, k) - c.Position.At(i, k)
+			}
+			for x := -n; x <= n; x++ {
+				for y := -n; y <= n; y++ {
+					for z := -n; z <= n; z++ {
+						if i == j && x == 0 && y == 0 && z == 0 {
+							continue
+						}
+						if nb, ok := c.neighbor(j, [3]int{x, y, z}, d, cutoff); ok {
+							nbs[i] = append(nbs[i], nb)
+						}
+					}
+				}
+			}
+		}
+		sortNeighbors(nbs[i])
+	}
+	return nbs
+}
+
+func TestNeighborsSkewed(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	pos := make([]float64, 3*12)
+	for i := range pos {
+		// include positions outside [0, 1)
+		pos[i] = rnd.Float64()*3 - 1
+	}
+	types := make([]int, 12)
+	c, _ := NewCell(
+		[]float64{4, 0, 0, 3.5, 1.5, 0, 1, 1, 5},
+		pos,
+		types,
+		false,
+	)
+	for _, cutoff := range []float64{0.6, 2.5, 6} {
+		nbs, err := c.Neighbors(cutoff)
+		if err != nil {
+			t.Fatalf("neighbors error: %v", err)
+		}
+		expected := bruteNeighbors(c, cutoff, 8)
+		for i := range expected {
+			if len(nbs[i]) != len(expected[i]) {
+				t.Errorf("cutoff %v atom %d: expect %d neighbors, got %d", cutoff, i, len(expected[i]), len(nbs[i]))
+				continue
+			}
+			for k := range expected[i] {
+				if nbs[i][k].Index != expected[i][k].Index || nbs[i][k].Image != expected[i][k].Image ||
+					math.Abs(nbs[i][k].Distance-expected[i][k].Distance) > 1e-10 {
+					t.Errorf("cutoff %v atom %d: expect %v, got %v", cutoff, i, expected[i][k], nbs[i][k])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestNeighborsMinimumImage(t *testing.T) {
+	// both code paths must agree when minimum image is valid
+	c, _ := NewCell(
+		[]float64{6, 0, 0, 1, 6, 0, 0.5, 0.5, 6},
+		[]float64{0, 0, 0, 0.1, 0.4, 0.2, 0.5, 0.9, 0.5, 0.8, 0.3, 0.95},
+		[]int{29, 29, 8, 8},
+		false,
+	)
+	frac, _ := c.wrappedPositions()
+	h := c.heights()
+	cutoff := 0.49 * math.Min(h[0], math.Min(h[1], h[2]))
+	mi := c.minimumImageNeighbors(cutoff, frac)
+	binned := c.binnedNeighbors(cutoff, frac, h)
+	for i := range mi {
+		sortNeighbors(mi[i])
+		sortNeighbors(binned[i])
+		if len(mi[i]) != len(binned[i]) {
+			t.Fatalf("atom %d: minimum image found %d neighbors, binning %d", i, len(mi[i]), len(binned[i]))
+		}
+		for k := range mi[i] {
+			if mi[i][k].Index != binned[i][k].Index || mi[i][k].Image != binned[i][k].Image {
+				t.Errorf("atom %d: minimum image %v, binning %v", i, mi[i][k], binned[i][k])
+			}
+		}
+	}
+}
+
+func TestDistanceMatrix(t *testing.T) {
+	c, _ := NewCell(
+		[]float64{4, 0, 0, 3.9, 0.5, 0, 0, 0, 4},
+		[]float64{0, 0, 0, 0.1, 0.9, 0.5, 0.95, 0.05, 0},
+		[]int{1, 1, 1},
+		false,
+	)
+	m := c.DistanceMatrix()
+	nbs := bruteNeighbors(c, 100, 6)
+	for i := 0; i < c.Natom; i++ {
+		if m.At(i, i) != 0 {
+			t.Errorf("diagonal expected 0, got %v", m.At(i, i))
+		}
+		for j := 0; j < c.Natom; j++ {
+			if i == j {
+				continue
+			}
+			best := math.Inf(1)
+			for _, n := range nbs[i] {
+				if n.Index == j {
+					best = math.Min(best, n.Distance)
+				}
+			}
+			if math.Abs(m.At(i, j)-best) > 1e-10 {
+				t.Errorf("distance %d-%d expected %v, got %v", i, j, best, m.At(i, j))
+			}
+		}
+	}
+}
@@ -2,6 +2,9 @@ package crystal
 
 import (
   "fmt"
+  "math"
+  "strconv"
+  "strings"
 
 	"gonum.org/v1/gonum/mat"
 )
@@ -10,6 +13,86 @@ type Rotation struct {
   data *mat.Dense
 }
 
+// NewRotation create rotation from matrix in fraction coordinate, x' = m x
+func NewRotation(m [3][3]float64) Rotation {
+  data := mat.NewDense(3, 3, nil)
+  for i:=0; i<3; i++ {
+    for j:=0; j<3; j++ {
+      data.Set(i, j, m[i][j])
+    }
+  }
+  return Rotation{data: data}
+}
+
+// At return element (i, j) of rotation matrix in fraction coordinate
+func (r Rotation) At(i, j int) float64 {
+  return r.data.At(i, j)
+}
+
+// Matrix return rotation matrix in fraction coordinate
+func (r Rotation) Matrix() [3][3]float64 {
+  var m [3][3]float64
+  for i:=0; i<3; i++ {
+    for j:=0; j<3; j++ {
+      m[i][j] = r.data.At(i, j)
+    }
+  }
+  return m
+}
+
+// Det return determinant, 1 for proper and -1 for improper rotation
+func (r Rotation) Det() int {
+  return int(math.Round(mat.Det(r.data)))
+}
+
+// Trace return trace of rotation matrix
+func (r Rotation) Trace() int {
+  return int(math.Round(mat.Trace(r.data)))
+}
+
+// Apply return rotated fraction point
+func (r Rotation) Apply(p [3]float64) [3]float64 {
+  var q [3]float64
+  for i:=0; i<3; i++ {
+    for j:=0; j<3; j++ {
+      q[i] += r.data.At(i, j) * p[j]
+    }
+  }
+  return q
+}
+
+// Mul return rotation r·o, i.e. o applied first
+func (r Rotation) Mul(o Rotation) Rotation {
+  var m mat.Dense
+  m.Mul(r.data, o.data)
+  return Rotation{data: &m}
+}
+
+// Inverse return inverse rotation
+func (r Rotation) Inverse() Rotation {
+  var m mat.Dense
+  m.Inverse(r.data)
+  for i:=0; i<3; i++ {
+    for j:=0; j<3; j++ {
+      // rotations are integer in lattice basis, clean round off
+      if v := math.Round(m.At(i, j)); math.Abs(v-m.At(i, j)) < 1e-8 {
+        m.Set(i, j, v)
+      }
+    }
+  }
+  return Rotation{data: &m}
+}
+
+// Cartesian return rotation matrix in cartesian coordinate of lattice row
+// vectors, L^T R L^-T
+func (r Rotation) Cartesian(lattice *mat.Dense) *mat.Dense {
+  var inv, m mat.Dense
+  inv.Inverse(lattice.T())
+  m.Mul(lattice.T(), r.data)
+  m.Mul(&m, &inv)
+  return &m
+}
+
 func (r Rotation) String() string {
   fa := mat.Formatted(r.data, mat.Squeeze())
   return fmt.Sprintf("%v", fa)
@@ -19,7 +102,583 @@ type Translation struct {
   data *mat.VecDense
 }
 
+// NewTranslation create translation from vector in fraction coordinate
+func NewTranslation(v [3]float64) Translation {
+  return Translation{data: mat.NewVecDense(3, []float64{v[0], v[1], v[2]})}
+}
+
+// AtVec return element i of translation in fraction coordinate
+func (t Translation) AtVec(i int) float64 {
+  return t.data.AtVec(i)
+}
+
+// Vector return translation in fraction coordinate
+func (t Translation) Vector() [3]float64 {
+  return [3]float64{t.data.AtVec(0), t.data.AtVec(1), t.data.AtVec(2)}
+}
+
+// Cartesian return translation in cartesian coordinate of lattice row
+// vectors
+func (t Translation) Cartesian(lattice *mat.Dense) [3]float64 {
+  var v [3]float64
+  for i:=0; i<3; i++ {
+    for j:=0; j<3; j++ {
+      v[j] += t.data.AtVec(i) * lattice.At(i, j)
+    }
+  }
+  return v
+}
+
 func (t Translation) String() string {
   fa := mat.Formatted(t.data.T(), mat.Squeeze())
   return fmt.Sprintf("%v", fa)
 }
+
+// Operation is space group operation x' = R x + t in fraction coordinate
+type Operation struct {
+  Rotation    Rotation
+  Translation Translation
+}
+
+// ParseXYZ parse operation such as "-y,x-y,z+1/2" as used by CIF and the
+// International Tables
+func ParseXYZ(op string) (Operation, error) {
+  var rot [3][3]float64
+  var trans [3]float64
+  s := strings.ToLower(strings.Join(strings.Fields(op), ""))
+  parts := strings.Split(s, ",")
+  if len(parts) != 3 {
+    return Operation{}, fmt.Errorf("symmetry operation %q: expect 3 components", op)
+  }
+  for i, p := range parts {
+    if p == "" {
+      return Operation{}, fmt.Errorf("symmetry operation %q: empty component", op)
+    }
+    for len(p) > 0 {
+      sign := 1.0
+      if p[0] == '+' || p[0] == '-' {
+        if p[0] == '-' {
+          sign = -1
+        }
+        p = p[1:]
+      }
+      // term is coefficient and/or one of x, y, z up to the next sign
+      end := strings.IndexAny(p, "+-")
+      if end < 0 {
+        end = len(p)
+      }
+      term := p[:end]
+      p = p[end:]
+      if term == "" {
+        return Operation{}, fmt.Errorf("symmetry operation %q: dangling sign", op)
+      }
+      axis := strings.IndexAny(term, "xyz")
+      if axis < 0 {
+        v, err := parseFraction(term)
+        if err != nil {
+          return Operation{}, fmt.Errorf("symmetry operation %q: %v", op, err)
+        }
+        trans[i] += sign * v
+        continue
+      }
+      coef := 1.0
+      if c := strings.TrimSuffix(term[:axis], "*"); c != "" {
+        v, err := parseFraction(c)
+        if err != nil {
+          return Operation{}, fmt.Errorf("symmetry operation %q: %v", op, err)
+        }
+        coef = v
+      }
+      if axis != len(term)-1 {
+        return Operation{}, fmt.Errorf("symmetry operation %q: bad term %q", op, term)
+      }
+      rot[i][int(term[axis]-'x')] += sign * coef
+    }
+  }
+  return Operation{NewRotation(rot), NewTranslation(trans)}, nil
+}
+
+// Apply return fraction point transformed by o, not wrapped into the cell
+func (o Operation) Apply(p [3]float64) [3]float64 {
+  q := o.Rotation.Apply(p)
+  for i:=0; i<3; i++ {
+    q[i] += o.Translation.AtVec(i)
+  }
+  return q
+}
+
+// ApplyCell return copy of c with positions transformed by o and wrapped
+// into [0, 1). The lattice is kept, so a symmetry operation of c give the
+// same structure with atoms permuted
+func (o Operation) ApplyCell(c *Cell) *Cell {
+  r := CellCopyOf(c)
+  for i:=0; i<c.Natom; i++ {
+    p := o.Apply([3]float64{c.Position.At(i, 0), c.Position.At(i, 1), c.Position.At(i, 2)})
+    for j:=0; j<3; j++ {
+      r.Position.Set(i, j, p[j]-math.Floor(p[j]))
+    }
+  }
+  return r
+}
+
+// Compose return operation o·p, i.e. p applied first
+func (o Operation) Compose(p Operation) Operation {
+  t := o.Apply(p.Translation.Vector())
+  return Operation{o.Rotation.Mul(p.Rotation), NewTranslation(t)}
+}
+
+// Inverse return inverse operation (R^-1, -R^-1 t)
+func (o Operation) Inverse() Operation {
+  ri := o.Rotation.Inverse()
+  t := ri.Apply(o.Translation.Vector())
+  for i:=0; i<3; i++ {
+    t[i] = -t[i]
+  }
+  return Operation{ri, NewTranslation(t)}
+}
+
+// Reduced return o with translation wrapped into [0, 1)
+func (o Operation) Reduced() Operation {
+  t := o.Translation.Vector()
+  for i:=0; i<3; i++ {
+    t[i] -= math.Floor(t[i] + 1e-8)
+  }
+  return Operation{o.Rotation, NewTranslation(t)}
+}
+
+// Cartesian return rotation and translation in cartesian coordinate of
+// lattice row vectors
+func (o Operation) Cartesian(lattice *mat.Dense) (*mat.Dense, [3]float64) {
+  return o.Rotation.Cartesian(lattice), o.Translation.Cartesian(lattice)
+}
+
+// OperationInfo describe geometric type of an operation
+type OperationInfo struct {
+  // Order is 1, 2, 3, 4, 6 for rotation and -1, -2, -3, -4, -6 for
+  // rotoinversion, -2 being a mirror
+  Order int
+  // Sense is +1 or -1 for counter-clockwise or clockwise rotation about
+  // Axis, 0 for order 1, 2, -1 and -2
+  Sense int
+  // Axis is rotation axis, or mirror normal, in lattice basis with first
+  // nonzero component positive. Zero for 1 and -1
+  Axis [3]int
+  // Intrinsic is screw or glide component of translation
+  Intrinsic [3]float64
+}
+
+// Info return order, axis and screw or glide component of o
+func (o Operation) Info() (OperationInfo, error) {
+  var info OperationInfo
+  det := o.Rotation.Det()
+  orders := map[int]int{3: 1, -1: 2, 0: 3, 1: 4, 2: 6}
+  n, ok := orders[det*o.Rotation.Trace()]
+  if !ok || (det != 1 && det != -1) {
+    return info, fmt.Errorf("%v is not a crystallographic rotation", o.Rotation)
+  }
+  info.Order = det * n
+  // order of W itself, rotoinversion of odd order n has order 2n
+  k := n
+  if det == -1 && n%2 == 1 {
+    k = 2 * n
+  }
+  w := o.Rotation.ints()
+  pw := identity3()
+  for j:=0; j<k; j++ {
+    pw = mulInt3(w, pw)
+  }
+  if pw != identity3() {
+    return info, fmt.Errorf("%v is not a crystallographic rotation", o.Rotation)
+  }
+
+  // proper part of rotation
+  var wp [3][3]int
+  for i:=0; i<3; i++ {
+    for j:=0; j<3; j++ {
+      wp[i][j] = det * w[i][j]
+    }
+  }
+  if n > 1 {
+    // sum of powers of proper part projects onto axis
+    var y, pow [3][3]int
+    pow = identity3()
+    for k:=0; k<n; k++ {
+      for i:=0; i<3; i++ {
+        for j:=0; j<3; j++ {
+          y[i][j] += pow[i][j]
+        }
+      }
+      pow = mulInt3(wp, pow)
+    }
+    best := 0
+    for j:=0; j<3; j++ {
+      norm := y[0][j]*y[0][j] + y[1][j]*y[1][j] + y[2][j]*y[2][j]
+      if norm > best {
+        best = norm
+        info.Axis = [3]int{y[0][j], y[1][j], y[2][j]}
+      }
+    }
+    info.Axis = primitiveDirection(info.Axis)
+  }
+  if n > 2 {
+    // sign of det(u, x, W x) for any x not parallel to u
+    u := info.Axis
+    for _, x := range [][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+      c := cross3(u, x)
+      if c == [3]int{} {
+        continue
+      }
+      var wx [3]int
+      for i:=0; i<3; i++ {
+        wx[i] = wp[i][0]*x[0] + wp[i][1]*x[1] + wp[i][2]*x[2]
+      }
+      d := c[0]*wx[0] + c[1]*wx[1] + c[2]*wx[2]
+      if d > 0 {
+        info.Sense = 1
+      } else {
+        info.Sense = -1
+      }
+      break
+    }
+  }
+
+  // intrinsic part is mean of translations of powers (W, w)^j over the
+  // order of W
+  p := Operation{NewRotation([3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}), NewTranslation([3]float64{})}
+  for j:=0; j<k; j++ {
+    p = o.Compose(p)
+  }
+  for i:=0; i<3; i++ {
+    v := p.Translation.AtVec(i) / float64(k)
+    if math.Abs(v) < 1e-8 {
+      v = 0
+    }
+    info.Intrinsic[i] = v
+  }
+  return info, nil
+}
+
+// Seitz return Seitz symbol such as {2 001|0 0 1/2} or {m 100|1/2 0 0}
+func (o Operation) Seitz() (string, error) {
+  info, err := o.Info()
+  if err != nil {
+    return "", err
+  }
+  var b strings.Builder
+  b.WriteString("{")
+  switch info.Order {
+  case 1:
+    b.WriteString("1")
+  case -1:
+    b.WriteString("-1")
+  case -2:
+    b.WriteString("m")
+  default:
+    b.WriteString(strconv.Itoa(info.Order))
+  }
+  switch info.Sense {
+  case 1:
+    b.WriteString("+")
+  case -1:
+    b.WriteString("-")
+  }
+  if info.Order != 1 && info.Order != -1 {
+    b.WriteString(" ")
+    for _, v := range info.Axis {
+      b.WriteString(strconv.Itoa(v))
+    }
+  }
+  b.WriteString("|")
+  for i:=0; i<3; i++ {
+    if i > 0 {
+      b.WriteString(" ")
+    }
+    b.WriteString(formatFraction(o.Translation.AtVec(i)))
+  }
+  b.WriteString("}")
+  return b.String(), nil
+}
+
+// ParseSeitz parse Seitz symbol as written by Seitz. The symbol gives the
+// rotation only up to the metric, so the rotation is taken among the
+// symmetry operations of lattice row vectors, e.g. 2 100 differs between
+// cubic and hexagonal lattices
+func ParseSeitz(symbol string, lattice *mat.Dense) (Operation, error) {
+  s := strings.TrimSpace(symbol)
+  if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+    return Operation{}, fmt.Errorf("seitz symbol %q: expect {R|t}", symbol)
+  }
+  parts := strings.Split(s[1:len(s)-1], "|")
+  if len(parts) != 2 {
+    return Operation{}, fmt.Errorf("seitz symbol %q: expect {R|t}", symbol)
+  }
+
+  var want OperationInfo
+  rot := strings.Fields(parts[0])
+  if len(rot) == 0 || len(rot) > 2 {
+    return Operation{}, fmt.Errorf("seitz symbol %q: expect rotation and axis", symbol)
+  }
+  r := rot[0]
+  if n := len(r); n > 1 && (r[n-1] == '+' || r[n-1] == '-') {
+    want.Sense = 1
+    if r[n-1] == '-' {
+      want.Sense = -1
+    }
+    r = r[:n-1]
+  }
+  if r == "m" {
+    want.Order = -2
+  } else {
+    n, err := strconv.Atoi(r)
+    if err != nil {
+      return Operation{}, fmt.Errorf("seitz symbol %q: bad rotation %q", symbol, rot[0])
+    }
+    want.Order = n
+  }
+  if (len(rot) == 2) == (want.Order == 1 || want.Order == -1) {
+    return Operation{}, fmt.Errorf("seitz symbol %q: axis expected for all but 1 and -1", symbol)
+  }
+  if len(rot) == 2 {
+    // components are single digits with optional sign, e.g. 1-10
+    a := rot[1]
+    k := 0
+    for i:=0; i<len(a); i++ {
+      sign := 1
+      if a[i] == '-' && i+1 < len(a) {
+        sign = -1
+        i++
+      }
+      if a[i] < '0' || a[i] > '9' || k == 3 {
+        return Operation{}, fmt.Errorf("seitz symbol %q: bad axis %q", symbol, a)
+      }
+      want.Axis[k] = sign * int(a[i]-'0')
+      k++
+    }
+    if k != 3 {
+      return Operation{}, fmt.Errorf("seitz symbol %q: bad axis %q", symbol, a)
+    }
+  }
+
+  var trans [3]float64
+  t := strings.Fields(parts[1])
+  if len(t) != 3 {
+    return Operation{}, fmt.Errorf("seitz symbol %q: expect 3 translation components", symbol)
+  }
+  for i, v := range t {
+    x, err := parseFraction(v)
+    if err != nil {
+      return Operation{}, fmt.Errorf("seitz symbol %q: %v", symbol, err)
+    }
+    trans[i] = x
+  }
+
+  for _, w := range latticeRotations(lattice) {
+    op := Operation{NewRotation(w), NewTranslation(trans)}
+    info, err := op.Info()
+    if err != nil {
+      continue
+    }
+    if info.Order == want.Order && info.Sense == want.Sense && info.Axis == want.Axis {
+      return op, nil
+    }
+  }
+  return Operation{}, fmt.Errorf("seitz symbol %q: no such rotation of lattice", symbol)
+}
+
+// latticeRotations return rotations W of lattice rows L in fraction
+// coordinate, i.e. W^T G W = G with metric G = L L^T, with entries in
+// [-2, 2]
+func latticeRotations(lattice *mat.Dense) [][3][3]float64 {
+  var g mat.Dense
+  g.Mul(lattice, lattice.T())
+  tol := 1e-5 * (g.At(0, 0) + g.At(1, 1) + g.At(2, 2))
+  dot := func(u, v [3]float64) float64 {
+    var d float64
+    for i:=0; i<3; i++ {
+      for j:=0; j<3; j++ {
+        d += u[i] * g.At(i, j) * v[j]
+      }
+    }
+    return d
+  }
+  // images of basis vector j are lattice vectors of the same length
+  var cols [3][][3]float64
+  for x:=-2; x<=2; x++ {
+    for y:=-2; y<=2; y++ {
+      for z:=-2; z<=2; z++ {
+        v := [3]float64{float64(x), float64(y), float64(z)}
+        for j:=0; j<3; j++ {
+          if math.Abs(dot(v, v) - g.At(j, j)) < tol {
+            cols[j] = append(cols[j], v)
+          }
+        }
+      }
+    }
+  }
+  var rots [][3][3]float64
+  for _, u := range cols[0] {
+    for _, v := range cols[1] {
+      if math.Abs(dot(u, v) - g.At(0, 1)) > tol {
+        continue
+      }
+      for _, w := range cols[2] {
+        if math.Abs(dot(u, w) - g.At(0, 2)) > tol || math.Abs(dot(v, w) - g.At(1, 2)) > tol {
+          continue
+        }
+        rots = append(rots, [3][3]float64{{u[0], v[0], w[0]}, {u[1], v[1], w[1]}, {u[2], v[2], w[2]}})
+      }
+    }
+  }
+  return rots
+}
+
+// XYZ render operation such as "-y,x-y,z+1/2", translation is written as
+// fraction with denominator up to 12 and wrapped into [0, 1)
+func (o Operation) XYZ() string {
+  parts := make([]string, 3)
+  for i:=0; i<3; i++ {
+    var b strings.Builder
+    for j:=0; j<3; j++ {
+      r := o.Rotation.At(i, j)
+      ri := int(math.Round(r))
+      switch {
+      case math.Abs(r) < 1e-8:
+        continue
+      case r < 0:
+        b.WriteString("-")
+      case b.Len() > 0:
+        b.WriteString("+")
+      }
+      switch {
+      case math.Abs(r-float64(ri)) > 1e-8:
+        b.WriteString(formatFraction(math.Abs(r)) + "*")
+      case ri > 1 || ri < -1:
+        fmt.Fprintf(&b, "%d", absInt(ri))
+      }
+      b.WriteByte("xyz"[j])
+    }
+    t := o.Translation.AtVec(i)
+    t -= math.Floor(t + 1e-8)
+    if t > 1e-8 {
+      b.WriteString("+" + formatFraction(t))
+    }
+    parts[i] = b.String()
+  }
+  return strings.Join(parts, ",")
+}
+
+func (o Operation) String() string {
+  return o.XYZ()
+}
+
+// Operations return operations of dataset
+func (d *Dataset) Operations() []Operation {
+  ops := make([]Operation, len(d.Rotations))
+  for i := range ops {
+    ops[i] = Operation{d.Rotations[i], d.Translations[i]}
+  }
+  return ops
+}
+
+// ints return rotation matrix rounded to integer
+func (r Rotation) ints() [3][3]int {
+  var m [3][3]int
+  for i:=0; i<3; i++ {
+    for j:=0; j<3; j++ {
+      m[i][j] = int(math.Round(r.data.At(i, j)))
+    }
+  }
+  return m
+}
+
+// parseFraction parse "1/2" or "0.5"
+func parseFraction(s string) (float64, error) {
+  if i := strings.Index(s, "/"); i >= 0 {
+    n, err := strconv.ParseFloat(s[:i], 64)
+    if err != nil {
+      return 0, err
+    }
+    d, err := strconv.ParseFloat(s[i+1:], 64)
+    if err != nil {
+      return 0, err
+    }
+    if d == 0 {
+      return 0, fmt.Errorf("zero denominator in %q", s)
+    }
+    return n / d, nil
+  }
+  return strconv.ParseFloat(s, 64)
+}
+
+// formatFraction render v as n/d with d up to 12, or decimal
+func formatFraction(v float64) string {
+  for d:=1; d<=12; d++ {
+    n := math.Round(v * float64(d))
+    if math.Abs(n/float64(d)-v) < 1e-6 {
+      if d == 1 {
+        return fmt.Sprintf("%d", int(n))
+      }
+      return fmt.Sprintf("%d/%d", int(n), d)
+    }
+  }
+  return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func identity3() [3][3]int {
+  return [3][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+}
+
+func mulInt3(a, b [3][3]int) [3][3]int {
+  var m [3][3]int
+  for i:=0; i<3; i++ {
+    for j:=0; j<3; j++ {
+      for k:=0; k<3; k++ {
+        m[i][j] += a[i][k] * b[k][j]
+      }
+    }
+  }
+  return m
+}
+
+func cross3(a, b [3]int) [3]int {
+  return [3]int{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+// primitiveDirection divide v by gcd of its components and make first
+// nonzero component positive
+func primitiveDirection(v [3]int) [3]int {
+  g := 0
+  for _, x := range v {
+    g = gcd(g, absInt(x))
+  }
+  if g == 0 {
+    return v
+  }
+  sign := 1
+  for _, x := range v {
+    if x != 0 {
+      if x < 0 {
+        sign = -1
+      }
+      break
+    }
+  }
+  for i := range v {
+    v[i] = sign * v[i] / g
+  }
+  return v
+}
+
+func gcd(a, b int) int {
+  for b != 0 {
+    a, b = b, a%b
+  }
+  return a
+}
+
+func absInt(a int) int {
+  if a < 0 {
+    return -a
+  }
+  return a
+}
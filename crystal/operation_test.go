@@ -0,0 +1,193 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestParseXYZ(t *testing.T) {
+	op, err := ParseXYZ("-y, x-y, z+1/2")
+	if err != nil {
+		t.Fatalf("parse xyz error: %v", err)
+	}
+	expectRot := [3][3]float64{{0, -1, 0}, {1, -1, 0}, {0, 0, 1}}
+	expectTrans := [3]float64{0, 0, 0.5}
+	if op.Rotation.Matrix() != expectRot || op.Translation.Vector() != expectTrans {
+		t.Errorf("parse xyz expected %v %v, got %v %v", expectRot, expectTrans, op.Rotation, op.Translation)
+	}
+	if s := op.XYZ(); s != "-y,x-y,z+1/2" {
+		t.Errorf("format xyz expected -y,x-y,z+1/2, got %s", s)
+	}
+	for _, s := range []string{"x,y", "x,y,w", "x,y,z+", "x,y,1/0"} {
+		if _, err := ParseXYZ(s); err == nil {
+			t.Errorf("expect error for %q", s)
+		}
+	}
+}
+
+func TestOperationInfo(t *testing.T) {
+	cases := []struct {
+		xyz   string
+		seitz string
+		info  OperationInfo
+	}{
+		{"x,y,z", "{1|0 0 0}", OperationInfo{Order: 1}},
+		{"-x,-y,-z", "{-1|0 0 0}", OperationInfo{Order: -1}},
+		{"-y,x-y,z+1/3", "{3+ 001|0 0 1/3}",
+			OperationInfo{Order: 3, Sense: 1, Axis: [3]int{0, 0, 1}, Intrinsic: [3]float64{0, 0, 1.0 / 3}}},
+		{"y,-x,z", "{4- 001|0 0 0}", OperationInfo{Order: 4, Sense: -1, Axis: [3]int{0, 0, 1}}},
+		{"-x,y+1/2,-z", "{2 010|0 1/2 0}",
+			OperationInfo{Order: 2, Axis: [3]int{0, 1, 0}, Intrinsic: [3]float64{0, 0.5, 0}}},
+		{"x,-y,z+1/2", "{m 010|0 0 1/2}",
+			OperationInfo{Order: -2, Axis: [3]int{0, 1, 0}, Intrinsic: [3]float64{0, 0, 0.5}}},
+		{"-z,-x,-y", "{-3+ 111|0 0 0}", OperationInfo{Order: -3, Sense: 1, Axis: [3]int{1, 1, 1}}},
+		{"x-y,x,-z", "{-3- 001|0 0 0}", OperationInfo{Order: -3, Sense: -1, Axis: [3]int{0, 0, 1}}},
+		{"y,x,z+1/2", "{m 1-10|0 0 1/2}",
+			OperationInfo{Order: -2, Axis: [3]int{1, -1, 0}, Intrinsic: [3]float64{0, 0, 0.5}}},
+	}
+	for _, c := range cases {
+		op, _ := ParseXYZ(c.xyz)
+		info, err := op.Info()
+		if err != nil {
+			t.Fatalf("%s: info error: %v", c.xyz, err)
+		}
+		for i := 0; i < 3; i++ {
+			if math.Abs(info.Intrinsic[i]-c.info.Intrinsic[i]) > 1e-8 {
+				t.Errorf("%s: intrinsic expected %v, got %v", c.xyz, c.info.Intrinsic, info.Intrinsic)
+				break
+			}
+		}
+		info.Intrinsic = c.info.Intrinsic
+		if info != c.info {
+			t.Errorf("%s: info expected %+v, got %+v", c.xyz, c.info, info)
+		}
+		if s, _ := op.Seitz(); s != c.seitz {
+			t.Errorf("%s: seitz expected %s, got %s", c.xyz, c.seitz, s)
+		}
+	}
+
+	op := Operation{NewRotation([3][3]float64{{1, 1, 0}, {0, 1, 0}, {0, 0, 1}}), NewTranslation([3]float64{})}
+	if _, err := op.Info(); err == nil {
+		t.Error("expect error for shear matrix")
+	}
+}
+
+func TestParseSeitz(t *testing.T) {
+	cubic := mat.NewDense(3, 3, []float64{4, 0, 0, 0, 4, 0, 0, 0, 4})
+	hexagonal := mat.NewDense(3, 3, []float64{3, 0, 0, -1.5, 1.5 * math.Sqrt(3), 0, 0, 0, 5})
+	cases := []struct {
+		seitz   string
+		lattice *mat.Dense
+		xyz     string
+	}{
+		{"{1|0 0 0}", cubic, "x,y,z"},
+		{"{-1|1/2 1/2 1/2}", cubic, "-x+1/2,-y+1/2,-z+1/2"},
+		// 2_1 screw and n glide
+		{"{2 001|0 0 1/2}", cubic, "-x,-y,z+1/2"},
+		{"{m 010|1/2 0 1/2}", cubic, "x+1/2,-y,z+1/2"},
+		{"{4- 001|1/4 1/4 1/4}", cubic, "y+1/4,-x+1/4,z+1/4"},
+		{"{-3+ 111|0 0 0}", cubic, "-z,-x,-y"},
+		{"{m 1-10|0 0 1/2}", cubic, "y,x,z+1/2"},
+		// 6_1 screw, -3 and -6 of hexagonal lattice
+		{"{6+ 001|0 0 1/6}", hexagonal, "x-y,x,z+1/6"},
+		{"{-3- 001|0 0 0}", hexagonal, "x-y,x,-z"},
+		{"{-6+ 001|0 0 0}", hexagonal, "-x+y,-x,-z"},
+		{"{-6- 001|0 0 1/2}", hexagonal, "-y,x-y,-z+1/2"},
+		{"{2 100|0 0 1/3}", hexagonal, "x-y,-y,-z+1/3"},
+	}
+	for _, c := range cases {
+		op, err := ParseSeitz(c.seitz, c.lattice)
+		if err != nil {
+			t.Errorf("%s: parse error: %v", c.seitz, err)
+			continue
+		}
+		if s := op.XYZ(); s != c.xyz {
+			t.Errorf("%s: expected %s, got %s", c.seitz, c.xyz, s)
+		}
+	}
+
+	// every operation of the lattices round trips through Seitz
+	trans := NewTranslation([3]float64{0.5, 0.25, 1.0 / 3})
+	for l, n := range map[*mat.Dense]int{cubic: 48, hexagonal: 24} {
+		rots := latticeRotations(l)
+		if len(rots) != n {
+			t.Errorf("expect %d lattice rotations, got %d", n, len(rots))
+		}
+		for _, w := range rots {
+			op := Operation{NewRotation(w), trans}
+			s, err := op.Seitz()
+			if err != nil {
+				t.Fatalf("%s: seitz error: %v", op, err)
+			}
+			got, err := ParseSeitz(s, l)
+			if err != nil || got.Rotation.Matrix() != w || got.Translation.Vector() != trans.Vector() {
+				t.Errorf("%s: round trip of %s gives %v, %v", op, s, got, err)
+			}
+		}
+	}
+
+	for _, s := range []string{"2 001|0 0 0", "{2|0 0 0}", "{1 001|0 0 0}", "{3+ 100|0 0 0}",
+		"{2 001|0 0}", "{2 0a1|0 0 0}", "{2 0001|0 0 0}", "{5 001|0 0 0}"} {
+		if _, err := ParseSeitz(s, cubic); err == nil {
+			t.Errorf("expect error for %q", s)
+		}
+	}
+}
+
+func TestOperationCompose(t *testing.T) {
+	op, _ := ParseXYZ("-y,x-y,z+1/3")
+	inv := op.Inverse()
+	if s := inv.Reduced().XYZ(); s != "-x+y,-x,z+2/3" {
+		t.Errorf("inverse expected -x+y,-x,z+2/3, got %s", s)
+	}
+	if s := op.Compose(inv).XYZ(); s != "x,y,z" {
+		t.Errorf("operation times inverse expected x,y,z, got %s", s)
+	}
+	p := op.Compose(op).Compose(op)
+	if p.Rotation.Matrix() != [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} ||
+		math.Abs(p.Translation.AtVec(2)-1) > 1e-8 {
+		t.Errorf("cube of 3_1 expected x,y,z+1, got %v %v", p.Rotation, p.Translation)
+	}
+	q := op.Apply([3]float64{0.1, 0.2, 0.3})
+	expected := [3]float64{-0.2, -0.1, 0.3 + 1.0/3}
+	for i := range q {
+		if math.Abs(q[i]-expected[i]) > 1e-8 {
+			t.Errorf("apply expected %v, got %v", expected, q)
+			break
+		}
+	}
+}
+
+func TestOperationCartesian(t *testing.T) {
+	lattice := mat.NewDense(3, 3, []float64{2, 0, 0, -1, math.Sqrt(3), 0, 0, 0, 5})
+	op, _ := ParseXYZ("-y,x-y,z+1/2")
+	r, v := op.Cartesian(lattice)
+	c, s := math.Cos(2*math.Pi/3), math.Sin(2*math.Pi/3)
+	expected := mat.NewDense(3, 3, []float64{c, -s, 0, s, c, 0, 0, 0, 1})
+	if !mat.EqualApprox(r, expected, 1e-8) {
+		t.Errorf("cartesian rotation expected %v, got %v", mat.Formatted(expected), mat.Formatted(r))
+	}
+	if math.Abs(v[2]-2.5) > 1e-8 || math.Abs(v[0]) > 1e-8 || math.Abs(v[1]) > 1e-8 {
+		t.Errorf("cartesian translation expected [0 0 2.5], got %v", v)
+	}
+}
+
+func TestOperationApplyCell(t *testing.T) {
+	c, _ := NewCell(
+		[]float64{4, 0, 0, 0, 4, 0, 0, 0, 4},
+		[]float64{0.25, 0, 0, 0.5, 0.5, 0.5},
+		[]int{1, 2},
+		false,
+	)
+	op, _ := ParseXYZ("-x,y,z+1/2")
+	r := op.ApplyCell(c)
+	expected := mat.NewDense(2, 3, []float64{0.75, 0, 0.5, 0.5, 0.5, 0})
+	if !mat.EqualApprox(r.Position, expected, 1e-8) {
+		t.Errorf("apply cell expected %v, got %v", mat.Formatted(expected), mat.Formatted(r.Position))
+	}
+	if c.Position.At(0, 0) != 0.25 {
+		t.Error("apply cell changed original cell")
+	}
+}
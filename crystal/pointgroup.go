@@ -0,0 +1,141 @@
+package crystal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PointGroup is crystallographic point group
+type PointGroup struct {
+	// Symbol is Hermann–Mauguin symbol, e.g. 4/mmm
+	Symbol string
+	// Schoenflies symbol, e.g. D4h
+	Schoenflies string
+	// Order is number of rotations
+	Order int
+	// CrystalSystem is one of triclinic, monoclinic, orthorhombic,
+	// tetragonal, trigonal, hexagonal and cubic
+	CrystalSystem string
+	// LatticeSystem is crystal system except for trigonal groups, whose
+	// lattice is rhombohedral or hexagonal. Empty for trigonal groups found
+	// by PointGroupOf, which does not know the lattice
+	LatticeSystem string
+	// Laue is Hermann–Mauguin symbol of Laue class
+	Laue string
+	// Centrosymmetric if group has inversion
+	Centrosymmetric bool
+	// Polar if a direction is left invariant by all operations
+	Polar bool
+	// Chiral if group has proper rotations only
+	Chiral bool
+}
+
+// pointGroups list the 32 point groups, each identified by the number of
+// rotations of type -6, -4, -3, -2, -1, 1, 2, 3, 4, 6
+var pointGroups = []struct {
+	symbol      string
+	schoenflies string
+	system      string
+	laue        string
+	polar       bool
+	count       [10]int
+}{
+	{"1", "C1", "triclinic", "-1", true, [10]int{0, 0, 0, 0, 0, 1, 0, 0, 0, 0}},
+	{"-1", "Ci", "triclinic", "-1", false, [10]int{0, 0, 0, 0, 1, 1, 0, 0, 0, 0}},
+	{"2", "C2", "monoclinic", "2/m", true, [10]int{0, 0, 0, 0, 0, 1, 1, 0, 0, 0}},
+	{"m", "Cs", "monoclinic", "2/m", true, [10]int{0, 0, 0, 1, 0, 1, 0, 0, 0, 0}},
+	{"2/m", "C2h", "monoclinic", "2/m", false, [10]int{0, 0, 0, 1, 1, 1, 1, 0, 0, 0}},
+	{"222", "D2", "orthorhombic", "mmm", false, [10]int{0, 0, 0, 0, 0, 1, 3, 0, 0, 0}},
+	{"mm2", "C2v", "orthorhombic", "mmm", true, [10]int{0, 0, 0, 2, 0, 1, 1, 0, 0, 0}},
+	{"mmm", "D2h", "orthorhombic", "mmm", false, [10]int{0, 0, 0, 3, 1, 1, 3, 0, 0, 0}},
+	{"4", "C4", "tetragonal", "4/m", true, [10]int{0, 0, 0, 0, 0, 1, 1, 0, 2, 0}},
+	{"-4", "S4", "tetragonal", "4/m", false, [10]int{0, 2, 0, 0, 0, 1, 1, 0, 0, 0}},
+	{"4/m", "C4h", "tetragonal", "4/m", false, [10]int{0, 2, 0, 1, 1, 1, 1, 0, 2, 0}},
+	{"422", "D4", "tetragonal", "4/mmm", false, [10]int{0, 0, 0, 0, 0, 1, 5, 0, 2, 0}},
+	{"4mm", "C4v", "tetragonal", "4/mmm", true, [10]int{0, 0, 0, 4, 0, 1, 1, 0, 2, 0}},
+	{"-42m", "D2d", "tetragonal", "4/mmm", false, [10]int{0, 2, 0, 2, 0, 1, 3, 0, 0, 0}},
+	{"4/mmm", "D4h", "tetragonal", "4/mmm", false, [10]int{0, 2, 0, 5, 1, 1, 5, 0, 2, 0}},
+	{"3", "C3", "trigonal", "-3", true, [10]int{0, 0, 0, 0, 0, 1, 0, 2, 0, 0}},
+	{"-3", "C3i", "trigonal", "-3", false, [10]int{0, 0, 2, 0, 1, 1, 0, 2, 0, 0}},
+	{"32", "D3", "trigonal", "-3m", false, [10]int{0, 0, 0, 0, 0, 1, 3, 2, 0, 0}},
+	{"3m", "C3v", "trigonal", "-3m", true, [10]int{0, 0, 0, 3, 0, 1, 0, 2, 0, 0}},
+	{"-3m", "D3d", "trigonal", "-3m", false, [10]int{0, 0, 2, 3, 1, 1, 3, 2, 0, 0}},
+	{"6", "C6", "hexagonal", "6/m", true, [10]int{0, 0, 0, 0, 0, 1, 1, 2, 0, 2}},
+	{"-6", "C3h", "hexagonal", "6/m", false, [10]int{2, 0, 0, 1, 0, 1, 0, 2, 0, 0}},
+	{"6/m", "C6h", "hexagonal", "6/m", false, [10]int{2, 0, 2, 1, 1, 1, 1, 2, 0, 2}},
+	{"622", "D6", "hexagonal", "6/mmm", false, [10]int{0, 0, 0, 0, 0, 1, 7, 2, 0, 2}},
+	{"6mm", "C6v", "hexagonal", "6/mmm", true, [10]int{0, 0, 0, 6, 0, 1, 1, 2, 0, 2}},
+	{"-6m2", "D3h", "hexagonal", "6/mmm", false, [10]int{2, 0, 0, 4, 0, 1, 3, 2, 0, 0}},
+	{"6/mmm", "D6h", "hexagonal", "6/mmm", false, [10]int{2, 0, 2, 7, 1, 1, 7, 2, 0, 2}},
+	{"23", "T", "cubic", "m-3", false, [10]int{0, 0, 0, 0, 0, 1, 3, 8, 0, 0}},
+	{"m-3", "Th", "cubic", "m-3", false, [10]int{0, 0, 8, 3, 1, 1, 3, 8, 0, 0}},
+	{"432", "O", "cubic", "m-3m", false, [10]int{0, 0, 0, 0, 0, 1, 9, 8, 6, 0}},
+	{"-43m", "Td", "cubic", "m-3m", false, [10]int{0, 6, 0, 6, 0, 1, 3, 8, 0, 0}},
+	{"m-3m", "Oh", "cubic", "m-3m", false, [10]int{0, 6, 8, 9, 1, 1, 9, 8, 6, 0}},
+}
+
+// PointGroupOf identify point group of rotations, repeated rotations such
+// as those of operations differing by translation are counted once
+func PointGroupOf(rots []Rotation) (*PointGroup, error) {
+	index := map[int]int{-6: 0, -4: 1, -3: 2, -2: 3, -1: 4, 1: 5, 2: 6, 3: 7, 4: 8, 6: 9}
+	var count [10]int
+	seen := make(map[[3][3]int]bool)
+	for _, r := range rots {
+		m := r.ints()
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		info, err := Operation{r, NewTranslation([3]float64{})}.Info()
+		if err != nil {
+			return nil, err
+		}
+		count[index[info.Order]]++
+	}
+	for _, g := range pointGroups {
+		if g.count != count {
+			continue
+		}
+		p := &PointGroup{
+			Symbol:          g.symbol,
+			Schoenflies:     g.schoenflies,
+			Order:           len(seen),
+			CrystalSystem:   g.system,
+			Laue:            g.laue,
+			Centrosymmetric: count[index[-1]] > 0,
+			Polar:           g.polar,
+			Chiral:          count[0]+count[1]+count[2]+count[3]+count[4] == 0,
+		}
+		if g.system != "trigonal" {
+			p.LatticeSystem = g.system
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("rotations do not form a crystallographic point group, count of types -6 to 6 is %v", count)
+}
+
+// PointGroup return point group of cell
+func (c *Cell) PointGroup(symprec float64) (*PointGroup, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return nil, err
+	}
+	p, err := PointGroupOf(ds.Rotations)
+	if err != nil {
+		return nil, err
+	}
+	if p.CrystalSystem == "trigonal" {
+		if strings.HasPrefix(ds.SpaceSymbol, "R") {
+			p.LatticeSystem = "rhombohedral"
+		} else {
+			p.LatticeSystem = "hexagonal"
+		}
+	}
+	return p, nil
+}
+
+// Piezoelectric return true if point group allows piezoelectricity and
+// second harmonic generation, i.e. non-centrosymmetric except 432
+func (p *PointGroup) Piezoelectric() bool {
+	return !p.Centrosymmetric && p.Symbol != "432"
+}
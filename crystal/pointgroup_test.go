@@ -0,0 +1,94 @@
+package crystal
+
+import (
+	"testing"
+)
+
+// rotationGroup return closure of rotations of generators given as xyz
+func rotationGroup(gens ...string) []Rotation {
+	group := []Rotation{NewRotation([3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}})}
+	seen := map[[3][3]int]bool{group[0].ints(): true}
+	for k := 0; k < len(group); k++ {
+		for _, g := range gens {
+			op, _ := ParseXYZ(g)
+			r := op.Rotation.Mul(group[k])
+			if !seen[r.ints()] {
+				seen[r.ints()] = true
+				group = append(group, r)
+			}
+		}
+	}
+	return group
+}
+
+func TestPointGroupOf(t *testing.T) {
+	cases := []struct {
+		gens        []string
+		symbol      string
+		schoenflies string
+		order       int
+		laue        string
+		centro      bool
+		polar       bool
+		chiral      bool
+	}{
+		{[]string{"x,y,z"}, "1", "C1", 1, "-1", false, true, true},
+		{[]string{"-x,-y,-z"}, "-1", "Ci", 2, "-1", true, false, false},
+		{[]string{"-x,-y,z", "x,-y,z"}, "mm2", "C2v", 4, "mmm", false, true, false},
+		{[]string{"-y,x,z", "x,-y,z", "-x,-y,-z"}, "4/mmm", "D4h", 16, "4/mmm", true, false, false},
+		{[]string{"y,-x,-z", "x,-y,-z"}, "-42m", "D2d", 8, "4/mmm", false, false, false},
+		{[]string{"-y,x-y,z", "y,x,z"}, "3m", "C3v", 6, "-3m", false, true, false},
+		{[]string{"-y,x-y,z", "x,y,-z", "-y,-x,z"}, "-6m2", "D3h", 12, "6/mmm", false, false, false},
+		{[]string{"x-y,x,z", "y,x,-z"}, "622", "D6", 12, "6/mmm", false, false, true},
+		{[]string{"z,x,y", "-x,-y,z", "y,x,z"}, "-43m", "Td", 24, "m-3m", false, false, false},
+		{[]string{"z,x,y", "-y,x,z", "-x,-y,-z"}, "m-3m", "Oh", 48, "m-3m", true, false, false},
+	}
+	for _, c := range cases {
+		p, err := PointGroupOf(rotationGroup(c.gens...))
+		if err != nil {
+			t.Fatalf("%s: point group error: %v", c.symbol, err)
+		}
+		if p.Symbol != c.symbol || p.Schoenflies != c.schoenflies || p.Order != c.order || p.Laue != c.laue {
+			t.Errorf("expect %s %s order %d Laue %s, got %s %s order %d Laue %s",
+				c.symbol, c.schoenflies, c.order, c.laue, p.Symbol, p.Schoenflies, p.Order, p.Laue)
+		}
+		if p.Centrosymmetric != c.centro || p.Polar != c.polar || p.Chiral != c.chiral {
+			t.Errorf("%s: expect centrosymmetric %v polar %v chiral %v, got %v %v %v",
+				c.symbol, c.centro, c.polar, c.chiral, p.Centrosymmetric, p.Polar, p.Chiral)
+		}
+	}
+
+	p, _ := PointGroupOf(rotationGroup("z,x,y", "-y,x,z"))
+	if p.Symbol != "432" || p.Piezoelectric() || p.CrystalSystem != "cubic" || p.LatticeSystem != "cubic" {
+		t.Errorf("expect non-piezoelectric cubic 432, got %+v", p)
+	}
+	p, _ = PointGroupOf(rotationGroup("-y,x-y,z"))
+	if p.CrystalSystem != "trigonal" || p.LatticeSystem != "" || !p.Piezoelectric() {
+		t.Errorf("expect piezoelectric trigonal 3 with unknown lattice system, got %+v", p)
+	}
+
+	if _, err := PointGroupOf(rotationGroup("-y,x,z")[:3]); err == nil {
+		t.Error("expect error for incomplete group")
+	}
+}
+
+func TestCellPointGroup(t *testing.T) {
+	// wurtzite ZnO, 6mm
+	u := 0.382
+	c, _ := NewCell(
+		[]float64{3.25, 0, 0, -1.625, 2.8146, 0, 0, 0, 5.207},
+		[]float64{
+			1.0 / 3, 2.0 / 3, 0, 2.0 / 3, 1.0 / 3, 0.5,
+			1.0 / 3, 2.0 / 3, u, 2.0 / 3, 1.0 / 3, 0.5 + u,
+		},
+		[]int{30, 30, 8, 8},
+		false,
+	)
+	p, err := c.PointGroup(1e-3)
+	if err != nil {
+		t.Fatalf("point group error: %v", err)
+	}
+	if p.Symbol != "6mm" || p.LatticeSystem != "hexagonal" || !p.Polar || !p.Piezoelectric() {
+		t.Errorf("expect polar piezoelectric hexagonal 6mm, got %+v", p)
+	}
+}
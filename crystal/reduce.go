@@ -0,0 +1,254 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DefaultReduceEps is relative tolerance of Niggli and Delaunay reduction
+const DefaultReduceEps = 1e-5
+
+// maxReduceSteps bound the iterations of reductions
+const maxReduceSteps = 10000
+
+// Niggli return Niggli reduced cell and integer matrix m with reduced
+// lattice rows m·Lattice, see SupercellMatrix. Comparisons of the metric
+// use tolerance eps·V^(2/3), eps <= 0 means DefaultReduceEps
+func (c *Cell) Niggli(eps float64) (*Cell, [3][3]int, error) {
+	if eps <= 0 {
+		eps = DefaultReduceEps
+	}
+	eps *= math.Pow(c.Volume(), 2.0/3)
+	m := [3][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	lt := func(x, y float64) bool { return x < y-eps }
+	eq := func(x, y float64) bool { return math.Abs(x-y) <= eps }
+	sign := func(x float64) int {
+		switch {
+		case x > eps:
+			return 1
+		case x < -eps:
+			return -1
+		}
+		return 0
+	}
+
+	for step := 0; step < maxReduceSteps; step++ {
+		a, b, cc, xi, eta, zeta := c.metric(m)
+		// A1
+		if lt(b, a) || (eq(a, b) && lt(math.Abs(eta), math.Abs(xi))) {
+			m = [3][3]int{negate(m[1]), negate(m[0]), negate(m[2])}
+			continue
+		}
+		// A2
+		if lt(cc, b) || (eq(b, cc) && lt(math.Abs(zeta), math.Abs(eta))) {
+			m = [3][3]int{negate(m[0]), negate(m[2]), negate(m[1])}
+			continue
+		}
+		// A3 and A4, make ξ, η, ζ all positive or all non-positive
+		l, mm, n := sign(xi), sign(eta), sign(zeta)
+		s := [3]int{1, 1, 1}
+		if l*mm*n == 1 {
+			s = [3]int{l, mm, n}
+		} else {
+			zero := -1
+			for i, v := range [3]int{l, mm, n} {
+				if v == 1 {
+					s[i] = -1
+				} else if v == 0 {
+					zero = i
+				}
+			}
+			if s[0]*s[1]*s[2] == -1 && zero >= 0 {
+				s[zero] = -1
+			}
+		}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				m[i][j] *= s[i]
+			}
+		}
+		a, b, cc, xi, eta, zeta = c.metric(m)
+		switch {
+		// A5
+		case lt(b, math.Abs(xi)) || (eq(xi, b) && lt(2*eta, zeta)) || (eq(xi, -b) && lt(zeta, 0)):
+			m[2] = addRow(m[2], m[1], -signInt(xi))
+		// A6
+		case lt(a, math.Abs(eta)) || (eq(eta, a) && lt(2*xi, zeta)) || (eq(eta, -a) && lt(zeta, 0)):
+			m[2] = addRow(m[2], m[0], -signInt(eta))
+		// A7
+		case lt(a, math.Abs(zeta)) || (eq(zeta, a) && lt(2*xi, eta)) || (eq(zeta, -a) && lt(eta, 0)):
+			m[1] = addRow(m[1], m[0], -signInt(zeta))
+		// A8
+		case lt(xi+eta+zeta+a+b, 0) || (eq(xi+eta+zeta+a+b, 0) && lt(0, 2*(a+eta)+zeta)):
+			m[2] = addRow(addRow(m[2], m[0], 1), m[1], 1)
+		default:
+			r, err := c.SupercellMatrix(m)
+			return r, m, err
+		}
+	}
+	return nil, m, fmt.Errorf("niggli reduction not converged in %d steps", maxReduceSteps)
+}
+
+// Delaunay return Delaunay (Selling) reduced cell, whose lattice vectors
+// together with minus their sum have pairwise non-acute angles, and integer
+// matrix m with reduced lattice rows m·Lattice. eps is as in Niggli
+func (c *Cell) Delaunay(eps float64) (*Cell, [3][3]int, error) {
+	if eps <= 0 {
+		eps = DefaultReduceEps
+	}
+	eps *= math.Pow(c.Volume(), 2.0/3)
+	// extended basis b1, b2, b3, b4 = -(b1+b2+b3) as rows of coefficients
+	ext := [4][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {-1, -1, -1}}
+	reduced := false
+	for step := 0; step < maxReduceSteps && !reduced; step++ {
+		reduced = true
+	search:
+		for i := 0; i < 4; i++ {
+			for j := i + 1; j < 4; j++ {
+				if c.dot(ext[i], ext[j]) > eps {
+					for k := 0; k < 4; k++ {
+						if k != i && k != j {
+							ext[k] = addRow(ext[k], ext[i], 1)
+						}
+					}
+					ext[i] = negate(ext[i])
+					reduced = false
+					break search
+				}
+			}
+		}
+	}
+	if !reduced {
+		return nil, [3][3]int{}, fmt.Errorf("delaunay reduction not converged in %d steps", maxReduceSteps)
+	}
+
+	// three shortest non-coplanar vectors of the extended basis and sums
+	cand := [][3]int{ext[0], ext[1], ext[2], ext[3],
+		addRow(ext[0], ext[1], 1), addRow(ext[1], ext[2], 1), addRow(ext[2], ext[0], 1)}
+	sort.SliceStable(cand, func(i, j int) bool {
+		return c.dot(cand[i], cand[i]) < c.dot(cand[j], cand[j])-eps
+	})
+	for i := 0; i < len(cand); i++ {
+		for j := i + 1; j < len(cand); j++ {
+			for k := j + 1; k < len(cand); k++ {
+				m := [3][3]int{cand[i], cand[j], cand[k]}
+				d := detInt3(m)
+				if d != 1 && d != -1 {
+					continue
+				}
+				if d < 0 {
+					m = [3][3]int{negate(m[0]), negate(m[1]), negate(m[2])}
+				}
+				r, err := c.SupercellMatrix(m)
+				return r, m, err
+			}
+		}
+	}
+	return nil, [3][3]int{}, fmt.Errorf("delaunay reduction found no basis")
+}
+
+// LLL return Lenstra–Lenstra–Lovász reduced cell and integer matrix m with
+// reduced lattice rows m·Lattice. delta is Lovász parameter in (0.25, 1),
+// 0 means 0.75
+func (c *Cell) LLL(delta float64) (*Cell, [3][3]int, error) {
+	if delta == 0 {
+		delta = 0.75
+	}
+	if delta <= 0.25 || delta >= 1 {
+		return nil, [3][3]int{}, fmt.Errorf("lll: delta must be in (0.25, 1), got %g", delta)
+	}
+	m := [3][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	k := 1
+	for step := 0; k < 3; step++ {
+		if step > maxReduceSteps {
+			return nil, m, fmt.Errorf("lll reduction not converged in %d steps", maxReduceSteps)
+		}
+		mu, bstar := c.gramSchmidt(m)
+		for j := k - 1; j >= 0; j-- {
+			if q := math.Round(mu[k][j]); q != 0 {
+				m[k] = addRow(m[k], m[j], -int(q))
+				mu, bstar = c.gramSchmidt(m)
+			}
+		}
+		if bstar[k] >= (delta-mu[k][k-1]*mu[k][k-1])*bstar[k-1] {
+			k++
+		} else {
+			m[k], m[k-1] = m[k-1], m[k]
+			if k > 1 {
+				k--
+			}
+		}
+	}
+	if detInt3(m) < 0 {
+		m[2] = negate(m[2])
+	}
+	r, err := c.SupercellMatrix(m)
+	return r, m, err
+}
+
+// metric return A, B, C, ξ, η, ζ of lattice rows m·Lattice
+func (c *Cell) metric(m [3][3]int) (a, b, cc, xi, eta, zeta float64) {
+	return c.dot(m[0], m[0]), c.dot(m[1], m[1]), c.dot(m[2], m[2]),
+		2 * c.dot(m[1], m[2]), 2 * c.dot(m[0], m[2]), 2 * c.dot(m[0], m[1])
+}
+
+// dot return scalar product of lattice vectors u·Lattice and v·Lattice
+func (c *Cell) dot(u, v [3]int) float64 {
+	var x, y [3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			x[j] += float64(u[i]) * c.Lattice.At(i, j)
+			y[j] += float64(v[i]) * c.Lattice.At(i, j)
+		}
+	}
+	return x[0]*y[0] + x[1]*y[1] + x[2]*y[2]
+}
+
+// gramSchmidt return coefficients mu and squared norms of Gram–Schmidt
+// orthogonalization of lattice rows m·Lattice
+func (c *Cell) gramSchmidt(m [3][3]int) ([3][3]float64, [3]float64) {
+	var mu [3][3]float64
+	var norm [3]float64
+	b := make([]*mat.VecDense, 3)
+	bs := make([]*mat.VecDense, 3)
+	for i := 0; i < 3; i++ {
+		b[i] = mat.NewVecDense(3, nil)
+		for k := 0; k < 3; k++ {
+			for j := 0; j < 3; j++ {
+				b[i].SetVec(j, b[i].AtVec(j)+float64(m[i][k])*c.Lattice.At(k, j))
+			}
+		}
+		bs[i] = mat.VecDenseCopyOf(b[i])
+		for j := 0; j < i; j++ {
+			mu[i][j] = mat.Dot(b[i], bs[j]) / norm[j]
+			bs[i].AddScaledVec(bs[i], -mu[i][j], bs[j])
+		}
+		norm[i] = mat.Dot(bs[i], bs[i])
+	}
+	return mu, norm
+}
+
+func negate(v [3]int) [3]int {
+	return [3]int{-v[0], -v[1], -v[2]}
+}
+
+// addRow return u + f v
+func addRow(u, v [3]int, f int) [3]int {
+	return [3]int{u[0] + f*v[0], u[1] + f*v[1], u[2] + f*v[2]}
+}
+
+func signInt(x float64) int {
+	if x < 0 {
+		return -1
+	}
+	return 1
+}
+
+func detInt3(m [3][3]int) int {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
@@ -0,0 +1,130 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// checkReduced verify lattice of r is m·Lattice of c and every atom of r is
+// at an atom of c
+func checkReduced(t *testing.T, name string, c, r *Cell, m [3][3]int) {
+	var expected mat.Dense
+	expected.Mul(mat.NewDense(3, 3, []float64{
+		float64(m[0][0]), float64(m[0][1]), float64(m[0][2]),
+		float64(m[1][0]), float64(m[1][1]), float64(m[1][2]),
+		float64(m[2][0]), float64(m[2][1]), float64(m[2][2]),
+	}), c.Lattice)
+	if !mat.EqualApprox(r.Lattice, &expected, 1e-8) {
+		t.Errorf("%s: lattice expected m·L %v, got %v", name, mat.Formatted(&expected), mat.Formatted(r.Lattice))
+	}
+	if r.Natom != c.Natom {
+		t.Fatalf("%s: expect %d atoms, got %d", name, c.Natom, r.Natom)
+	}
+	var cart mat.Dense
+	cart.Mul(r.Position, r.Lattice)
+	var inv mat.Dense
+	inv.Inverse(c.Lattice)
+	var frac mat.Dense
+	frac.Mul(&cart, &inv)
+	for i := 0; i < r.Natom; i++ {
+		found := false
+		for j := 0; j < c.Natom && !found; j++ {
+			found = r.Elem[i] == c.Elem[j]
+			for k := 0; k < 3; k++ {
+				d := frac.At(i, k) - c.Position.At(j, k)
+				if math.Abs(d-math.Round(d)) > 1e-8 {
+					found = false
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s: atom %d of reduced cell not at an atom of cell", name, i)
+		}
+		for k := 0; k < 3; k++ {
+			if v := r.Position.At(i, k); v < 0 || v >= 1 {
+				t.Errorf("%s: position %v not wrapped", name, v)
+			}
+		}
+	}
+}
+
+func TestReduceSkewedCubic(t *testing.T) {
+	c, _ := NewCell(
+		[]float64{3, 0, 0, 0, 3, 0, 0, 0, 3},
+		[]float64{0, 0, 0, 0.5, 0.5, 0.5},
+		[]int{11, 17},
+		false,
+	)
+	skewed, err := c.SupercellMatrix([3][3]int{{2, 3, 1}, {1, 2, 1}, {3, 5, 3}})
+	if err != nil {
+		t.Fatalf("supercell error: %v", err)
+	}
+	reducers := map[string]func(*Cell) (*Cell, [3][3]int, error){
+		"niggli":   func(c *Cell) (*Cell, [3][3]int, error) { return c.Niggli(0) },
+		"delaunay": func(c *Cell) (*Cell, [3][3]int, error) { return c.Delaunay(0) },
+		"lll":      func(c *Cell) (*Cell, [3][3]int, error) { return c.LLL(0) },
+	}
+	for name, reduce := range reducers {
+		r, m, err := reduce(skewed)
+		if err != nil {
+			t.Fatalf("%s: reduce error: %v", name, err)
+		}
+		checkReduced(t, name, skewed, r, m)
+		l := r.Lengths()
+		an := r.Angles()
+		for i := 0; i < 3; i++ {
+			if math.Abs(l[i]-3) > 1e-8 || math.Abs(an[i]-90) > 1e-8 {
+				t.Errorf("%s: expect cubic cell, got lengths %v angles %v", name, l, an)
+				break
+			}
+		}
+		if mat.Det(r.Lattice) < 0 {
+			t.Errorf("%s: expect right-handed lattice", name)
+		}
+	}
+}
+
+func TestNiggliFcc(t *testing.T) {
+	// primitive fcc in a skewed setting, Niggli cell has all angles 60°
+	c, _ := NewCell(
+		[]float64{0, 2, 2, 2, 0, 2, 2, 2, 0},
+		[]float64{0, 0, 0},
+		[]int{29},
+		false,
+	)
+	skewed, _ := c.SupercellMatrix([3][3]int{{1, 0, 0}, {3, 1, 0}, {-2, 5, 1}})
+	r, m, err := skewed.Niggli(0)
+	if err != nil {
+		t.Fatalf("niggli error: %v", err)
+	}
+	checkReduced(t, "niggli", skewed, r, m)
+	a, b, cc, alpha, beta, gamma := r.Parameters()
+	expected := []float64{2 * math.Sqrt2, 2 * math.Sqrt2, 2 * math.Sqrt2, 60, 60, 60}
+	for i, v := range []float64{a, b, cc, alpha, beta, gamma} {
+		if math.Abs(v-expected[i]) > 1e-6 {
+			t.Errorf("niggli cell expected %v, got %v", expected, []float64{a, b, cc, alpha, beta, gamma})
+			break
+		}
+	}
+
+	d, md, err := skewed.Delaunay(0)
+	if err != nil {
+		t.Fatalf("delaunay error: %v", err)
+	}
+	checkReduced(t, "delaunay", skewed, d, md)
+	// lattice vectors and minus their sum pairwise non-acute
+	ext := [4][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {-1, -1, -1}}
+	for i := 0; i < 4; i++ {
+		for j := i + 1; j < 4; j++ {
+			if v := d.dot(ext[i], ext[j]); v > 1e-8 {
+				t.Errorf("delaunay: expect non-acute extended basis, got b%d.b%d = %v", i+1, j+1, v)
+			}
+		}
+	}
+
+	if _, _, err := c.LLL(1.5); err == nil {
+		t.Error("expect error for delta out of range")
+	}
+}
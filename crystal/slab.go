@@ -0,0 +1,400 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Default tolerances of Slabs
+const (
+	DefaultLayerTol    = 0.1
+	DefaultSlabSymprec = 1e-3
+)
+
+// SlabOptions control Slabs, zero values of tolerances mean the defaults
+type SlabOptions struct {
+	// MinThickness is minimum distance in Å between bottom and top layers
+	MinThickness float64
+	// Vacuum is distance in Å between top and bottom layers across the
+	// periodic boundary
+	Vacuum float64
+	// Symmetric add layers on top, up to one more surface cell, until both
+	// surfaces are related by symmetry. Such slabs need not keep the
+	// composition of the bulk, terminations without one are dropped
+	Symmetric bool
+	// Orthogonal make c perpendicular to the surface
+	Orthogonal bool
+	// FrozenLayers is number of bottom layers flagged as frozen
+	FrozenLayers int
+	// LayerTol is distance in Å along the surface normal below which
+	// atoms are in the same layer
+	LayerTol float64
+	// Symprec is tolerance of symmetry search for Symmetric
+	Symprec float64
+}
+
+// Slab is surface model with vacuum along c
+type Slab struct {
+	// Cell has a along x and b in xy plane, so the surface is normal to z.
+	// Atoms are grouped by type in order of the bulk, bottom to top
+	Cell *Cell
+	// Miller indices of the surface
+	Miller [3]int
+	// Matrix of surface cell, see SurfaceCell
+	Matrix [3][3]int
+	// Termination is index of the layer of surface cell at bottom
+	Termination int
+	// Layers is number of atomic layers
+	Layers int
+	// Frozen flags atoms of bottom layers
+	Frozen []bool
+}
+
+// SelectiveDynamics return 3 flags (x, y, z) per atom, true for atoms
+// allowed to relax, as io.Cell.SelectiveDynamics
+func (s *Slab) SelectiveDynamics() []bool {
+	sd := make([]bool, 3*len(s.Frozen))
+	for i, f := range s.Frozen {
+		sd[3*i], sd[3*i+1], sd[3*i+2] = !f, !f, !f
+	}
+	return sd
+}
+
+// SurfaceCell return cell with a and b in lattice plane (hkl) of c, and
+// integer matrix m of determinant 1 with its lattice rows m·Lattice. c is
+// the shortest lattice vector from one plane to the next
+func (c *Cell) SurfaceCell(miller [3]int) (*Cell, [3][3]int, error) {
+	m, err := c.surfaceMatrix(miller)
+	if err != nil {
+		return nil, m, err
+	}
+	s, err := c.SupercellMatrix(m)
+	return s, m, err
+}
+
+// Slabs return slabs of surface (hkl) of c for distinct terminations. The
+// Miller indices refer to lattice of c, usually the conventional cell.
+// opt can be nil
+func (c *Cell) Slabs(miller [3]int, opt *SlabOptions) ([]*Slab, error) {
+	if opt == nil {
+		opt = &SlabOptions{}
+	}
+	tol := opt.LayerTol
+	if tol <= 0 {
+		tol = DefaultLayerTol
+	}
+	symprec := opt.Symprec
+	if symprec <= 0 {
+		symprec = DefaultSlabSymprec
+	}
+	if opt.MinThickness < 0 || opt.Vacuum < 0 {
+		return nil, fmt.Errorf("slab thickness and vacuum must not be negative, got %g and %g", opt.MinThickness, opt.Vacuum)
+	}
+	sc, m, err := c.SurfaceCell(miller)
+	if err != nil {
+		return nil, err
+	}
+	ls, err := sc.layers(tol)
+	if err != nil {
+		return nil, err
+	}
+	order := make(map[int]int)
+	for _, e := range c.Elem {
+		if _, ok := order[e]; !ok {
+			order[e] = len(order)
+		}
+	}
+
+	var slabs []*Slab
+	matcher := &StructureMatcher{}
+	nc := len(ls.atoms)
+	for t := 0; t < nc; t++ {
+		n := nc
+		for ls.height(t, n-1)-ls.height(t, 0) < opt.MinThickness {
+			n += nc
+		}
+		var slab *Slab
+		for k := n; k <= n+nc; k++ {
+			s, err := ls.slab(t, k, opt, order)
+			if err != nil {
+				return nil, err
+			}
+			if !opt.Symmetric {
+				slab = s
+				break
+			}
+			flips, err := s.Cell.flipsSurface(symprec)
+			if err != nil {
+				return nil, err
+			}
+			if flips {
+				slab = s
+				break
+			}
+		}
+		if slab == nil {
+			continue
+		}
+		dup := false
+		for _, s := range slabs {
+			if s.Layers != slab.Layers {
+				continue
+			}
+			ok, err := matcher.Fit(s.Cell, slab.Cell)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				dup = true
+				break
+			}
+		}
+		if dup {
+			continue
+		}
+		slab.Miller = miller
+		slab.Matrix = m
+		slab.Termination = t
+		slabs = append(slabs, slab)
+	}
+	return slabs, nil
+}
+
+// surfaceMatrix return rows of SurfaceCell. The in-plane rows are the
+// shortest pair spanning the plane lattice, the third the shortest step
+// to the next plane
+func (c *Cell) surfaceMatrix(miller [3]int) ([3][3]int, error) {
+	var m [3][3]int
+	g := gcd(gcd(absInt(miller[0]), absInt(miller[1])), absInt(miller[2]))
+	if g == 0 {
+		return m, fmt.Errorf("invalid miller indices %v", miller)
+	}
+	h := [3]int{miller[0] / g, miller[1] / g, miller[2] / g}
+	r := 1
+	for _, v := range h {
+		if absInt(v)+1 > r {
+			r = absInt(v) + 1
+		}
+	}
+
+	var plane [][3]int
+	var step [3]int
+	found := false
+	for x := -r; x <= r; x++ {
+		for y := -r; y <= r; y++ {
+			for z := -r; z <= r; z++ {
+				u := [3]int{x, y, z}
+				switch h[0]*x + h[1]*y + h[2]*z {
+				case 0:
+					if u != [3]int{} {
+						plane = append(plane, u)
+					}
+				case 1:
+					if !found || c.dot(u, u) < c.dot(step, step) {
+						step, found = u, true
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(plane, func(i, j int) bool {
+		return c.dot(plane[i], plane[i]) < c.dot(plane[j], plane[j])
+	})
+	ok := false
+search:
+	for i := 0; i < len(plane); i++ {
+		for j := i + 1; j < len(plane); j++ {
+			switch cross3(plane[i], plane[j]) {
+			case h:
+				m[0], m[1] = plane[i], plane[j]
+				ok = true
+				break search
+			case negate(h):
+				m[0], m[1] = plane[j], plane[i]
+				ok = true
+				break search
+			}
+		}
+	}
+	if !ok || !found {
+		return m, fmt.Errorf("no lattice basis found for plane %v", miller)
+	}
+
+	// remove in-plane component of the step as far as possible
+	aa, bb, ab := c.dot(m[0], m[0]), c.dot(m[1], m[1]), c.dot(m[0], m[1])
+	wa, wb := c.dot(step, m[0]), c.dot(step, m[1])
+	det := aa*bb - ab*ab
+	p := (wa*bb - wb*ab) / det
+	q := (wb*aa - wa*ab) / det
+	m[2] = step
+	for _, i := range []float64{math.Floor(p), math.Ceil(p)} {
+		for _, j := range []float64{math.Floor(q), math.Ceil(q)} {
+			w := addRow(addRow(step, m[0], -int(i)), m[1], -int(j))
+			if c.dot(w, w) < c.dot(m[2], m[2]) {
+				m[2] = w
+			}
+		}
+	}
+	return m, nil
+}
+
+// surfaceLayers is atomic layers of surface cell
+type surfaceLayers struct {
+	cell *Cell
+	// z is fraction coordinate along c with origin between layers
+	z []float64
+	// atoms of each layer, bottom to top
+	atoms [][]int
+	// heights of layers in [0, spacing) along the normal
+	heights []float64
+	spacing float64
+	// p and q are in-plane components of c in unit of a and b
+	p, q float64
+}
+
+// layers group atoms of surface cell in layers normal to c with gap
+// larger than tol. The origin is moved to the middle of the largest gap
+func (c *Cell) layers(tol float64) (*surfaceLayers, error) {
+	u := [3]int{1, 0, 0}
+	v := [3]int{0, 1, 0}
+	w := [3]int{0, 0, 1}
+	aa, bb, ab := c.dot(u, u), c.dot(v, v), c.dot(u, v)
+	area := math.Sqrt(aa*bb - ab*ab)
+	ls := &surfaceLayers{cell: c, spacing: c.Volume() / area}
+	wa, wb := c.dot(w, u), c.dot(w, v)
+	ls.p = (wa*bb - wb*ab) / (area * area)
+	ls.q = (wb*aa - wa*ab) / (area * area)
+
+	idx := make([]int, c.Natom)
+	z := make([]float64, c.Natom)
+	for i := range idx {
+		idx[i] = i
+		z[i] = c.Position.At(i, 2) - math.Floor(c.Position.At(i, 2))
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return z[idx[i]] < z[idx[j]] })
+	// largest gap, the one across the boundary included
+	d := ls.spacing
+	gap := (1 + z[idx[0]] - z[idx[len(idx)-1]]) * d
+	origin := z[idx[len(idx)-1]] + gap/d/2
+	for k := 1; k < len(idx); k++ {
+		if g := (z[idx[k]] - z[idx[k-1]]) * d; g > gap {
+			gap = g
+			origin = z[idx[k-1]] + g/d/2
+		}
+	}
+	if gap <= tol {
+		return nil, fmt.Errorf("no gap between layers larger than %g Å", tol)
+	}
+	for i := range z {
+		z[i] -= origin
+		z[i] -= math.Floor(z[i])
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return z[idx[i]] < z[idx[j]] })
+	ls.z = z
+	for k, i := range idx {
+		if k == 0 || (z[i]-z[idx[k-1]])*d > tol {
+			ls.atoms = append(ls.atoms, nil)
+			ls.heights = append(ls.heights, z[i]*d)
+		}
+		ls.atoms[len(ls.atoms)-1] = append(ls.atoms[len(ls.atoms)-1], i)
+	}
+	return ls, nil
+}
+
+// height return height of k-th layer of slab with layer t at bottom
+func (ls *surfaceLayers) height(t, k int) float64 {
+	n := len(ls.atoms)
+	return ls.heights[(t+k)%n] + float64((t+k)/n)*ls.spacing
+}
+
+// slab return slab of n layers with layer t at bottom
+func (ls *surfaceLayers) slab(t, n int, opt *SlabOptions, order map[int]int) (*Slab, error) {
+	c := ls.cell
+	d := ls.spacing
+	h0 := ls.height(t, 0)
+	total := ls.height(t, n-1) - h0 + opt.Vacuum
+	if total <= 0 {
+		return nil, fmt.Errorf("slab of a single layer needs vacuum")
+	}
+
+	type site struct {
+		elem   int
+		frac   [3]float64
+		frozen bool
+	}
+	var sites []site
+	nl := len(ls.atoms)
+	for k := 0; k < n; k++ {
+		rep := float64((t + k) / nl)
+		for _, i := range ls.atoms[(t+k)%nl] {
+			z := ls.z[i] + rep
+			f := [3]float64{c.Position.At(i, 0), c.Position.At(i, 1), (z*d - h0 + opt.Vacuum/2) / total}
+			if opt.Orthogonal {
+				f[0] += z * ls.p
+				f[1] += z * ls.q
+			}
+			f[0] -= math.Floor(f[0])
+			f[1] -= math.Floor(f[1])
+			sites = append(sites, site{c.Elem[i], f, k < opt.FrozenLayers})
+		}
+	}
+	sort.SliceStable(sites, func(i, j int) bool { return order[sites[i].elem] < order[sites[j].elem] })
+
+	// frame with a along x and surface normal along z
+	var a, b, w, nrm [3]float64
+	for j := 0; j < 3; j++ {
+		a[j] = c.Lattice.At(0, j)
+		b[j] = c.Lattice.At(1, j)
+		w[j] = c.Lattice.At(2, j)
+	}
+	nrm = crossF3(a, b)
+	e3 := scaleF3(nrm, 1/norm3(nrm))
+	e1 := scaleF3(a, 1/norm3(a))
+	e2 := crossF3(e3, e1)
+	cn := scaleF3(e3, total)
+	if !opt.Orthogonal {
+		cn = scaleF3(w, total/d)
+	}
+	lattice := make([]float64, 0, 9)
+	for _, v := range [][3]float64{a, b, cn} {
+		lattice = append(lattice, dot3(v, e1), dot3(v, e2), dot3(v, e3))
+	}
+	pos := make([]float64, 0, 3*len(sites))
+	elem := make([]int, 0, len(sites))
+	frozen := make([]bool, 0, len(sites))
+	for _, s := range sites {
+		pos = append(pos, s.frac[:]...)
+		elem = append(elem, s.elem)
+		frozen = append(frozen, s.frozen)
+	}
+	cell, err := NewCell(lattice, pos, elem, false)
+	if err != nil {
+		return nil, err
+	}
+	cell.System = c.System
+	return &Slab{Cell: cell, Layers: n, Frozen: frozen}, nil
+}
+
+// flipsSurface return true if an operation of c maps z to -z, i.e. the
+// bottom surface of slab to the top one
+func (c *Cell) flipsSurface(symprec float64) (bool, error) {
+	ds, err := c.Dataset(symprec)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range ds.Rotations {
+		if r.At(2, 0) == 0 && r.At(2, 1) == 0 && r.At(2, 2) == -1 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func crossF3(u, v [3]float64) [3]float64 {
+	return [3]float64{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+}
+
+func scaleF3(v [3]float64, f float64) [3]float64 {
+	return [3]float64{v[0] * f, v[1] * f, v[2] * f}
+}
@@ -0,0 +1,157 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSurfaceCell(t *testing.T) {
+	a := 4.0
+	c, _ := NewCell([]float64{a, 0, 0, 0, a, 0, 0, 0, a},
+		[]float64{0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0}, []int{29, 29, 29, 29}, false)
+	// lattice of the conventional cell is simple cubic
+	cases := []struct {
+		miller  [3]int
+		ab      [2]float64
+		spacing float64
+		layers  int
+	}{
+		{[3]int{1, 0, 0}, [2]float64{a, a}, a, 2},
+		{[3]int{1, 1, 0}, [2]float64{a, a * math.Sqrt2}, a / math.Sqrt2, 2},
+		{[3]int{1, 1, 1}, [2]float64{a * math.Sqrt2, a * math.Sqrt2}, a / math.Sqrt(3), 1},
+		{[3]int{2, 2, 2}, [2]float64{a * math.Sqrt2, a * math.Sqrt2}, a / math.Sqrt(3), 1},
+		{[3]int{2, 1, 0}, [2]float64{a, a * math.Sqrt(5)}, a / math.Sqrt(5), 2},
+	}
+	for _, cc := range cases {
+		s, m, err := c.SurfaceCell(cc.miller)
+		if err != nil {
+			t.Fatalf("%v: surface cell error: %v", cc.miller, err)
+		}
+		if detInt3(m) != 1 || s.Natom != c.Natom {
+			t.Errorf("%v: expect unimodular matrix, got %v", cc.miller, m)
+		}
+		g := gcd(gcd(cc.miller[0], cc.miller[1]), cc.miller[2])
+		for i := 0; i < 3; i++ {
+			var hu int
+			for j := 0; j < 3; j++ {
+				hu += cc.miller[j] / g * m[i][j]
+			}
+			if (i < 2 && hu != 0) || (i == 2 && hu != 1) {
+				t.Errorf("%v: row %d of %v not in or next to plane", cc.miller, i, m)
+			}
+		}
+		l := s.Lengths()
+		if math.Abs(l[0]-cc.ab[0]) > 1e-8 || math.Abs(l[1]-cc.ab[1]) > 1e-8 {
+			t.Errorf("%v: unexpected in-plane lengths %v", cc.miller, l)
+		}
+		ls, err := s.layers(DefaultLayerTol)
+		if err != nil {
+			t.Fatalf("%v: layers error: %v", cc.miller, err)
+		}
+		if math.Abs(ls.spacing-cc.spacing) > 1e-8 || len(ls.atoms) != cc.layers {
+			t.Errorf("%v: expect %d layers in %g, got %d in %g", cc.miller, cc.layers, cc.spacing, len(ls.atoms), ls.spacing)
+		}
+	}
+	if _, _, err := c.SurfaceCell([3]int{}); err == nil {
+		t.Error("expect error for zero miller indices")
+	}
+}
+
+func TestSlabs(t *testing.T) {
+	a := 5.64
+	c := rocksalt(a, 11, 17)
+	slabs, err := c.Slabs([3]int{1, 0, 0}, &SlabOptions{MinThickness: 10, Vacuum: 12, Orthogonal: true, FrozenLayers: 2})
+	if err != nil {
+		t.Fatalf("slab error: %v", err)
+	}
+	// every (100) layer is NaCl, so a single termination
+	if len(slabs) != 1 {
+		t.Fatalf("expect 1 termination, got %d", len(slabs))
+	}
+	s := slabs[0]
+	// 6 layers of a/2 spacing span 5a/2 > 10 Å
+	if s.Layers != 6 || s.Cell.Natom != 24 {
+		t.Errorf("expect 6 layers of 24 atoms, got %d layers of %d atoms", s.Layers, s.Cell.Natom)
+	}
+	l := s.Cell.LatticeSlice()
+	expect := []float64{a, 0, 0, 0, a, 0, 0, 0, 2.5*a + 12}
+	for i := range expect {
+		if math.Abs(l[i]-expect[i]) > 1e-8 {
+			t.Fatalf("expect lattice %v, got %v", expect, l)
+		}
+	}
+	nfrozen := 0
+	for i, f := range s.Frozen {
+		z := s.Cell.Position.At(i, 2) * l[8]
+		if f {
+			nfrozen++
+		}
+		if f != (z < 6+a/2+0.1) {
+			t.Errorf("atom %d at z=%g: frozen %v", i, z, f)
+		}
+		if i > 0 && s.Cell.Elem[i] < s.Cell.Elem[i-1] {
+			t.Errorf("expect atoms grouped by type, got %v", s.Cell.Elem)
+			break
+		}
+	}
+	if nfrozen != 8 {
+		t.Errorf("expect 8 frozen atoms, got %d", nfrozen)
+	}
+	sd := s.SelectiveDynamics()
+	if len(sd) != 72 || sd[0] || !sd[71] {
+		t.Errorf("unexpected selective dynamics %v", sd)
+	}
+
+	slabs, err = c.Slabs([3]int{1, 1, 1}, &SlabOptions{MinThickness: 8, Vacuum: 10})
+	if err != nil {
+		t.Fatalf("slab error: %v", err)
+	}
+	for _, s := range slabs {
+		// Na and Cl layers alternate
+		var heights []float64
+		var elems []int
+		cart := mat.NewDense(s.Cell.Natom, 3, nil)
+		cart.Mul(s.Cell.Position, s.Cell.Lattice)
+		for i := 0; i < s.Cell.Natom; i++ {
+			heights = append(heights, cart.At(i, 2))
+			elems = append(elems, s.Cell.Elem[i])
+		}
+		if s.Cell.Natom != 4*s.Layers || s.Layers%2 != 0 {
+			t.Errorf("expect 4 atoms per layer and even layers, got %d atoms %d layers", s.Cell.Natom, s.Layers)
+		}
+		top, bottom := 0, 0
+		for i := range heights {
+			if heights[i] > heights[top] {
+				top = i
+			}
+			if heights[i] < heights[bottom] {
+				bottom = i
+			}
+		}
+		if elems[top] == elems[bottom] {
+			t.Errorf("expect different top and bottom layers of stoichiometric slab")
+		}
+		if d := heights[top] - heights[bottom]; d < 8 || math.Abs(heights[bottom]-5) > 1e-8 {
+			t.Errorf("expect slab of at least 8 Å from 5 Å, got %g from %g", d, heights[bottom])
+		}
+	}
+}
+
+func TestSlabsSymmetric(t *testing.T) {
+	c := rocksalt(5.64, 11, 17)
+	slabs, err := c.Slabs([3]int{1, 1, 1}, &SlabOptions{MinThickness: 8, Vacuum: 10, Symmetric: true})
+	if err != nil {
+		t.Fatalf("slab error: %v", err)
+	}
+	// Na and Cl terminated slabs with an odd number of layers
+	if len(slabs) != 2 {
+		t.Fatalf("expect 2 terminations, got %d", len(slabs))
+	}
+	for _, s := range slabs {
+		if s.Layers%2 != 1 {
+			t.Errorf("expect odd number of layers, got %d", s.Layers)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Default options of SQS
+const (
+	DefaultSQSSteps       = 20000
+	DefaultSQSTemperature = 1e-3
+)
+
+// SQSOptions control SQS, zero values mean the defaults
+type SQSOptions struct {
+	// PairCutoff in Å of pair correlations
+	PairCutoff float64
+	// TripletCutoff in Å of longest edge of triplet correlations, 0 means
+	// pairs only
+	TripletCutoff float64
+	// Steps of simulated annealing
+	Steps int
+	// Temperature at the start of annealing, in unit of the objective
+	Temperature float64
+	// Seed of random numbers
+	Seed int64
+}
+
+// sqsCluster is pair or triplet of decorated sites of type kind
+type sqsCluster struct {
+	kind  int
+	sites []int
+}
+
+// SQS return special quasirandom structure, decoration of supercell m of c
+// whose pair and triplet correlations best match the random alloy, found
+// by simulated annealing of site swaps. The objective is the sum over
+// clusters of squared differences of probabilities of species multisets
+// from the random alloy. Atoms of the result are grouped by type
+func (c *Cell) SQS(d *Decoration, m [3][3]int, opt *SQSOptions) (*Cell, float64, error) {
+	if opt == nil {
+		opt = &SQSOptions{}
+	}
+	if opt.PairCutoff <= 0 {
+		return nil, 0, fmt.Errorf("sqs pair cutoff must be positive, got %g", opt.PairCutoff)
+	}
+	steps := opt.Steps
+	if steps <= 0 {
+		steps = DefaultSQSSteps
+	}
+	temp := opt.Temperature
+	if temp <= 0 {
+		temp = DefaultSQSTemperature
+	}
+	sites, err := d.sites(c)
+	if err != nil {
+		return nil, 0, err
+	}
+	sc, err := c.SupercellMatrix(m)
+	if err != nil {
+		return nil, 0, err
+	}
+	n := sc.Natom / c.Natom
+	var decorated []int
+	for _, s := range sites {
+		for k := 0; k < n; k++ {
+			decorated = append(decorated, s*n+k)
+		}
+	}
+	counts, err := d.counts(len(decorated))
+	if err != nil {
+		return nil, 0, err
+	}
+	clusters, kinds, err := sc.sqsClusters(decorated, opt.PairCutoff, opt.TripletCutoff)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// random decoration to start with
+	rng := rand.New(rand.NewSource(opt.Seed))
+	col := make([]int, 0, len(decorated))
+	for s, k := range counts {
+		for i := 0; i < k; i++ {
+			col = append(col, s)
+		}
+	}
+	rng.Shuffle(len(col), func(i, j int) { col[i], col[j] = col[j], col[i] })
+
+	obj := newSQSObjective(clusters, kinds, d.Concentrations, col)
+	best := append([]int(nil), col...)
+	bestValue := obj.value()
+	value := bestValue
+	if len(counts) > 1 {
+		for step := 0; step < steps && bestValue > 0; step++ {
+			i, j := rng.Intn(len(col)), rng.Intn(len(col))
+			if col[i] == col[j] {
+				continue
+			}
+			obj.swap(col, i, j)
+			v := obj.value()
+			t := temp * (1 - float64(step)/float64(steps))
+			if v <= value || (t > 0 && rng.Float64() < math.Exp((value-v)/t)) {
+				value = v
+				if v < bestValue {
+					bestValue = v
+					copy(best, col)
+				}
+			} else {
+				obj.swap(col, i, j)
+			}
+		}
+	}
+
+	r := CellCopyOf(sc)
+	for i, j := range decorated {
+		r.Elem[j] = d.Species[best[i]]
+	}
+	return groupTypes(r), bestValue, nil
+}
+
+// sqsClusters return pairs within pairCut and triplets with edges within
+// tripletCut of decorated atoms, counting periodic images, and the number
+// of kinds. Kinds of pairs are shells of distance, kinds of triplets are
+// sorted shells of edges
+func (c *Cell) sqsClusters(decorated []int, pairCut, tripletCut float64) ([]sqsCluster, int, error) {
+	cut := math.Max(pairCut, tripletCut)
+	nbs, err := c.Neighbors(cut)
+	if err != nil {
+		return nil, 0, err
+	}
+	index := make(map[int]int)
+	for i, j := range decorated {
+		index[j] = i
+	}
+	const tol = 1e-3
+	var dists []float64
+	for _, i := range decorated {
+		for _, nb := range nbs[i] {
+			if _, ok := index[nb.Index]; ok && nb.Distance > tol {
+				dists = append(dists, nb.Distance)
+			}
+		}
+	}
+	sort.Float64s(dists)
+	var shells []float64
+	for _, v := range dists {
+		if len(shells) == 0 || v-shells[len(shells)-1] > tol {
+			shells = append(shells, v)
+		}
+	}
+	shell := func(v float64) int {
+		k := sort.SearchFloat64s(shells, v-tol)
+		if k < len(shells) && math.Abs(shells[k]-v) <= tol {
+			return k
+		}
+		return -1
+	}
+
+	var clusters []sqsCluster
+	kinds := make(map[[3]int]int)
+	kind := func(key [3]int) int {
+		if k, ok := kinds[key]; ok {
+			return k
+		}
+		kinds[key] = len(kinds)
+		return kinds[key]
+	}
+	for _, i := range decorated {
+		var near []Neighbor
+		for _, nb := range nbs[i] {
+			if _, ok := index[nb.Index]; ok && nb.Distance > tol {
+				near = append(near, nb)
+			}
+		}
+		for p, a := range near {
+			if a.Distance <= pairCut+tol {
+				clusters = append(clusters, sqsCluster{kind([3]int{shell(a.Distance), -1, -1}), []int{index[i], index[a.Index]}})
+			}
+			if a.Distance > tripletCut+tol {
+				continue
+			}
+			for _, b := range near[p+1:] {
+				if b.Distance > tripletCut+tol {
+					continue
+				}
+				e := norm3([3]float64{b.Vector[0] - a.Vector[0], b.Vector[1] - a.Vector[1], b.Vector[2] - a.Vector[2]})
+				if e <= tol || e > tripletCut+tol {
+					continue
+				}
+				key := []int{shell(a.Distance), shell(b.Distance), shell(e)}
+				sort.Ints(key)
+				clusters = append(clusters, sqsCluster{
+					kind([3]int{key[0], key[1], key[2]}),
+					[]int{index[i], index[a.Index], index[b.Index]},
+				})
+			}
+		}
+	}
+	return clusters, len(kinds), nil
+}
+
+// sqsObjective keep count of species multisets of each kind of cluster
+type sqsObjective struct {
+	clusters []sqsCluster
+	// of give clusters of each site
+	of [][]int
+	// count of clusters of each kind and multiset key
+	count  []map[int]int
+	total  []int
+	size   []int
+	target []map[int]float64
+	ns     int
+}
+
+func newSQSObjective(clusters []sqsCluster, kinds int, conc []float64, col []int) *sqsObjective {
+	o := &sqsObjective{
+		clusters: clusters,
+		of:       make([][]int, len(col)),
+		count:    make([]map[int]int, kinds),
+		total:    make([]int, kinds),
+		size:     make([]int, kinds),
+		target:   make([]map[int]float64, kinds),
+		ns:       len(conc),
+	}
+	for k := range o.count {
+		o.count[k] = make(map[int]int)
+	}
+	for ci, cl := range clusters {
+		seen := make(map[int]bool)
+		for _, s := range cl.sites {
+			if !seen[s] {
+				seen[s] = true
+				o.of[s] = append(o.of[s], ci)
+			}
+		}
+		o.total[cl.kind]++
+		o.size[cl.kind] = len(cl.sites)
+		o.count[cl.kind][o.key(cl, col)]++
+	}
+	// probability of multiset in random alloy, multinomial
+	for k := range o.target {
+		o.target[k] = make(map[int]float64)
+		var fill func(species []int)
+		fill = func(species []int) {
+			if len(species) == o.size[k] {
+				p := 1.0
+				perms := 1.0
+				same := 1
+				for i, s := range species {
+					p *= conc[s]
+					perms *= float64(i + 1)
+					if i > 0 && s == species[i-1] {
+						same++
+						perms /= float64(same)
+					} else {
+						same = 1
+					}
+				}
+				o.target[k][o.multiset(species)] = perms * p
+				return
+			}
+			start := 0
+			if len(species) > 0 {
+				start = species[len(species)-1]
+			}
+			for s := start; s < o.ns; s++ {
+				fill(append(species, s))
+			}
+		}
+		fill(nil)
+	}
+	return o
+}
+
+// multiset encode sorted species as integer
+func (o *sqsObjective) multiset(species []int) int {
+	key := 0
+	for _, s := range species {
+		key = key*o.ns + s
+	}
+	return key
+}
+
+func (o *sqsObjective) key(cl sqsCluster, col []int) int {
+	species := make([]int, len(cl.sites))
+	for i, s := range cl.sites {
+		species[i] = col[s]
+	}
+	sort.Ints(species)
+	return o.multiset(species)
+}
+
+// swap exchange species of sites i and j and update counts
+func (o *sqsObjective) swap(col []int, i, j int) {
+	affected := make(map[int]bool)
+	for _, ci := range o.of[i] {
+		affected[ci] = true
+	}
+	for _, ci := range o.of[j] {
+		affected[ci] = true
+	}
+	for ci := range affected {
+		cl := o.clusters[ci]
+		o.count[cl.kind][o.key(cl, col)]--
+	}
+	col[i], col[j] = col[j], col[i]
+	for ci := range affected {
+		cl := o.clusters[ci]
+		o.count[cl.kind][o.key(cl, col)]++
+	}
+}
+
+func (o *sqsObjective) value() float64 {
+	var v float64
+	for k, target := range o.target {
+		if o.total[k] == 0 {
+			continue
+		}
+		for key, p := range target {
+			diff := float64(o.count[k][key])/float64(o.total[k]) - p
+			v += diff * diff
+		}
+	}
+	return v
+}
@@ -0,0 +1,50 @@
+package crystal
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSQS(t *testing.T) {
+	a := 3.6
+	fcc, _ := NewCell([]float64{a, 0, 0, 0, a, 0, 0, 0, a},
+		[]float64{0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0}, []int{29, 29, 29, 29}, false)
+	d := &Decoration{Species: []int{29, 79}, Concentrations: []float64{0.5, 0.5}}
+	m := [3][3]int{{2, 0, 0}, {0, 2, 0}, {0, 0, 2}}
+	opt := &SQSOptions{PairCutoff: a, TripletCutoff: a / 1.4, Steps: 5000, Seed: 1}
+	r, obj, err := fcc.SQS(d, m, opt)
+	if err != nil {
+		t.Fatalf("sqs error: %v", err)
+	}
+	count := countSpecies(r)
+	if r.Natom != 32 || count[29] != 16 || count[79] != 16 || r.Elem[0] != r.Elem[15] || r.Elem[16] != r.Elem[31] {
+		t.Errorf("expect 16 Cu and 16 Au grouped, got %v", r.Elem)
+	}
+
+	// L1_0 ordering has pair correlations far from random
+	sc, _ := fcc.SupercellMatrix(m)
+	var decorated []int
+	for i := 0; i < sc.Natom; i++ {
+		decorated = append(decorated, i)
+	}
+	clusters, kinds, _ := sc.sqsClusters(decorated, opt.PairCutoff, opt.TripletCutoff)
+	col := make([]int, sc.Natom)
+	for i := 0; i < sc.Natom; i++ {
+		if sc.Position.At(i, 2)*2-float64(int(sc.Position.At(i, 2)*2)) > 0.25 {
+			col[i] = 1
+		}
+	}
+	ordered := newSQSObjective(clusters, kinds, d.Concentrations, col).value()
+	if obj >= ordered || obj > 0.05 {
+		t.Errorf("expect objective of sqs %g below that of L1_0 %g", obj, ordered)
+	}
+
+	again, obj2, _ := fcc.SQS(d, m, opt)
+	if obj2 != obj || !mat.Equal(again.Position, r.Position) {
+		t.Error("expect same sqs with same seed")
+	}
+	if _, _, err := fcc.SQS(d, m, &SQSOptions{}); err == nil {
+		t.Error("expect error without pair cutoff")
+	}
+}
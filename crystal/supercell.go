@@ -0,0 +1,168 @@
+package crystal
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Supercell return cell repeated na, nb and nc times along a, b and c
+func (c *Cell) Supercell(na, nb, nc int) (*Cell, error) {
+	return c.SupercellMatrix([3][3]int{{na, 0, 0}, {0, nb, 0}, {0, 0, nc}})
+}
+
+// SupercellMatrix return supercell with lattice rows m·Lattice, e.g.
+// {{1, -1, 0}, {1, 1, 0}, {0, 0, 1}} for √2×√2 cell. The determinant of m
+// must be positive. Images of each atom are consecutive, in the order of
+// the atoms of c, so atoms grouped by type stay grouped. Images are sorted
+// by lattice translation
+func (c *Cell) SupercellMatrix(m [3][3]int) (*Cell, error) {
+	tm := mat.NewDense(3, 3, nil)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			tm.Set(i, j, float64(m[i][j]))
+		}
+	}
+	det := int(math.Round(mat.Det(tm)))
+	if det <= 0 {
+		return nil, fmt.Errorf("supercell matrix %v must have positive determinant, got %d", m, det)
+	}
+	var inv mat.Dense
+	if err := inv.Inverse(tm); err != nil {
+		return nil, fmt.Errorf("supercell matrix %v: %v", m, err)
+	}
+
+	// lattice translations inside the supercell lie in the bounding box
+	// of the corners of m
+	var lo, hi [3]int
+	for corner := 0; corner < 8; corner++ {
+		var v [3]int
+		for i := 0; i < 3; i++ {
+			if corner&(1<<uint(i)) != 0 {
+				for j := 0; j < 3; j++ {
+					v[j] += m[i][j]
+				}
+			}
+		}
+		for j := 0; j < 3; j++ {
+			if v[j] < lo[j] {
+				lo[j] = v[j]
+			}
+			if v[j] > hi[j] {
+				hi[j] = v[j]
+			}
+		}
+	}
+
+	const eps = 1e-8
+	n := c.Natom * det
+	pos := make([]float64, 0, 3*n)
+	elem := make([]int, 0, n)
+	x := mat.NewVecDense(3, nil)
+	var xp mat.VecDense
+	for a := 0; a < c.Natom; a++ {
+		// the search below expects fraction coordinates in [0, 1)
+		var p [3]float64
+		for d := 0; d < 3; d++ {
+			v := c.Position.At(a, d)
+			p[d] = v - math.Floor(v)
+			if p[d] >= 1-eps {
+				p[d] = 0
+			}
+		}
+		count := 0
+		for i := lo[0]; i <= hi[0]; i++ {
+			for j := lo[1]; j <= hi[1]; j++ {
+				for k := lo[2]; k <= hi[2]; k++ {
+					x.SetVec(0, p[0]+float64(i))
+					x.SetVec(1, p[1]+float64(j))
+					x.SetVec(2, p[2]+float64(k))
+					// row vector x·m⁻¹
+					xp.MulVec(inv.T(), x)
+					inside := true
+					for d := 0; d < 3; d++ {
+						v := xp.AtVec(d)
+						if v < -eps || v >= 1-eps {
+							inside = false
+							break
+						}
+					}
+					if !inside {
+						continue
+					}
+					for d := 0; d < 3; d++ {
+						pos = append(pos, math.Max(0, xp.AtVec(d)))
+					}
+					elem = append(elem, c.Elem[a])
+					count++
+				}
+			}
+		}
+		if count != det {
+			return nil, fmt.Errorf("supercell: found %d images of atom %d, expect %d", count, a, det)
+		}
+	}
+
+	var latt mat.Dense
+	latt.Mul(tm, c.Lattice)
+	sc, err := NewCell(latt.RawMatrix().Data, pos, elem, false)
+	if err != nil {
+		return nil, err
+	}
+	sc.System = c.System
+	return sc, nil
+}
+
+// changeBasis return cell with lattice rows p·Lattice, p need not be integer,
+// e.g. to a primitive cell of a centred one. Positions are wrapped into
+// [0, 1) and atoms on the same site within tol in fraction coordinate are
+// merged. The number of atoms must be Natom·|det p|
+func (c *Cell) changeBasis(p *mat.Dense, tol float64) (*Cell, error) {
+	var inv mat.Dense
+	if err := inv.Inverse(p); err != nil {
+		return nil, fmt.Errorf("change basis %v: %v", mat.Formatted(p), err)
+	}
+	var x mat.Dense
+	x.Mul(c.Position, &inv)
+	var pos []float64
+	var elem []int
+	for i := 0; i < c.Natom; i++ {
+		var f [3]float64
+		for d := 0; d < 3; d++ {
+			v := x.At(i, d)
+			f[d] = v - math.Floor(v)
+			if f[d] > 1-tol {
+				f[d] = 0
+			}
+		}
+		dup := false
+		for j := range elem {
+			same := elem[j] == c.Elem[i]
+			for d := 0; d < 3 && same; d++ {
+				diff := f[d] - pos[j*3+d]
+				same = math.Abs(diff-math.Round(diff)) < tol
+			}
+			if same {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			pos = append(pos, f[:]...)
+			elem = append(elem, c.Elem[i])
+		}
+	}
+	n := float64(c.Natom) * math.Abs(mat.Det(p))
+	if math.Abs(n-float64(len(elem))) > 1e-6 {
+		return nil, fmt.Errorf("change basis %v: expect %g atoms, got %d", mat.Formatted(p), n, len(elem))
+	}
+	var latt mat.Dense
+	latt.Mul(p, c.Lattice)
+	r, err := NewCell(latt.RawMatrix().Data, pos, elem, false)
+	if err != nil {
+		return nil, err
+	}
+	r.System = c.System
+	return r, nil
+}
@@ -0,0 +1,118 @@
+package crystal
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSupercell(t *testing.T) {
+	c, _ := NewCell(
+		[]float64{4, 0, 0, 0, 4, 0, 0, 0, 4},
+		[]float64{0, 0, 0, 0.5, 0.5, 0.5},
+		[]int{11, 17},
+		false,
+	)
+	sc, err := c.Supercell(2, 1, 1)
+	if err != nil {
+		t.Fatalf("supercell error: %v", err)
+	}
+	expected, _ := NewCell(
+		[]float64{8, 0, 0, 0, 4, 0, 0, 0, 4},
+		[]float64{0, 0, 0, 0.5, 0, 0, 0.25, 0.5, 0.5, 0.75, 0.5, 0.5},
+		[]int{11, 11, 17, 17},
+		false,
+	)
+	if !mat.EqualApprox(sc.Lattice, expected.Lattice, 1e-8) {
+		t.Errorf("lattice: supercell expected %v, got %v.", expected, sc)
+	}
+	if !mat.EqualApprox(sc.Position, expected.Position, 1e-8) {
+		t.Errorf("position: supercell expected %v, got %v.", expected, sc)
+	}
+	for i := range expected.Elem {
+		if sc.Elem[i] != expected.Elem[i] {
+			t.Errorf("elem: supercell expected %v, got %v.", expected.Elem, sc.Elem)
+			break
+		}
+	}
+}
+
+func TestSupercellMatrix(t *testing.T) {
+	c, _ := NewCell(
+		[]float64{4, 0, 0, 0, 4, 0, 0, 0, 4},
+		[]float64{0, 0, 0, 0.5, 0.5, 0.5},
+		[]int{11, 17},
+		false,
+	)
+	// √2×√2 in plane, doubled along c
+	sc, err := c.SupercellMatrix([3][3]int{{1, -1, 0}, {1, 1, 0}, {0, 0, 2}})
+	if err != nil {
+		t.Fatalf("supercell error: %v", err)
+	}
+	if sc.Natom != 8 {
+		t.Fatalf("expect 8 atoms, got %d", sc.Natom)
+	}
+	if math.Abs(sc.Volume()-4*c.Volume()) > 1e-8 {
+		t.Errorf("volume expected %v, got %v", 4*c.Volume(), sc.Volume())
+	}
+	// every image is a lattice translation of an original atom
+	var cart, frac mat.Dense
+	cart.Mul(sc.Position, sc.Lattice)
+	var inv mat.Dense
+	inv.Inverse(c.Lattice)
+	frac.Mul(&cart, &inv)
+	for i := 0; i < sc.Natom; i++ {
+		orig := 0
+		if sc.Elem[i] == 17 {
+			orig = 1
+		}
+		for j := 0; j < 3; j++ {
+			d := frac.At(i, j) - c.Position.At(orig, j)
+			if math.Abs(d-math.Round(d)) > 1e-8 {
+				t.Errorf("atom %d is not an image of atom %d: %v", i, orig, frac.RawRowView(i))
+			}
+		}
+		for j := 0; j < 3; j++ {
+			if v := sc.Position.At(i, j); v < 0 || v >= 1 {
+				t.Errorf("position of atom %d not wrapped: %v", i, v)
+			}
+		}
+	}
+
+	for _, m := range [][3][3]int{
+		{{1, 0, 0}, {0, 1, 0}, {0, 0, 0}},
+		{{0, 1, 0}, {1, 0, 0}, {0, 0, 1}},
+	} {
+		if _, err := c.SupercellMatrix(m); err == nil {
+			t.Errorf("expect error for matrix %v", m)
+		}
+	}
+}
+
+func TestSupercellUnwrapped(t *testing.T) {
+	// coordinates 1 and -0.25 are legal in a POSCAR
+	c, _ := NewCell(
+		[]float64{4, 0, 0, 0, 4, 0, 0, 0, 4},
+		[]float64{1, 0, 0, 0.5, -0.25, 1.5},
+		[]int{11, 17},
+		false,
+	)
+	sc, err := c.Supercell(2, 2, 2)
+	if err != nil {
+		t.Fatalf("supercell error: %v", err)
+	}
+	if sc.Natom != 16 {
+		t.Fatalf("expect 16 atoms, got %d", sc.Natom)
+	}
+	for i := 0; i < sc.Natom; i++ {
+		for j := 0; j < 3; j++ {
+			if v := sc.Position.At(i, j); v < 0 || v >= 1 {
+				t.Errorf("position of atom %d not wrapped: %v", i, v)
+			}
+		}
+	}
+	if _, _, err := c.Niggli(1e-5); err != nil {
+		t.Errorf("niggli error: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package crystal
+
+import (
+	"fmt"
+
+	"github.com/unkcpz/spgolib"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Dataset is symmetry of cell found by spglib
+type Dataset struct {
+	// SpaceNumber is international table number of space group
+	SpaceNumber int
+	// SpaceSymbol is short Hermann–Mauguin symbol, e.g. P4_2/mnm
+	SpaceSymbol string
+	// HallNumber is serial number 1-530 of Hall symbol
+	HallNumber int
+	// HallSymbol e.g. -P 4n 2n
+	HallSymbol string
+	// Choice is setting of space group, e.g. origin choice 1 or 2
+	Choice string
+	// Transformation P and OriginShift p map cell to standard setting,
+	// (a_s b_s c_s) = (a b c) P^-1 and x_s = P x + p
+	Transformation *mat.Dense
+	OriginShift    *mat.VecDense
+	// Rotations and Translations are operations in fraction coordinate
+	Rotations    []Rotation
+	Translations []Translation
+	// Wyckoffs is Wyckoff letter of each atom
+	Wyckoffs []string
+	// SiteSymmetry is site symmetry symbol of each atom, e.g. mmm.
+	SiteSymmetry []string
+	// EquivalentAtoms map each atom to first atom equivalent by Rotations
+	// and Translations
+	EquivalentAtoms []int
+	// Orbits map each atom to first atom equivalent by symmetry of
+	// primitive cell
+	Orbits []int
+	// PointGroup is Hermann–Mauguin symbol of point group, e.g. 4/mmm
+	PointGroup string
+}
+
+// Dataset return symmetry dataset of cell
+func (c *Cell) Dataset(symprec float64) (*Dataset, error) {
+	ds := spgolib.NewDataset(c.LatticeSlice(), c.PositionSlice(), c.Elem, symprec)
+	if ds == nil || ds.SpaceNumber == 0 {
+		return nil, fmt.Errorf("spglib: symmetry not found with symprec %g", symprec)
+	}
+	rots, trans := operations(ds)
+	wyckoffs := make([]string, len(ds.Wyckoffs))
+	for i, w := range ds.Wyckoffs {
+		wyckoffs[i] = wyckoffLetter(w)
+	}
+	d := &Dataset{
+		SpaceNumber:     ds.SpaceNumber,
+		SpaceSymbol:     ds.SpaceSymbol,
+		HallNumber:      ds.HallNumber,
+		HallSymbol:      ds.HallSymbol,
+		Choice:          ds.Choice,
+		Transformation:  mat.NewDense(3, 3, append([]float64(nil), ds.TransformationMatrix...)),
+		OriginShift:     mat.NewVecDense(3, append([]float64(nil), ds.OriginShift...)),
+		Rotations:       rots,
+		Translations:    trans,
+		Wyckoffs:        wyckoffs,
+		SiteSymmetry:    append([]string(nil), ds.SiteSymmetrySymbols...),
+		EquivalentAtoms: append([]int(nil), ds.EquivalentAtoms...),
+		Orbits:          append([]int(nil), ds.CrystallographicOrbits...),
+		PointGroup:      ds.PointgroupSymbol,
+	}
+	return d, nil
+}
+
+// InequivalentAtoms return first atom of each set of equivalent atoms
+func (d *Dataset) InequivalentAtoms() []int {
+	var r []int
+	for i, e := range d.EquivalentAtoms {
+		if i == e {
+			r = append(r, i)
+		}
+	}
+	return r
+}
+
+// Orbit return atoms equivalent to atom i, including i
+func (d *Dataset) Orbit(i int) []int {
+	var r []int
+	for j, e := range d.EquivalentAtoms {
+		if e == d.EquivalentAtoms[i] {
+			r = append(r, j)
+		}
+	}
+	return r
+}
+
+// Multiplicity return number of atoms equivalent to atom i in cell
+func (d *Dataset) Multiplicity(i int) int {
+	return len(d.Orbit(i))
+}
+
+// operations convert spglib operations
+func operations(ds *spgolib.Dataset) ([]Rotation, []Translation) {
+	nop := ds.Nops
+	rots := make([]Rotation, nop)
+	trans := make([]Translation, nop)
+	for i := 0; i < nop; i++ {
+		rots[i].data = mat.NewDense(3, 3, intToFloat(ds.Rotations[i*9:i*9+9]))
+		trans[i].data = mat.NewVecDense(3, append([]float64(nil), ds.Translations[i*3:i*3+3]...))
+	}
+	return rots, trans
+}
+
+// wyckoffLetter convert spglib Wyckoff index to letter, the 27th position
+// of Pmmm is denoted by α
+func wyckoffLetter(w int) string {
+	if w >= 0 && w < 26 {
+		return string(rune('a' + w))
+	}
+	return "α"
+}
@@ -0,0 +1,57 @@
+package crystal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDataset(t *testing.T) {
+	// rutile TiO2, Ti at 2a and O at 4f
+	u := 0.3048
+	c, _ := NewCell(
+		[]float64{4.594, 0, 0, 0, 4.594, 0, 0, 0, 2.959},
+		[]float64{
+			0, 0, 0, 0.5, 0.5, 0.5,
+			u, u, 0, 1 - u, 1 - u, 0, 0.5 + u, 0.5 - u, 0.5, 0.5 - u, 0.5 + u, 0.5,
+		},
+		[]int{22, 22, 8, 8, 8, 8},
+		false,
+	)
+	ds, err := c.Dataset(1e-5)
+	if err != nil {
+		t.Fatalf("dataset error: %v", err)
+	}
+	if ds.SpaceNumber != 136 || ds.HallNumber != 419 || ds.PointGroup != "4/mmm" {
+		t.Errorf("expect P4_2/mnm (136), Hall 419, 4/mmm, got %s (%d), Hall %d, %s",
+			ds.SpaceSymbol, ds.SpaceNumber, ds.HallNumber, ds.PointGroup)
+	}
+	if len(ds.Rotations) != 16 {
+		t.Errorf("expect 16 operations, got %d", len(ds.Rotations))
+	}
+	expected := []string{"a", "a", "f", "f", "f", "f"}
+	if !reflect.DeepEqual(ds.Wyckoffs, expected) {
+		t.Errorf("wyckoffs expected %v, got %v", expected, ds.Wyckoffs)
+	}
+	if !reflect.DeepEqual(ds.InequivalentAtoms(), []int{0, 2}) {
+		t.Errorf("inequivalent atoms expected [0 2], got %v", ds.InequivalentAtoms())
+	}
+	if ds.SiteSymmetry[0] != "m.mm" || ds.SiteSymmetry[2] != "m.2m" {
+		t.Errorf("site symmetry expected m.mm and m.2m, got %v", ds.SiteSymmetry)
+	}
+}
+
+func TestDatasetOrbit(t *testing.T) {
+	ds := &Dataset{EquivalentAtoms: []int{0, 0, 2, 2, 2, 5}}
+	if !reflect.DeepEqual(ds.InequivalentAtoms(), []int{0, 2, 5}) {
+		t.Errorf("inequivalent atoms expected [0 2 5], got %v", ds.InequivalentAtoms())
+	}
+	if !reflect.DeepEqual(ds.Orbit(3), []int{2, 3, 4}) {
+		t.Errorf("orbit expected [2 3 4], got %v", ds.Orbit(3))
+	}
+	if ds.Multiplicity(5) != 1 || ds.Multiplicity(1) != 2 {
+		t.Errorf("multiplicity expected 1 and 2, got %d and %d", ds.Multiplicity(5), ds.Multiplicity(1))
+	}
+	if wyckoffLetter(0) != "a" || wyckoffLetter(25) != "z" || wyckoffLetter(26) != "α" {
+		t.Error("wyckoff letters expected a, z, α")
+	}
+}
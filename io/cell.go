@@ -1,5 +1,10 @@
 package io
 
+import (
+  "fmt"
+  "math"
+)
+
 type CoorType int
 
 const (
@@ -18,4 +23,77 @@ type Cell struct {
   Positions []float64
   //
   Types []string
+  // SelectiveDynamics holds 3 flags (x, y, z) per atom, true means the
+  // coordinate is allowed to relax, nil if not constrained
+  SelectiveDynamics []bool
+  // Velocities holds cartesian velocity (Å/fs) per atom, nil if absent
+  Velocities []float64
+  // PredictorCorrector is the raw predictor-corrector block of a CONTCAR
+  // from molecular dynamics, kept verbatim for restarts
+  PredictorCorrector string
+}
+
+// FractionalPositions return positions in fraction of lattice vectors
+func (c *Cell) FractionalPositions() ([]float64, error) {
+  pos := make([]float64, len(c.Positions))
+  copy(pos, c.Positions)
+  if c.Coordinate == Fractional {
+    return pos, nil
+  }
+  inv, err := inverse3(c.Lattice)
+  if err != nil {
+    return nil, err
+  }
+  return mulRows(pos, inv), nil
+}
+
+// CartesianPositions return positions in cartesian coordinate
+func (c *Cell) CartesianPositions() ([]float64, error) {
+  pos := make([]float64, len(c.Positions))
+  copy(pos, c.Positions)
+  if c.Coordinate == Cartesian {
+    return pos, nil
+  }
+  if len(c.Lattice) != 9 {
+    return nil, fmt.Errorf("expect 9 value as lattice, got %d", len(c.Lattice))
+  }
+  return mulRows(pos, c.Lattice), nil
+}
+
+// mulRows multiply every 3-vector in v by row-major 3x3 matrix m
+func mulRows(v []float64, m []float64) []float64 {
+  r := make([]float64, len(v))
+  for i:=0; i+2<len(v); i+=3 {
+    for j:=0; j<3; j++ {
+      r[i+j] = v[i]*m[j] + v[i+1]*m[3+j] + v[i+2]*m[6+j]
+    }
+  }
+  return r
+}
+
+// inverse3 return inverse of row-major 3x3 matrix m
+func inverse3(m []float64) ([]float64, error) {
+  if len(m) != 9 {
+    return nil, fmt.Errorf("expect 9 value as lattice, got %d", len(m))
+  }
+  det := det3(m)
+  if math.Abs(det) < 1e-10 {
+    return nil, fmt.Errorf("lattice cannot be det=0")
+  }
+  inv := []float64{
+    m[4]*m[8] - m[5]*m[7], m[2]*m[7] - m[1]*m[8], m[1]*m[5] - m[2]*m[4],
+    m[5]*m[6] - m[3]*m[8], m[0]*m[8] - m[2]*m[6], m[2]*m[3] - m[0]*m[5],
+    m[3]*m[7] - m[4]*m[6], m[1]*m[6] - m[0]*m[7], m[0]*m[4] - m[1]*m[3],
+  }
+  for i := range inv {
+    inv[i] /= det
+  }
+  return inv, nil
+}
+
+// det3 return determinant of row-major 3x3 matrix m
+func det3(m []float64) float64 {
+  return m[0]*(m[4]*m[8]-m[5]*m[7]) -
+    m[1]*(m[3]*m[8]-m[5]*m[6]) +
+    m[2]*(m[3]*m[7]-m[4]*m[6])
 }
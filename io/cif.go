@@ -0,0 +1,610 @@
+package io
+
+import (
+  "fmt"
+  "io"
+  "math"
+  "strconv"
+  "strings"
+  "unicode"
+
+  "github.com/unkcpz/gocmp/crystal"
+)
+
+// CifError is returned by ParseCif when the input is malformed
+type CifError struct {
+  // Line is 1-based line number of the offending token
+  Line int
+  // Msg describe what is wrong
+  Msg string
+}
+
+func (e *CifError) Error() string {
+  return fmt.Sprintf("cif line %d: %s", e.Line, e.Msg)
+}
+
+// CifSite is an atom site of CIF
+type CifSite struct {
+  // Label of site, e.g. Fe1
+  Label string
+  // Symbol of element
+  Symbol string
+  // Position in fraction coordinate
+  Position [3]float64
+  // Occupancy of site, 1 if not given
+  Occupancy float64
+}
+
+// Cif is crystal structure of a CIF data block
+type Cif struct {
+  // Name of data block
+  Name string
+  // A, B, C are cell lengths in Å, Alpha, Beta, Gamma cell angles in degree
+  A, B, C, Alpha, Beta, Gamma float64
+  // SpaceGroup is Hermann-Mauguin symbol, empty if not given
+  SpaceGroup string
+  // SpaceGroupNumber is number in International Tables, 0 if not given
+  SpaceGroupNumber int
+  // Operations of space group as xyz strings, e.g. "-y,x-y,z+1/2"
+  Operations []string
+  // Sites of asymmetric unit
+  Sites []CifSite
+}
+
+// DefaultCifSiteTolerance is distance in Å under which two symmetry images
+// of a site are merged
+const DefaultCifSiteTolerance = 0.01
+
+// ParseCif parse all data blocks of CIF which contain a crystal structure
+func ParseCif(txt string) ([]*Cif, error) {
+  blocks, err := parseCifBlocks(txt)
+  if err != nil {
+    return nil, err
+  }
+  cifs := make([]*Cif, 0)
+  for _, b := range blocks {
+    if _, ok := b.items["_cell_length_a"]; !ok {
+      continue
+    }
+    c, err := b.cif()
+    if err != nil {
+      return nil, err
+    }
+    cifs = append(cifs, c)
+  }
+  if len(cifs) == 0 {
+    return nil, fmt.Errorf("cif: no data block with cell parameters")
+  }
+  return cifs, nil
+}
+
+// Expand apply symmetry operations to asymmetric unit and return all sites
+// of the cell, images closer than tol Å are merged. tol <= 0 means
+// DefaultCifSiteTolerance
+func (c *Cif) Expand(tol float64) ([]CifSite, error) {
+  if tol <= 0 {
+    tol = DefaultCifSiteTolerance
+  }
+  lattice, err := crystal.LatticeFromParameters(c.A, c.B, c.C, c.Alpha, c.Beta, c.Gamma)
+  if err != nil {
+    return nil, err
+  }
+  ops := c.Operations
+  if len(ops) == 0 {
+    ops = []string{"x,y,z"}
+  }
+  sym := make([]crystal.Operation, len(ops))
+  for i, op := range ops {
+    sym[i], err = crystal.ParseXYZ(op)
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  sites := make([]CifSite, 0, len(c.Sites)*len(ops))
+  for _, s := range c.Sites {
+    start := len(sites)
+    for _, op := range sym {
+      p := op.Apply(s.Position)
+      for j:=0; j<3; j++ {
+        p[j] -= math.Floor(p[j])
+      }
+      dup := false
+      for _, o := range sites[start:] {
+        if periodicDistance(lattice, p, o.Position) < tol {
+          dup = true
+          break
+        }
+      }
+      if !dup {
+        ns := s
+        ns.Position = p
+        sites = append(sites, ns)
+      }
+    }
+  }
+  return sites, nil
+}
+
+// Cell expand c to crystal.Cell, partially occupied sites cannot be
+// represented and return error
+func (c *Cif) Cell(tol float64) (*crystal.Cell, error) {
+  sites, err := c.Expand(tol)
+  if err != nil {
+    return nil, err
+  }
+  lattice, err := crystal.LatticeFromParameters(c.A, c.B, c.C, c.Alpha, c.Beta, c.Gamma)
+  if err != nil {
+    return nil, err
+  }
+  elem := make([]int, len(sites))
+  pos := make([]float64, 3*len(sites))
+  for i, s := range sites {
+    if s.Occupancy < 1-1e-3 {
+      return nil, fmt.Errorf("cif: site %s has partial occupancy %g", s.Label, s.Occupancy)
+    }
+    elem[i] = crystal.SymToNum(s.Symbol)
+    if elem[i] == 0 {
+      return nil, fmt.Errorf("cif: unknown element symbol %q of site %s", s.Symbol, s.Label)
+    }
+    copy(pos[i*3:], s.Position[:])
+  }
+  cell, err := crystal.NewCell(lattice, pos, elem, false)
+  if err != nil {
+    return nil, err
+  }
+  cell.System = c.Name
+  return cell, nil
+}
+
+// CifOptions control FormatCif
+type CifOptions struct {
+  // Symmetrize write asymmetric unit and symmetry operations found by
+  // spglib instead of all atoms in P1. Operations are given in the basis of
+  // the cell, the space group symbol and number are left out unless the cell
+  // is in the standard setting, refine the cell first to get them
+  Symmetrize bool
+  // Symprec is tolerance of symmetry search, 0 means 1e-5
+  Symprec float64
+  // Precision is number of decimals of positions, 0 means 6
+  Precision int
+}
+
+// FormatCif render cell as CIF. opt can be nil
+func FormatCif(c *crystal.Cell, opt *CifOptions) (string, error) {
+  if opt == nil {
+    opt = &CifOptions{}
+  }
+  symprec := opt.Symprec
+  if symprec <= 0 {
+    symprec = 1e-5
+  }
+  prec := opt.Precision
+  if prec <= 0 {
+    prec = 6
+  }
+  types := make([]string, c.Natom)
+  for i:=0; i<c.Natom; i++ {
+    types[i] = crystal.NumToSym(c.Elem[i])
+    if types[i] == "" {
+      return "", fmt.Errorf("unknown atomic number %d of atom %d", c.Elem[i], i)
+    }
+  }
+  pos := c.PositionSlice()
+
+  hm, number := "P 1", 1
+  ops := []string{"x,y,z"}
+  sites := make([]int, c.Natom)
+  for i := range sites {
+    sites[i] = i
+  }
+  if opt.Symmetrize {
+    ds, err := c.Dataset(symprec)
+    if err != nil {
+      return "", fmt.Errorf("cif: %v", err)
+    }
+    hm, number = "", 0
+    if standardSetting(ds) {
+      hm, number = ds.SpaceSymbol, ds.SpaceNumber
+    }
+    ops = make([]string, len(ds.Rotations))
+    for i, op := range ds.Operations() {
+      ops[i] = op.XYZ()
+    }
+    sites = ds.InequivalentAtoms()
+  }
+
+  name := strings.Join(strings.Fields(c.System), "_")
+  if name == "" {
+    name = "gocmp"
+  }
+  a, b, cc, alpha, beta, gamma := c.Parameters()
+  var w strings.Builder
+  fmt.Fprintf(&w, "data_%s\n", name)
+  if number > 0 {
+    fmt.Fprintf(&w, "_symmetry_space_group_name_H-M    '%s'\n", hm)
+    fmt.Fprintf(&w, "_symmetry_Int_Tables_number       %d\n", number)
+  }
+  fmt.Fprintf(&w, "_cell_length_a                    %.*f\n", prec, a)
+  fmt.Fprintf(&w, "_cell_length_b                    %.*f\n", prec, b)
+  fmt.Fprintf(&w, "_cell_length_c                    %.*f\n", prec, cc)
+  fmt.Fprintf(&w, "_cell_angle_alpha                 %.*f\n", prec, alpha)
+  fmt.Fprintf(&w, "_cell_angle_beta                  %.*f\n", prec, beta)
+  fmt.Fprintf(&w, "_cell_angle_gamma                 %.*f\n", prec, gamma)
+  fmt.Fprintf(&w, "_cell_volume                      %.*f\n", prec, c.Volume())
+  fmt.Fprintln(&w)
+  fmt.Fprintln(&w, "loop_")
+  fmt.Fprintln(&w, "_symmetry_equiv_pos_as_xyz")
+  for _, op := range ops {
+    fmt.Fprintf(&w, "  '%s'\n", op)
+  }
+  fmt.Fprintln(&w)
+  fmt.Fprintln(&w, "loop_")
+  fmt.Fprintln(&w, "_atom_site_label")
+  fmt.Fprintln(&w, "_atom_site_type_symbol")
+  fmt.Fprintln(&w, "_atom_site_fract_x")
+  fmt.Fprintln(&w, "_atom_site_fract_y")
+  fmt.Fprintln(&w, "_atom_site_fract_z")
+  fmt.Fprintln(&w, "_atom_site_occupancy")
+  count := make(map[string]int)
+  for _, i := range sites {
+    count[types[i]]++
+    fmt.Fprintf(&w, "  %s%d %s %.*f %.*f %.*f 1.0\n", types[i], count[types[i]], types[i],
+      prec, pos[i*3], prec, pos[i*3+1], prec, pos[i*3+2])
+  }
+  return w.String(), nil
+}
+
+// standardSetting return true if ds transforms the cell to itself, then its
+// operations are those of the space group symbol
+func standardSetting(ds *crystal.Dataset) bool {
+  const eps = 1e-8
+  for i:=0; i<3; i++ {
+    for j:=0; j<3; j++ {
+      want := 0.0
+      if i == j {
+        want = 1
+      }
+      if math.Abs(ds.Transformation.At(i, j) - want) > eps {
+        return false
+      }
+    }
+    p := ds.OriginShift.AtVec(i)
+    if math.Abs(p - math.Round(p)) > eps {
+      return false
+    }
+  }
+  return true
+}
+
+// WriteCif write cell to w as CIF, see FormatCif
+func WriteCif(w io.Writer, c *crystal.Cell, opt *CifOptions) error {
+  txt, err := FormatCif(c, opt)
+  if err != nil {
+    return err
+  }
+  _, err = io.WriteString(w, txt)
+  return err
+}
+
+// periodicDistance return cartesian distance between fraction points p and
+// q, taking the nearest periodic image along each axis
+func periodicDistance(lattice []float64, p, q [3]float64) float64 {
+  d := make([]float64, 3)
+  for j:=0; j<3; j++ {
+    d[j] = p[j] - q[j]
+    d[j] -= math.Floor(d[j] + 0.5)
+  }
+  best := math.Inf(1)
+  // try neighbouring images, nearest fraction image is not always nearest
+  // in cartesian for skewed lattice
+  for i:=-1; i<=1; i++ {
+    for j:=-1; j<=1; j++ {
+      for k:=-1; k<=1; k++ {
+        v := mulRows([]float64{d[0]+float64(i), d[1]+float64(j), d[2]+float64(k)}, lattice)
+        best = math.Min(best, math.Sqrt(v[0]*v[0]+v[1]*v[1]+v[2]*v[2]))
+      }
+    }
+  }
+  return best
+}
+
+// cifBlock is a data block of CIF, tags are lower case
+type cifBlock struct {
+  name string
+  items map[string]string
+  itemLine map[string]int
+  loops []cifLoop
+}
+
+type cifLoop struct {
+  tags []string
+  rows [][]string
+  line int
+}
+
+// column return values of tag in loop, nil if absent
+func (b *cifBlock) column(tag string) ([]string, int) {
+  for _, l := range b.loops {
+    for j, t := range l.tags {
+      if t == tag {
+        col := make([]string, len(l.rows))
+        for i, r := range l.rows {
+          col[i] = r[j]
+        }
+        return col, l.line
+      }
+    }
+  }
+  return nil, 0
+}
+
+func (b *cifBlock) number(tag string) (float64, error) {
+  v, ok := b.items[tag]
+  if !ok {
+    return 0, fmt.Errorf("cif: data_%s missing %s", b.name, tag)
+  }
+  f, err := cifNumber(v)
+  if err != nil {
+    return 0, &CifError{Line: b.itemLine[tag], Msg: fmt.Sprintf("parse %s, %v", tag, err)}
+  }
+  return f, nil
+}
+
+func (b *cifBlock) cif() (*Cif, error) {
+  c := &Cif{Name: b.name}
+  var err error
+  vals := []*float64{&c.A, &c.B, &c.C, &c.Alpha, &c.Beta, &c.Gamma}
+  tags := []string{"_cell_length_a", "_cell_length_b", "_cell_length_c",
+    "_cell_angle_alpha", "_cell_angle_beta", "_cell_angle_gamma"}
+  for i, t := range tags {
+    if *vals[i], err = b.number(t); err != nil {
+      return nil, err
+    }
+  }
+  for _, t := range []string{"_symmetry_space_group_name_h-m", "_space_group_name_h-m_alt"} {
+    if v, ok := b.items[t]; ok && !cifUnknown(v) {
+      c.SpaceGroup = v
+    }
+  }
+  for _, t := range []string{"_symmetry_int_tables_number", "_space_group_it_number"} {
+    if v, ok := b.items[t]; ok && !cifUnknown(v) {
+      n, err := strconv.Atoi(v)
+      if err != nil {
+        return nil, &CifError{Line: b.itemLine[t], Msg: fmt.Sprintf("parse %s, %v", t, err)}
+      }
+      c.SpaceGroupNumber = n
+    }
+  }
+
+  for _, t := range []string{"_symmetry_equiv_pos_as_xyz", "_space_group_symop_operation_xyz"} {
+    ops, line := b.column(t)
+    if ops == nil {
+      if v, ok := b.items[t]; ok {
+        ops, line = []string{v}, b.itemLine[t]
+      }
+    }
+    for _, op := range ops {
+      if _, err := crystal.ParseXYZ(op); err != nil {
+        return nil, &CifError{Line: line, Msg: err.Error()}
+      }
+    }
+    if ops != nil {
+      c.Operations = ops
+      break
+    }
+  }
+
+  labels, line := b.column("_atom_site_label")
+  symbols, symLine := b.column("_atom_site_type_symbol")
+  xs, xLine := b.column("_atom_site_fract_x")
+  ys, yLine := b.column("_atom_site_fract_y")
+  zs, zLine := b.column("_atom_site_fract_z")
+  occs, occLine := b.column("_atom_site_occupancy")
+  if xs == nil || ys == nil || zs == nil {
+    return nil, fmt.Errorf("cif: data_%s missing _atom_site_fract_x/y/z", b.name)
+  }
+  if labels == nil && symbols == nil {
+    return nil, fmt.Errorf("cif: data_%s missing _atom_site_label and _atom_site_type_symbol", b.name)
+  }
+  if labels == nil {
+    line = symLine
+  }
+  // columns may come from different loops, each must have a row per site
+  for _, col := range []struct {
+    tag  string
+    vals []string
+    line int
+  }{
+    {"_atom_site_label", labels, line},
+    {"_atom_site_type_symbol", symbols, symLine},
+    {"_atom_site_fract_y", ys, yLine},
+    {"_atom_site_fract_z", zs, zLine},
+    {"_atom_site_occupancy", occs, occLine},
+  } {
+    if col.vals != nil && len(col.vals) != len(xs) {
+      return nil, &CifError{Line: col.line, Msg: fmt.Sprintf(
+        "%s has %d values, _atom_site_fract_x in loop at line %d has %d", col.tag, len(col.vals), xLine, len(xs))}
+    }
+  }
+  for i := range xs {
+    s := CifSite{Occupancy: 1}
+    if labels != nil {
+      s.Label = labels[i]
+    }
+    sym := s.Label
+    if symbols != nil && !cifUnknown(symbols[i]) {
+      sym = symbols[i]
+    }
+    s.Symbol = elementOfLabel(sym)
+    if s.Label == "" {
+      s.Label = s.Symbol
+    }
+    if s.Symbol == "" {
+      return nil, &CifError{Line: line, Msg: fmt.Sprintf("unknown element of site %q", sym)}
+    }
+    for j, v := range []string{xs[i], ys[i], zs[i]} {
+      if s.Position[j], err = cifNumber(v); err != nil {
+        return nil, &CifError{Line: line, Msg: fmt.Sprintf("parse position of site %s, %v", s.Label, err)}
+      }
+    }
+    if occs != nil && !cifUnknown(occs[i]) {
+      if s.Occupancy, err = cifNumber(occs[i]); err != nil {
+        return nil, &CifError{Line: line, Msg: fmt.Sprintf("parse occupancy of site %s, %v", s.Label, err)}
+      }
+    }
+    c.Sites = append(c.Sites, s)
+  }
+  return c, nil
+}
+
+// cifUnknown report whether v is "?" (unknown) or "." (inapplicable)
+func cifUnknown(v string) bool {
+  return v == "?" || v == "."
+}
+
+// cifNumber parse number with optional standard uncertainty, e.g. 5.431(2)
+func cifNumber(v string) (float64, error) {
+  if i := strings.Index(v, "("); i >= 0 {
+    v = v[:i]
+  }
+  return strconv.ParseFloat(v, 64)
+}
+
+// elementOfLabel return element symbol at start of label or type symbol
+// such as "Fe1", "Fe3+" or "O2-", empty if none
+func elementOfLabel(s string) string {
+  letters := 0
+  for letters < len(s) && letters < 2 && unicode.IsLetter(rune(s[letters])) {
+    letters++
+  }
+  if letters == 0 {
+    return ""
+  }
+  if letters == 2 {
+    sym := strings.ToUpper(s[:1]) + strings.ToLower(s[1:2])
+    if crystal.SymToNum(sym) != 0 {
+      return sym
+    }
+  }
+  sym := strings.ToUpper(s[:1])
+  if crystal.SymToNum(sym) != 0 {
+    return sym
+  }
+  return ""
+}
+
+type cifToken struct {
+  value string
+  line int
+  // quoted values are never tags or keywords
+  quoted bool
+}
+
+// tokenizeCif split CIF into tokens, dropping comments
+func tokenizeCif(txt string) ([]cifToken, error) {
+  lines := strings.Split(strings.Replace(txt, "\r\n", "\n", -1), "\n")
+  tokens := make([]cifToken, 0)
+  for n:=0; n<len(lines); n++ {
+    line := lines[n]
+    if strings.HasPrefix(line, ";") {
+      // semicolon text field up to next line starting with ;
+      start := n
+      text := []string{line[1:]}
+      for n++; n<len(lines) && !strings.HasPrefix(lines[n], ";"); n++ {
+        text = append(text, lines[n])
+      }
+      if n == len(lines) {
+        return nil, &CifError{Line: start+1, Msg: "unterminated text field"}
+      }
+      tokens = append(tokens, cifToken{strings.TrimSpace(strings.Join(text, "\n")), start+1, true})
+      continue
+    }
+    for i:=0; i<len(line); {
+      c := line[i]
+      switch {
+      case c == ' ' || c == '\t':
+        i++
+      case c == '#':
+        i = len(line)
+      case c == '\'' || c == '"':
+        // quote ends at matching quote followed by whitespace or end
+        j := i+1
+        for ; j<len(line); j++ {
+          if line[j] == c && (j+1 == len(line) || line[j+1] == ' ' || line[j+1] == '\t') {
+            break
+          }
+        }
+        if j >= len(line) {
+          return nil, &CifError{Line: n+1, Msg: "unterminated quoted string"}
+        }
+        tokens = append(tokens, cifToken{line[i+1:j], n+1, true})
+        i = j+1
+      default:
+        j := i
+        for j < len(line) && line[j] != ' ' && line[j] != '\t' {
+          j++
+        }
+        tokens = append(tokens, cifToken{line[i:j], n+1, false})
+        i = j
+      }
+    }
+  }
+  return tokens, nil
+}
+
+func parseCifBlocks(txt string) ([]*cifBlock, error) {
+  tokens, err := tokenizeCif(txt)
+  if err != nil {
+    return nil, err
+  }
+  isTag := func(t cifToken) bool { return !t.quoted && strings.HasPrefix(t.value, "_") }
+  isKeyword := func(t cifToken, k string) bool {
+    return !t.quoted && strings.HasPrefix(strings.ToLower(t.value), k)
+  }
+
+  blocks := make([]*cifBlock, 0)
+  var b *cifBlock
+  for i:=0; i<len(tokens); {
+    t := tokens[i]
+    switch {
+    case isKeyword(t, "data_"):
+      b = &cifBlock{name: t.value[5:], items: map[string]string{}, itemLine: map[string]int{}}
+      blocks = append(blocks, b)
+      i++
+    case b == nil:
+      return nil, &CifError{Line: t.line, Msg: fmt.Sprintf("expect data_ block, got %q", t.value)}
+    case isKeyword(t, "loop_"):
+      l := cifLoop{line: t.line}
+      for i++; i<len(tokens) && isTag(tokens[i]); i++ {
+        l.tags = append(l.tags, strings.ToLower(tokens[i].value))
+      }
+      if len(l.tags) == 0 {
+        return nil, &CifError{Line: t.line, Msg: "loop_ without tags"}
+      }
+      values := make([]string, 0)
+      for ; i<len(tokens) && !isTag(tokens[i]) && !isKeyword(tokens[i], "loop_") &&
+        !isKeyword(tokens[i], "data_"); i++ {
+        values = append(values, tokens[i].value)
+      }
+      if len(values)%len(l.tags) != 0 {
+        return nil, &CifError{Line: t.line, Msg: fmt.Sprintf("loop_ has %d values for %d tags", len(values), len(l.tags))}
+      }
+      for j:=0; j<len(values); j+=len(l.tags) {
+        l.rows = append(l.rows, values[j:j+len(l.tags)])
+      }
+      b.loops = append(b.loops, l)
+    case isTag(t):
+      if i+1 >= len(tokens) || isTag(tokens[i+1]) {
+        return nil, &CifError{Line: t.line, Msg: fmt.Sprintf("tag %s without value", t.value)}
+      }
+      tag := strings.ToLower(t.value)
+      b.items[tag] = tokens[i+1].value
+      b.itemLine[tag] = t.line
+      i += 2
+    default:
+      return nil, &CifError{Line: t.line, Msg: fmt.Sprintf("unexpected value %q", t.value)}
+    }
+  }
+  return blocks, nil
+}
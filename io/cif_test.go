@@ -0,0 +1,228 @@
+package io
+
+import(
+  "errors"
+  "math"
+  "testing"
+
+  "github.com/unkcpz/gocmp/crystal"
+  "gonum.org/v1/gonum/mat"
+)
+
+// rutile TiO2 in the style of COD
+const cifRutile = `#------------------------------------------------------------------------------
+data_9004141
+_chemical_formula_sum                    'O2 Ti'
+_space_group_IT_number                   136
+_symmetry_space_group_name_H-M           'P 42/m n m'
+_cell_angle_alpha                        90
+_cell_angle_beta                         90
+_cell_angle_gamma                        90
+_cell_length_a                           4.5937(3)
+_cell_length_b                           4.5937(3)
+_cell_length_c                           2.9587(2)
+_journal_name_full
+;
+American Mineralogist
+;
+loop_
+_space_group_symop_operation_xyz
+x,y,z
+-x,-y,z
+1/2-y,1/2+x,1/2+z
+1/2+y,1/2-x,1/2+z
+1/2-x,1/2+y,1/2-z
+1/2+x,1/2-y,1/2-z
+y,x,-z
+-y,-x,-z
+-x,-y,-z
+x,y,-z
+1/2+y,1/2-x,1/2-z
+1/2-y,1/2+x,1/2-z
+1/2+x,1/2-y,1/2+z
+1/2-x,1/2+y,1/2+z
+-y,-x,z
+y,x,z
+loop_
+_atom_site_label
+_atom_site_type_symbol
+_atom_site_fract_x
+_atom_site_fract_y
+_atom_site_fract_z
+_atom_site_occupancy
+Ti1 Ti4+ 0.00000 0.00000 0.00000 1.0
+O1  O2-  0.30478 0.30478 0.00000 1.0
+`
+
+func TestParseCif(t *testing.T) {
+  cifs, err := ParseCif(cifRutile)
+  if err != nil {
+    t.Fatalf("parse cif error: %v", err)
+  }
+  c := cifs[0]
+  if c.Name != "9004141" || c.SpaceGroupNumber != 136 || c.SpaceGroup != "P 42/m n m" {
+    t.Errorf("cif header: got %q %q %d", c.Name, c.SpaceGroup, c.SpaceGroupNumber)
+  }
+  if math.Abs(c.A - 4.5937) > 1e-8 || math.Abs(c.C - 2.9587) > 1e-8 || c.Gamma != 90 {
+    t.Errorf("cif cell parameters: got %v %v %v %v", c.A, c.B, c.C, c.Gamma)
+  }
+  if len(c.Operations) != 16 || len(c.Sites) != 2 {
+    t.Fatalf("expect 16 operations and 2 sites, got %d and %d", len(c.Operations), len(c.Sites))
+  }
+  if c.Sites[0].Symbol != "Ti" || c.Sites[1].Symbol != "O" {
+    t.Errorf("site symbols expected [Ti O], got %v %v", c.Sites[0].Symbol, c.Sites[1].Symbol)
+  }
+
+  cell, err := c.Cell(0)
+  if err != nil {
+    t.Fatalf("cif to cell error: %v", err)
+  }
+  if cell.Natom != 6 {
+    t.Fatalf("expect 6 atoms in rutile cell, got %d", cell.Natom)
+  }
+  nTi := 0
+  for _, e := range cell.Elem {
+    if e == 22 {
+      nTi++
+    }
+  }
+  if nTi != 2 {
+    t.Errorf("expect 2 Ti in rutile cell, got %d", nTi)
+  }
+}
+
+func TestParseCifPartialOccupancy(t *testing.T) {
+  txt := `data_alloy
+_cell_length_a 3.6
+_cell_length_b 3.6
+_cell_length_c 3.6
+_cell_angle_alpha 90
+_cell_angle_beta 90
+_cell_angle_gamma 90
+loop_
+_symmetry_equiv_pos_as_xyz
+'x, y, z'
+'x+1/2, y+1/2, z'
+loop_
+_atom_site_label
+_atom_site_fract_x
+_atom_site_fract_y
+_atom_site_fract_z
+_atom_site_occupancy
+Cu1 0 0 0 0.5
+Au1 0 0 0 0.5
+`
+  cifs, err := ParseCif(txt)
+  if err != nil {
+    t.Fatalf("parse cif error: %v", err)
+  }
+  sites, err := cifs[0].Expand(0)
+  if err != nil {
+    t.Fatalf("expand cif error: %v", err)
+  }
+  if len(sites) != 4 || sites[0].Occupancy != 0.5 || sites[2].Symbol != "Au" {
+    t.Errorf("expanded sites: got %v", sites)
+  }
+  if _, err := cifs[0].Cell(0); err == nil {
+    t.Error("expect error for partial occupancy")
+  }
+}
+
+func TestParseCifError(t *testing.T) {
+  txt := `data_bad
+_cell_length_a 3.6
+_cell_length_b 3.6
+_cell_length_c 3.6a
+_cell_angle_alpha 90
+`
+  _, err := ParseCif(txt)
+  var ce *CifError
+  if !errors.As(err, &ce) || ce.Line != 4 {
+    t.Errorf("expect error at line 4, got %v", err)
+  }
+}
+
+func TestParseCifColumnLength(t *testing.T) {
+  txt := `data_bad
+_cell_length_a 3.6
+_cell_length_b 3.6
+_cell_length_c 3.6
+_cell_angle_alpha 90
+_cell_angle_beta 90
+_cell_angle_gamma 90
+loop_
+_atom_site_label
+Cu1
+Cu2
+loop_
+_atom_site_fract_x
+_atom_site_fract_y
+_atom_site_fract_z
+0 0 0
+0.5 0.5 0
+0.5 0 0.5
+`
+  _, err := ParseCif(txt)
+  var ce *CifError
+  if !errors.As(err, &ce) || ce.Line != 8 {
+    t.Errorf("expect error at loop of line 8, got %v", err)
+  }
+}
+
+func TestCifRoundTrip(t *testing.T) {
+  cell, _ := crystal.NewCell(
+    []float64{5.6, 0, 0, 0, 5.6, 0, 0, 0, 5.6},
+    []float64{
+      0, 0, 0, 0, 0.5, 0.5, 0.5, 0, 0.5, 0.5, 0.5, 0,
+      0.5, 0.5, 0.5, 0.5, 0, 0, 0, 0.5, 0, 0, 0, 0.5,
+    },
+    []int{11, 11, 11, 11, 17, 17, 17, 17},
+    false,
+  )
+  cell.System = "NaCl"
+  for _, sym := range []bool{false, true} {
+    txt, err := FormatCif(cell, &CifOptions{Symmetrize: sym})
+    if err != nil {
+      t.Fatalf("format cif error: %v", err)
+    }
+    cifs, err := ParseCif(txt)
+    if err != nil {
+      t.Fatalf("parse written cif error: %v\n%s", err, txt)
+    }
+    c, err := cifs[0].Cell(0)
+    if err != nil {
+      t.Fatalf("written cif to cell error: %v", err)
+    }
+    if c.Natom != 8 || c.System != "NaCl" {
+      t.Errorf("symmetrize %v: expect 8 atoms of NaCl, got %d of %q", sym, c.Natom, c.System)
+    }
+    if !sym && len(cifs[0].Sites) != 8 {
+      t.Errorf("expect 8 sites in P1 cif, got %d", len(cifs[0].Sites))
+    }
+  }
+}
+
+func TestCifStandardSetting(t *testing.T) {
+  id := []float64{1, 0, 0, 0, 1, 0, 0, 0, 1}
+  cases := []struct{
+    p, shift []float64
+    std bool
+  }{
+    {id, []float64{0, 0, 0}, true},
+    {id, []float64{1, 0, -1}, true},
+    // origin choice 1 of a cell given in origin choice 2
+    {id, []float64{0.25, 0.25, 0.25}, false},
+    // axes of cell permuted from the standard setting
+    {[]float64{0, 0, 1, 1, 0, 0, 0, 1, 0}, []float64{0, 0, 0}, false},
+  }
+  for _, c := range cases {
+    ds := &crystal.Dataset{
+      SpaceNumber: 14,
+      Transformation: mat.NewDense(3, 3, c.p),
+      OriginShift: mat.NewVecDense(3, c.shift),
+    }
+    if got := standardSetting(ds); got != c.std {
+      t.Errorf("P=%v p=%v: expect standard %v, got %v", c.p, c.shift, c.std, got)
+    }
+  }
+}
@@ -0,0 +1,66 @@
+package io
+
+import (
+  "fmt"
+  "strings"
+
+  "github.com/unkcpz/gocmp/crystal"
+)
+
+// ToCrystal convert c to crystal.Cell, species are mapped to atomic numbers
+// and positions are converted to fraction coordinate
+func (c *Cell) ToCrystal() (*crystal.Cell, error) {
+  elem := make([]int, len(c.Types))
+  for i, t := range c.Types {
+    n := crystal.SymToNum(speciesSymbol(t))
+    if n == 0 {
+      return nil, fmt.Errorf("unknown element symbol %q of atom %d", t, i)
+    }
+    elem[i] = n
+  }
+  pos, err := c.FractionalPositions()
+  if err != nil {
+    return nil, err
+  }
+  lattice := make([]float64, len(c.Lattice))
+  copy(lattice, c.Lattice)
+  cc, err := crystal.NewCell(lattice, pos, elem, false)
+  if err != nil {
+    return nil, err
+  }
+  cc.System = c.System
+  return cc, nil
+}
+
+// FromCrystal convert crystal.Cell to Cell with fraction coordinate
+func FromCrystal(c *crystal.Cell) (*Cell, error) {
+  types := make([]string, c.Natom)
+  for i:=0; i<c.Natom; i++ {
+    s := crystal.NumToSym(c.Elem[i])
+    if s == "" {
+      return nil, fmt.Errorf("unknown atomic number %d of atom %d", c.Elem[i], i)
+    }
+    types[i] = s
+  }
+  lattice := make([]float64, 9)
+  copy(lattice, c.LatticeSlice())
+  positions := make([]float64, 3*c.Natom)
+  copy(positions, c.PositionSlice())
+
+  cell := &Cell{
+    System: c.System,
+    Lattice: lattice,
+    Coordinate: Fractional,
+    Positions: positions,
+    Types: types,
+  }
+  return cell, nil
+}
+
+// speciesSymbol strip POTCAR suffix such as "Fe_pv" or "Fe/4f" to symbol
+func speciesSymbol(t string) string {
+  if i := strings.IndexAny(t, "_/"); i > 0 {
+    return t[:i]
+  }
+  return t
+}
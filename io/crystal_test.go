@@ -0,0 +1,65 @@
+package io
+
+import(
+  "math"
+  "testing"
+
+  "github.com/unkcpz/gocmp/crystal"
+)
+
+func TestToCrystal(t *testing.T) {
+  c := &Cell{
+    System: "BN",
+    Lattice: []float64{4.0, 0.0, 0.0, 0.0, 4.0, 0.0, 0.0, 0.0, 4.0},
+    Coordinate: Cartesian,
+    Positions: []float64{0.0, 0.0, 0.0, 2.0, 2.0, 2.0},
+    Types: []string{"B", "N_s"},
+  }
+  cc, err := c.ToCrystal()
+  if err != nil {
+    t.Fatalf("to crystal error: %v", err)
+  }
+  if cc.System != "BN" {
+    t.Errorf("system not preserved, got %q", cc.System)
+  }
+  if cc.Natom != 2 || cc.Elem[0] != 5 || cc.Elem[1] != 7 {
+    t.Errorf("elem expected [5 7], got %v", cc.Elem)
+  }
+  expect_pos := []float64{0.0, 0.0, 0.0, 0.5, 0.5, 0.5}
+  for i, v := range cc.PositionSlice() {
+    if math.Abs(v - expect_pos[i]) > 1e-8 {
+      t.Errorf("position expected %v, got %v", expect_pos, cc.PositionSlice())
+      break
+    }
+  }
+
+  c.Types[1] = "Xx"
+  if _, err := c.ToCrystal(); err == nil {
+    t.Error("expect error for unknown symbol")
+  }
+}
+
+func TestFromCrystal(t *testing.T) {
+  cc, _ := crystal.NewCell(
+    []float64{4, 0, 0, 0, 4, 0, 0, 0, 4},
+    []float64{0, 0, 0, 0.5, 0.5, 0.5},
+    []int{11, 17},
+    false,
+  )
+  cc.System = "NaCl"
+  c, err := FromCrystal(cc)
+  if err != nil {
+    t.Fatalf("from crystal error: %v", err)
+  }
+  if c.System != "NaCl" || c.Coordinate != Fractional {
+    t.Errorf("from crystal got system %q, coordinate %v", c.System, c.Coordinate)
+  }
+  if c.Types[0] != "Na" || c.Types[1] != "Cl" {
+    t.Errorf("types expected [Na Cl], got %v", c.Types)
+  }
+
+  cc.Elem[1] = 0
+  if _, err := FromCrystal(cc); err == nil {
+    t.Error("expect error for unknown atomic number")
+  }
+}
@@ -0,0 +1,903 @@
+package io
+
+import (
+  "fmt"
+  "io"
+  "math"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+const (
+  // Bohr is Bohr radius in Å
+  Bohr = 0.529177210903
+  // Rydberg is Rydberg energy in eV
+  Rydberg = 13.605693122994
+)
+
+// PwError is returned when pw.x input or output is malformed
+type PwError struct {
+  // Line is 1-based line number of the offending line
+  Line int
+  // Msg describe what is wrong
+  Msg string
+}
+
+func (e *PwError) Error() string {
+  return fmt.Sprintf("pw.x line %d: %s", e.Line, e.Msg)
+}
+
+// PwSpecies is a line of ATOMIC_SPECIES card
+type PwSpecies struct {
+  Name string
+  Mass float64
+  Pseudo string
+}
+
+// PwCard is a card of pw.x input kept verbatim
+type PwCard struct {
+  // Option is text after card name, e.g. "automatic" of K_POINTS
+  Option string
+  Lines []string
+}
+
+// PwInput is pw.x input
+type PwInput struct {
+  // Namelists holds raw values by lower case namelist and key, e.g.
+  // Namelists["system"]["ecutwfc"], string values keep their quotes
+  Namelists map[string]map[string]string
+  // Species of ATOMIC_SPECIES card
+  Species []PwSpecies
+  // Cell holds lattice and positions in Å, or fraction for crystal
+  // positions. Fixed coordinates (if_pos = 0) are kept as
+  // SelectiveDynamics
+  Cell *Cell
+  // Cards holds other cards verbatim such as K_POINTS, keyed by name
+  Cards map[string]PwCard
+}
+
+// PwOutput is result of pw.x run
+type PwOutput struct {
+  // Cell is final structure, in Å or fraction for crystal positions
+  Cell *Cell
+  // Energy is last total energy in eV
+  Energy float64
+  // Forces holds last forces in eV/Å, 3 per atom, nil if not printed
+  Forces []float64
+  // Stress is last stress tensor in kbar, row-major 3x3, nil if not
+  // printed
+  Stress []float64
+}
+
+// pwCards are card names of pw.x input
+var pwCards = []string{
+  "ATOMIC_SPECIES", "ATOMIC_POSITIONS", "K_POINTS", "ADDITIONAL_K_POINTS",
+  "CELL_PARAMETERS", "CONSTRAINTS", "OCCUPATIONS", "ATOMIC_VELOCITIES",
+  "ATOMIC_FORCES", "SOLVENTS", "HUBBARD",
+}
+
+// pwNamelists are namelists of pw.x input in the order they are written
+var pwNamelists = []string{"control", "system", "electrons", "ions", "cell", "fcp", "rism"}
+
+// ParsePwInput parse pw.x input. Lattice is taken from CELL_PARAMETERS or
+// generated from ibrav with celldm or A, B, C, cosAB, cosAC, cosBC
+func ParsePwInput(txt string) (*PwInput, error) {
+  lines := strings.Split(strings.Replace(txt, "\r\n", "\n", -1), "\n")
+  in := &PwInput{
+    Namelists: make(map[string]map[string]string),
+    Cards: make(map[string]PwCard),
+  }
+  cardLine := make(map[string]int)
+  for n:=0; n<len(lines); n++ {
+    line := strings.TrimSpace(stripFortranComment(lines[n]))
+    switch {
+    case line == "":
+    case strings.HasPrefix(line, "&"):
+      fs := strings.Fields(line[1:])
+      if len(fs) == 0 || strings.HasPrefix(fs[0], "/") {
+        return nil, &PwError{Line: n+1, Msg: "expect namelist name after &"}
+      }
+      name := strings.ToLower(fs[0])
+      nl := make(map[string]string)
+      body := make([]string, 0)
+      rest := strings.TrimSpace(strings.TrimSpace(line[1:])[len(fs[0]):])
+      start := n
+      for {
+        if strings.HasSuffix(rest, "/") {
+          body = append(body, strings.TrimSuffix(rest, "/"))
+          break
+        }
+        body = append(body, rest)
+        n++
+        if n >= len(lines) {
+          return nil, &PwError{Line: start+1, Msg: fmt.Sprintf("namelist &%s not terminated by /", name)}
+        }
+        rest = strings.TrimSpace(stripFortranComment(lines[n]))
+      }
+      for _, item := range splitFortranItems(strings.Join(body, "\n")) {
+        kv := strings.SplitN(item, "=", 2)
+        if len(kv) != 2 {
+          return nil, &PwError{Line: start+1, Msg: fmt.Sprintf("namelist &%s: expect key = value, got %q", name, item)}
+        }
+        key := strings.ToLower(strings.Join(strings.Fields(kv[0]), ""))
+        nl[key] = strings.TrimSpace(kv[1])
+      }
+      in.Namelists[name] = nl
+    default:
+      fs := strings.Fields(line)
+      name := strings.ToUpper(fs[0])
+      if !isPwCard(name) {
+        return nil, &PwError{Line: n+1, Msg: fmt.Sprintf("unknown card %q", fs[0])}
+      }
+      card := PwCard{Option: cardOption(line[len(fs[0]):])}
+      cardLine[name] = n+1
+      for n+1 < len(lines) {
+        next := strings.TrimSpace(stripFortranComment(lines[n+1]))
+        nf := strings.Fields(next)
+        if len(nf) > 0 && (isPwCard(strings.ToUpper(nf[0])) || strings.HasPrefix(next, "&")) {
+          break
+        }
+        n++
+        if next != "" {
+          card.Lines = append(card.Lines, next)
+        }
+      }
+      in.Cards[name] = card
+    }
+  }
+
+  system := in.Namelists["system"]
+  if system == nil {
+    return nil, fmt.Errorf("pw.x input: missing &system namelist")
+  }
+  nat, err := pwInt(system, "nat")
+  if err != nil {
+    return nil, err
+  }
+  ibrav, err := pwInt(system, "ibrav")
+  if err != nil {
+    return nil, err
+  }
+  alat, celldm, err := pwCelldm(system, ibrav)
+  if err != nil {
+    return nil, err
+  }
+
+  var lattice []float64
+  if card, ok := in.Cards["CELL_PARAMETERS"]; ok {
+    if ibrav != 0 {
+      return nil, &PwError{Line: cardLine["CELL_PARAMETERS"], Msg: "CELL_PARAMETERS requires ibrav = 0"}
+    }
+    unit := card.Option
+    if unit == "" {
+      // legacy input: alat if celldm(1) or A given, bohr otherwise
+      unit = "bohr"
+      if alat > 0 {
+        unit = "alat"
+      }
+    }
+    lattice, err = pwCellParameters(card.Lines, unit, alat)
+    if err != nil {
+      return nil, &PwError{Line: cardLine["CELL_PARAMETERS"], Msg: err.Error()}
+    }
+    delete(in.Cards, "CELL_PARAMETERS")
+  } else {
+    if ibrav == 0 {
+      return nil, fmt.Errorf("pw.x input: ibrav = 0 requires CELL_PARAMETERS")
+    }
+    lattice, err = IbravLattice(ibrav, celldm)
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  if card, ok := in.Cards["ATOMIC_SPECIES"]; ok {
+    for _, l := range card.Lines {
+      fs := strings.Fields(l)
+      if len(fs) < 3 {
+        return nil, &PwError{Line: cardLine["ATOMIC_SPECIES"], Msg: fmt.Sprintf("expect name mass pseudo, got %q", l)}
+      }
+      m, err := fortranFloat(fs[1])
+      if err != nil {
+        return nil, &PwError{Line: cardLine["ATOMIC_SPECIES"], Msg: fmt.Sprintf("parse mass, %v", err)}
+      }
+      in.Species = append(in.Species, PwSpecies{Name: fs[0], Mass: m, Pseudo: fs[2]})
+    }
+    delete(in.Cards, "ATOMIC_SPECIES")
+  }
+
+  card, ok := in.Cards["ATOMIC_POSITIONS"]
+  if !ok {
+    return nil, fmt.Errorf("pw.x input: missing ATOMIC_POSITIONS")
+  }
+  unit := card.Option
+  if unit == "" {
+    unit = "alat"
+  }
+  if len(card.Lines) != nat {
+    return nil, &PwError{Line: cardLine["ATOMIC_POSITIONS"], Msg: fmt.Sprintf("expect %d positions, got %d", nat, len(card.Lines))}
+  }
+  cell, err := pwPositions(card.Lines, unit, alat)
+  if err != nil {
+    return nil, &PwError{Line: cardLine["ATOMIC_POSITIONS"], Msg: err.Error()}
+  }
+  delete(in.Cards, "ATOMIC_POSITIONS")
+  cell.Lattice = lattice
+  in.Cell = cell
+  return in, nil
+}
+
+// FormatPwInput render pw.x input. The lattice is written as
+// CELL_PARAMETERS in Å with ibrav = 0, and nat and ntyp are set from the
+// cell, overriding the namelists
+func FormatPwInput(in *PwInput) (string, error) {
+  c := in.Cell
+  natoms := len(c.Types)
+  if len(c.Lattice) != 9 {
+    return "", fmt.Errorf("expect 9 value as lattice, got %d", len(c.Lattice))
+  }
+  if len(c.Positions) != 3*natoms {
+    return "", fmt.Errorf("atom number not compatible, len(types)=%d, len(positions)/3=%d/3", natoms, len(c.Positions))
+  }
+  if sd := c.SelectiveDynamics; sd != nil && len(sd) != 3*natoms {
+    return "", fmt.Errorf("expect 3 selective dynamics flags per atom, got %d for %d atoms", len(sd), natoms)
+  }
+  known := make(map[string]bool)
+  for _, s := range in.Species {
+    known[s.Name] = true
+  }
+  for _, t := range c.Types {
+    if !known[t] {
+      return "", fmt.Errorf("species %s missing in ATOMIC_SPECIES", t)
+    }
+  }
+
+  nls := make(map[string]map[string]string)
+  for name, nl := range in.Namelists {
+    nls[name] = make(map[string]string)
+    for k, v := range nl {
+      nls[name][k] = v
+    }
+  }
+  if nls["control"] == nil {
+    nls["control"] = make(map[string]string)
+  }
+  if nls["system"] == nil {
+    nls["system"] = make(map[string]string)
+  }
+  if nls["electrons"] == nil {
+    nls["electrons"] = make(map[string]string)
+  }
+  system := nls["system"]
+  for k := range system {
+    if strings.HasPrefix(k, "celldm(") || k == "a" || k == "b" || k == "c" ||
+      k == "cosab" || k == "cosac" || k == "cosbc" {
+      delete(system, k)
+    }
+  }
+  system["ibrav"] = "0"
+  system["nat"] = strconv.Itoa(natoms)
+  system["ntyp"] = strconv.Itoa(len(in.Species))
+
+  names := make([]string, 0, len(nls))
+  for _, name := range pwNamelists {
+    if _, ok := nls[name]; ok {
+      names = append(names, name)
+    }
+  }
+  others := make([]string, 0)
+  for name := range nls {
+    if !containsString(pwNamelists, name) {
+      others = append(others, name)
+    }
+  }
+  sort.Strings(others)
+  names = append(names, others...)
+
+  var b strings.Builder
+  for _, name := range names {
+    fmt.Fprintf(&b, "&%s\n", strings.ToUpper(name))
+    keys := make([]string, 0, len(nls[name]))
+    for k := range nls[name] {
+      keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    for _, k := range keys {
+      fmt.Fprintf(&b, "  %s = %s\n", k, nls[name][k])
+    }
+    fmt.Fprintln(&b, "/")
+  }
+
+  fmt.Fprintln(&b, "ATOMIC_SPECIES")
+  for _, s := range in.Species {
+    fmt.Fprintf(&b, "  %s %.6f %s\n", s.Name, s.Mass, s.Pseudo)
+  }
+  fmt.Fprintln(&b, "CELL_PARAMETERS angstrom")
+  for i:=0; i<3; i++ {
+    fmt.Fprintf(&b, "  %15.10f %15.10f %15.10f\n", c.Lattice[i*3], c.Lattice[i*3+1], c.Lattice[i*3+2])
+  }
+  if c.Coordinate == Fractional {
+    fmt.Fprintln(&b, "ATOMIC_POSITIONS crystal")
+  } else {
+    fmt.Fprintln(&b, "ATOMIC_POSITIONS angstrom")
+  }
+  for i:=0; i<natoms; i++ {
+    fmt.Fprintf(&b, "  %-3s %15.10f %15.10f %15.10f", c.Types[i], c.Positions[i*3], c.Positions[i*3+1], c.Positions[i*3+2])
+    if sd := c.SelectiveDynamics; sd != nil {
+      fmt.Fprintf(&b, " %d %d %d", boolInt(sd[i*3]), boolInt(sd[i*3+1]), boolInt(sd[i*3+2]))
+    }
+    fmt.Fprintln(&b)
+  }
+  cards := make([]string, 0, len(in.Cards))
+  for name := range in.Cards {
+    cards = append(cards, name)
+  }
+  sort.Strings(cards)
+  for _, name := range cards {
+    card := in.Cards[name]
+    fmt.Fprintln(&b, strings.TrimSpace(name+" "+card.Option))
+    for _, l := range card.Lines {
+      fmt.Fprintf(&b, "  %s\n", l)
+    }
+  }
+  return b.String(), nil
+}
+
+// WritePwInput write pw.x input to w, see FormatPwInput
+func WritePwInput(w io.Writer, in *PwInput) error {
+  txt, err := FormatPwInput(in)
+  if err != nil {
+    return err
+  }
+  _, err = io.WriteString(w, txt)
+  return err
+}
+
+// ParsePwOutput parse final structure, total energy, forces and stress of
+// pw.x output
+func ParsePwOutput(txt string) (*PwOutput, error) {
+  lines := strings.Split(strings.Replace(txt, "\r\n", "\n", -1), "\n")
+  var alat float64
+  nat := -1
+  var axes []float64
+  var header *Cell
+  var cellCard, posCard *PwCard
+  var cellLine, posLine int
+  out := &PwOutput{}
+  energy := false
+
+  // block return n lines after line i, error if output ends early
+  block := func(i, n int, what string) ([]string, error) {
+    if n < 0 || n >= len(lines)-i {
+      return nil, &PwError{Line: i+1, Msg: fmt.Sprintf("unexpected end of output in %s", what)}
+    }
+    return lines[i+1 : i+1+n], nil
+  }
+  for i:=0; i<len(lines); i++ {
+    line := lines[i]
+    t := strings.TrimSpace(line)
+    switch {
+    case strings.Contains(line, "lattice parameter (alat)"):
+      v, err := valueAfter(line, "=")
+      if err != nil {
+        return nil, &PwError{Line: i+1, Msg: fmt.Sprintf("parse alat, %v", err)}
+      }
+      alat = v * Bohr
+    case strings.Contains(line, "number of atoms/cell"):
+      v, err := valueAfter(line, "=")
+      if err != nil {
+        return nil, &PwError{Line: i+1, Msg: fmt.Sprintf("parse number of atoms, %v", err)}
+      }
+      nat = int(v)
+    case strings.HasPrefix(t, "crystal axes:"):
+      ls, err := block(i, 3, "crystal axes")
+      if err != nil {
+        return nil, err
+      }
+      axes = make([]float64, 9)
+      for j, l := range ls {
+        vs, err := floatsInParens(l, 3)
+        if err != nil {
+          return nil, &PwError{Line: i+2+j, Msg: fmt.Sprintf("parse crystal axes, %v", err)}
+        }
+        for k := range vs {
+          axes[j*3+k] = vs[k] * alat
+        }
+      }
+      i += 3
+    case strings.Contains(line, "site n.") && strings.Contains(line, "(alat units)"):
+      if nat < 0 {
+        return nil, &PwError{Line: i+1, Msg: "positions before number of atoms"}
+      }
+      ls, err := block(i, nat, "positions")
+      if err != nil {
+        return nil, err
+      }
+      header = &Cell{Coordinate: Cartesian}
+      for j, l := range ls {
+        fs := strings.Fields(l)
+        vs, err := floatsInParens(l, 3)
+        if err != nil || len(fs) < 2 {
+          return nil, &PwError{Line: i+2+j, Msg: fmt.Sprintf("parse position, %v", err)}
+        }
+        header.Types = append(header.Types, fs[1])
+        for _, v := range vs {
+          header.Positions = append(header.Positions, v*alat)
+        }
+      }
+      i += nat
+    case strings.HasPrefix(t, "!") && strings.Contains(line, "total energy"):
+      v, err := valueAfter(line, "=")
+      if err != nil {
+        return nil, &PwError{Line: i+1, Msg: fmt.Sprintf("parse total energy, %v", err)}
+      }
+      out.Energy = v * Rydberg
+      energy = true
+    case strings.Contains(line, "Forces acting on atoms"):
+      if nat < 0 {
+        return nil, &PwError{Line: i+1, Msg: "forces before number of atoms"}
+      }
+      // nat comes from the output, do not trust it for allocation
+      size := nat
+      if size > len(lines) {
+        size = len(lines)
+      }
+      forces := make([]float64, 0, 3*size)
+      for j:=i+1; j<len(lines) && len(forces) < 3*nat; j++ {
+        if !strings.Contains(lines[j], "force =") {
+          continue
+        }
+        vs, err := floatsAfter(lines[j], "=", 3)
+        if err != nil {
+          return nil, &PwError{Line: j+1, Msg: fmt.Sprintf("parse force, %v", err)}
+        }
+        for _, v := range vs {
+          forces = append(forces, v*Rydberg/Bohr)
+        }
+      }
+      if len(forces) != 3*nat {
+        return nil, &PwError{Line: i+1, Msg: fmt.Sprintf("expect forces of %d atoms", nat)}
+      }
+      out.Forces = forces
+    case strings.Contains(line, "total   stress"):
+      ls, err := block(i, 3, "stress")
+      if err != nil {
+        return nil, err
+      }
+      stress := make([]float64, 9)
+      for j, l := range ls {
+        fs := strings.Fields(l)
+        if len(fs) != 6 {
+          return nil, &PwError{Line: i+2+j, Msg: "expect 6 values of stress"}
+        }
+        for k:=0; k<3; k++ {
+          if stress[j*3+k], err = fortranFloat(fs[3+k]); err != nil {
+            return nil, &PwError{Line: i+2+j, Msg: fmt.Sprintf("parse stress, %v", err)}
+          }
+        }
+      }
+      out.Stress = stress
+      i += 3
+    case strings.HasPrefix(t, "CELL_PARAMETERS"):
+      ls, err := block(i, 3, "CELL_PARAMETERS")
+      if err != nil {
+        return nil, err
+      }
+      cellCard = &PwCard{Option: cardOption(t[len("CELL_PARAMETERS"):]), Lines: ls}
+      cellLine = i+1
+      i += 3
+    case strings.HasPrefix(t, "ATOMIC_POSITIONS"):
+      if nat < 0 {
+        return nil, &PwError{Line: i+1, Msg: "positions before number of atoms"}
+      }
+      ls, err := block(i, nat, "ATOMIC_POSITIONS")
+      if err != nil {
+        return nil, err
+      }
+      posCard = &PwCard{Option: cardOption(t[len("ATOMIC_POSITIONS"):]), Lines: ls}
+      posLine = i+1
+      i += nat
+    }
+  }
+
+  if !energy {
+    return nil, fmt.Errorf("pw.x output: no total energy found")
+  }
+  lattice := axes
+  if cellCard != nil {
+    unit, a := cellCard.Option, alat
+    // vc-relax prints "CELL_PARAMETERS (alat= 10.20000000)"
+    if strings.HasPrefix(unit, "alat=") {
+      v, err := fortranFloat(strings.TrimPrefix(unit, "alat="))
+      if err != nil {
+        return nil, &PwError{Line: cellLine, Msg: fmt.Sprintf("parse alat, %v", err)}
+      }
+      unit, a = "alat", v*Bohr
+    }
+    var err error
+    if lattice, err = pwCellParameters(cellCard.Lines, unit, a); err != nil {
+      return nil, &PwError{Line: cellLine, Msg: err.Error()}
+    }
+  }
+  if lattice == nil {
+    return nil, fmt.Errorf("pw.x output: no lattice found")
+  }
+  cell := header
+  if posCard != nil {
+    var err error
+    if cell, err = pwPositions(posCard.Lines, posCard.Option, alat); err != nil {
+      return nil, &PwError{Line: posLine, Msg: err.Error()}
+    }
+  }
+  if cell == nil {
+    return nil, fmt.Errorf("pw.x output: no atomic positions found")
+  }
+  cell.Lattice = lattice
+  out.Cell = cell
+  return out, nil
+}
+
+// IbravLattice return lattice row vectors in Å of pw.x Bravais lattice
+// index ibrav, celldm as in pw.x with celldm[0] in bohr
+func IbravLattice(ibrav int, celldm [6]float64) ([]float64, error) {
+  a := celldm[0] * Bohr
+  if a <= 0 {
+    return nil, fmt.Errorf("ibrav = %d requires celldm(1) or A", ibrav)
+  }
+  b, c := a*celldm[1], a*celldm[2]
+  var v []float64
+  switch ibrav {
+  case 1:
+    v = []float64{a, 0, 0, 0, a, 0, 0, 0, a}
+  case 2:
+    v = []float64{-a/2, 0, a/2, 0, a/2, a/2, -a/2, a/2, 0}
+  case 3:
+    v = []float64{a/2, a/2, a/2, -a/2, a/2, a/2, -a/2, -a/2, a/2}
+  case -3:
+    v = []float64{-a/2, a/2, a/2, a/2, -a/2, a/2, a/2, a/2, -a/2}
+  case 4:
+    v = []float64{a, 0, 0, -a/2, a*math.Sqrt(3)/2, 0, 0, 0, c}
+  case 5, -5:
+    cg := celldm[3]
+    tx := math.Sqrt((1-cg)/2)
+    ty := math.Sqrt((1-cg)/6)
+    tz := math.Sqrt((1+2*cg)/3)
+    if ibrav == 5 {
+      v = []float64{a*tx, -a*ty, a*tz, 0, 2*a*ty, a*tz, -a*tx, -a*ty, a*tz}
+    } else {
+      ap := a / math.Sqrt(3)
+      u := tz - 2*math.Sqrt(2)*ty
+      w := tz + math.Sqrt(2)*ty
+      v = []float64{ap*u, ap*w, ap*w, ap*w, ap*u, ap*w, ap*w, ap*w, ap*u}
+    }
+  case 6:
+    v = []float64{a, 0, 0, 0, a, 0, 0, 0, c}
+  case 7:
+    v = []float64{a/2, -a/2, c/2, a/2, a/2, c/2, -a/2, -a/2, c/2}
+  case 8:
+    v = []float64{a, 0, 0, 0, b, 0, 0, 0, c}
+  case 9:
+    v = []float64{a/2, b/2, 0, -a/2, b/2, 0, 0, 0, c}
+  case -9:
+    v = []float64{a/2, -b/2, 0, a/2, b/2, 0, 0, 0, c}
+  case 91:
+    v = []float64{a, 0, 0, 0, b/2, -c/2, 0, b/2, c/2}
+  case 10:
+    v = []float64{a/2, 0, c/2, a/2, b/2, 0, 0, b/2, c/2}
+  case 11:
+    v = []float64{a/2, b/2, c/2, -a/2, b/2, c/2, -a/2, -b/2, c/2}
+  case 12:
+    cg := celldm[3]
+    sg := math.Sqrt(1 - cg*cg)
+    v = []float64{a, 0, 0, b*cg, b*sg, 0, 0, 0, c}
+  case -12:
+    cb := celldm[4]
+    sb := math.Sqrt(1 - cb*cb)
+    v = []float64{a, 0, 0, 0, b, 0, c*cb, 0, c*sb}
+  case 13:
+    cg := celldm[3]
+    sg := math.Sqrt(1 - cg*cg)
+    v = []float64{a/2, 0, -c/2, b*cg, b*sg, 0, a/2, 0, c/2}
+  case 14:
+    ca, cb, cg := celldm[3], celldm[4], celldm[5]
+    sg := math.Sqrt(1 - cg*cg)
+    vol := 1 + 2*ca*cb*cg - ca*ca - cb*cb - cg*cg
+    if vol <= 0 {
+      return nil, fmt.Errorf("ibrav = 14: invalid angles")
+    }
+    v = []float64{a, 0, 0, b*cg, b*sg, 0, c*cb, c*(ca-cb*cg)/sg, c*math.Sqrt(vol)/sg}
+  default:
+    return nil, fmt.Errorf("ibrav = %d not supported", ibrav)
+  }
+  if math.Abs(det3(v)) < 1e-10 || hasNaN(v) {
+    return nil, fmt.Errorf("ibrav = %d: celldm %v give degenerate lattice", ibrav, celldm)
+  }
+  return v, nil
+}
+
+// pwCelldm return alat in Å, 0 if not given, and celldm from either
+// celldm(i) or A, B, C, cosAB, cosAC, cosBC of &system
+func pwCelldm(system map[string]string, ibrav int) (float64, [6]float64, error) {
+  var celldm [6]float64
+  hasCelldm := false
+  for i:=0; i<6; i++ {
+    if v, ok := system[fmt.Sprintf("celldm(%d)", i+1)]; ok {
+      f, err := fortranFloat(v)
+      if err != nil {
+        return 0, celldm, fmt.Errorf("pw.x input: parse celldm(%d), %v", i+1, err)
+      }
+      celldm[i] = f
+      hasCelldm = true
+    }
+  }
+  abc := make(map[string]float64)
+  for _, k := range []string{"a", "b", "c", "cosab", "cosac", "cosbc"} {
+    if v, ok := system[k]; ok {
+      f, err := fortranFloat(v)
+      if err != nil {
+        return 0, celldm, fmt.Errorf("pw.x input: parse %s, %v", k, err)
+      }
+      abc[k] = f
+    }
+  }
+  if len(abc) > 0 {
+    if hasCelldm {
+      return 0, celldm, fmt.Errorf("pw.x input: celldm and A, B, C are exclusive")
+    }
+    a := abc["a"]
+    if a <= 0 {
+      return 0, celldm, fmt.Errorf("pw.x input: A must be positive")
+    }
+    celldm[0] = a / Bohr
+    celldm[1] = abc["b"] / a
+    celldm[2] = abc["c"] / a
+    // same mapping as abc2celldm of pw.x
+    switch ibrav {
+    case 14:
+      celldm[3], celldm[4], celldm[5] = abc["cosbc"], abc["cosac"], abc["cosab"]
+    case -12, -13:
+      celldm[4] = abc["cosac"]
+    default:
+      celldm[3] = abc["cosab"]
+    }
+  }
+  return celldm[0] * Bohr, celldm, nil
+}
+
+// pwCellParameters parse 3 lattice lines in unit alat, bohr or angstrom
+func pwCellParameters(lines []string, unit string, alat float64) ([]float64, error) {
+  if len(lines) != 3 {
+    return nil, fmt.Errorf("expect 3 lattice vectors, got %d", len(lines))
+  }
+  f, err := pwUnit(unit, alat)
+  if err != nil {
+    return nil, err
+  }
+  lattice := make([]float64, 9)
+  for i, l := range lines {
+    fs := strings.Fields(l)
+    if len(fs) < 3 {
+      return nil, fmt.Errorf("expect 3 values as lattice vector, got %q", l)
+    }
+    for j:=0; j<3; j++ {
+      v, err := fortranFloat(fs[j])
+      if err != nil {
+        return nil, fmt.Errorf("parse lattice vector, %v", err)
+      }
+      lattice[i*3+j] = v * f
+    }
+  }
+  return lattice, nil
+}
+
+// pwPositions parse ATOMIC_POSITIONS lines, optional if_pos flags become
+// SelectiveDynamics
+func pwPositions(lines []string, unit string, alat float64) (*Cell, error) {
+  cell := &Cell{Coordinate: Cartesian}
+  f := 1.0
+  if unit == "crystal" {
+    cell.Coordinate = Fractional
+  } else {
+    var err error
+    if f, err = pwUnit(unit, alat); err != nil {
+      return nil, err
+    }
+  }
+  for _, l := range lines {
+    fs := strings.Fields(l)
+    if len(fs) != 4 && len(fs) != 7 {
+      return nil, fmt.Errorf("expect species, 3 positions and optional 3 if_pos, got %q", l)
+    }
+    cell.Types = append(cell.Types, fs[0])
+    for j:=1; j<4; j++ {
+      v, err := fortranFloat(fs[j])
+      if err != nil {
+        return nil, fmt.Errorf("parse position, %v", err)
+      }
+      cell.Positions = append(cell.Positions, v*f)
+    }
+    if len(fs) == 7 && cell.SelectiveDynamics == nil {
+      cell.SelectiveDynamics = make([]bool, 3*(len(cell.Types)-1), 3*len(lines))
+      for i := range cell.SelectiveDynamics {
+        cell.SelectiveDynamics[i] = true
+      }
+    }
+    if cell.SelectiveDynamics != nil {
+      flags := []string{"1", "1", "1"}
+      if len(fs) == 7 {
+        flags = fs[4:]
+      }
+      for _, s := range flags {
+        if s != "0" && s != "1" {
+          return nil, fmt.Errorf("if_pos must be 0 or 1, got %q", s)
+        }
+        cell.SelectiveDynamics = append(cell.SelectiveDynamics, s == "1")
+      }
+    }
+  }
+  return cell, nil
+}
+
+// pwUnit return factor converting unit to Å
+func pwUnit(unit string, alat float64) (float64, error) {
+  switch unit {
+  case "angstrom":
+    return 1, nil
+  case "bohr":
+    return Bohr, nil
+  case "alat":
+    if alat <= 0 {
+      return 0, fmt.Errorf("unit alat requires celldm(1) or A")
+    }
+    return alat, nil
+  }
+  return 0, fmt.Errorf("unknown unit %q", unit)
+}
+
+// cardOption return lower case option of card, braces and parentheses
+// removed, e.g. "{crystal}" to "crystal"
+func cardOption(s string) string {
+  s = strings.Trim(strings.TrimSpace(s), "{}()")
+  return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
+
+func isPwCard(name string) bool {
+  return containsString(pwCards, name)
+}
+
+func containsString(ss []string, s string) bool {
+  for _, v := range ss {
+    if v == s {
+      return true
+    }
+  }
+  return false
+}
+
+// stripFortranComment remove ! or # comment outside quotes
+func stripFortranComment(s string) string {
+  var quote byte
+  for i:=0; i<len(s); i++ {
+    switch c := s[i]; {
+    case quote != 0:
+      if c == quote {
+        quote = 0
+      }
+    case c == '\'' || c == '"':
+      quote = c
+    case c == '!' || c == '#':
+      return s[:i]
+    }
+  }
+  return s
+}
+
+// splitFortranItems split namelist body at commas and newlines outside
+// quotes
+func splitFortranItems(s string) []string {
+  items := make([]string, 0)
+  var quote byte
+  start := 0
+  for i:=0; i<=len(s); i++ {
+    if i < len(s) {
+      c := s[i]
+      if quote != 0 {
+        if c == quote {
+          quote = 0
+        }
+        continue
+      }
+      if c == '\'' || c == '"' {
+        quote = c
+        continue
+      }
+      if c != ',' && c != '\n' {
+        continue
+      }
+    }
+    if item := strings.TrimSpace(s[start:i]); item != "" {
+      items = append(items, item)
+    }
+    start = i+1
+  }
+  return items
+}
+
+// fortranFloat parse number with Fortran d exponent, e.g. 1.0d-8
+func fortranFloat(s string) (float64, error) {
+  s = strings.Replace(strings.Replace(s, "d", "e", 1), "D", "e", 1)
+  return strconv.ParseFloat(s, 64)
+}
+
+func pwInt(nl map[string]string, key string) (int, error) {
+  v, ok := nl[key]
+  if !ok {
+    return 0, fmt.Errorf("pw.x input: missing %s", key)
+  }
+  n, err := strconv.Atoi(v)
+  if err != nil {
+    return 0, fmt.Errorf("pw.x input: parse %s, %v", key, err)
+  }
+  return n, nil
+}
+
+// valueAfter parse first field after sep of line
+func valueAfter(line, sep string) (float64, error) {
+  vs, err := floatsAfter(line, sep, 1)
+  if err != nil {
+    return 0, err
+  }
+  return vs[0], nil
+}
+
+// floatsAfter parse first n fields after sep of line
+func floatsAfter(line, sep string, n int) ([]float64, error) {
+  i := strings.Index(line, sep)
+  if i < 0 {
+    return nil, fmt.Errorf("missing %q", sep)
+  }
+  fs := strings.Fields(line[i+len(sep):])
+  if len(fs) < n {
+    return nil, fmt.Errorf("expect %d values, got %d", n, len(fs))
+  }
+  vs := make([]float64, n)
+  for j:=0; j<n; j++ {
+    v, err := fortranFloat(fs[j])
+    if err != nil {
+      return nil, err
+    }
+    vs[j] = v
+  }
+  return vs, nil
+}
+
+// floatsInParens parse n values in the parentheses after "= (" of line,
+// e.g. "a(1) = ( 1.0 0.0 0.0 )"
+func floatsInParens(line string, n int) ([]float64, error) {
+  i := strings.Index(line, "= (")
+  if i < 0 {
+    return nil, fmt.Errorf("missing \"= (\"")
+  }
+  s := line[i+3:]
+  if j := strings.Index(s, ")"); j >= 0 {
+    s = s[:j]
+  }
+  return floatsAfter(" "+s, " ", n)
+}
+
+func boolInt(b bool) int {
+  if b {
+    return 1
+  }
+  return 0
+}
+
+func hasNaN(v []float64) bool {
+  for _, x := range v {
+    if math.IsNaN(x) {
+      return true
+    }
+  }
+  return false
+}
@@ -0,0 +1,271 @@
+package io
+
+import(
+  "errors"
+  "math"
+  "strings"
+  "testing"
+)
+
+const pwSi = `&CONTROL
+  calculation = 'scf', prefix = 'si'
+  pseudo_dir = './pseudo/' ! comment
+/
+&SYSTEM
+  ibrav = 2, celldm(1) = 10.20d0, nat = 2, ntyp = 1
+  ecutwfc = 30.0
+/
+&ELECTRONS
+/
+ATOMIC_SPECIES
+  Si 28.086 Si.pz-vbc.UPF
+ATOMIC_POSITIONS alat
+  Si 0.00 0.00 0.00
+  Si 0.25 0.25 0.25
+K_POINTS automatic
+  4 4 4 1 1 1
+`
+
+func TestParsePwInputIbrav(t *testing.T) {
+  in, err := ParsePwInput(pwSi)
+  if err != nil {
+    t.Fatalf("parse pw input error: %v", err)
+  }
+  a := 10.2 * Bohr
+  expect_latt := []float64{-a/2, 0, a/2, 0, a/2, a/2, -a/2, a/2, 0}
+  for i := range expect_latt {
+    if math.Abs(in.Cell.Lattice[i] - expect_latt[i]) > 1e-8 {
+      t.Errorf("lattice expected %v, got %v", expect_latt, in.Cell.Lattice)
+      break
+    }
+  }
+  if in.Cell.Coordinate != Cartesian || math.Abs(in.Cell.Positions[3] - a/4) > 1e-8 {
+    t.Errorf("positions in alat read fail: %v", in.Cell.Positions)
+  }
+  if in.Namelists["control"]["pseudo_dir"] != "'./pseudo/'" || in.Namelists["system"]["ecutwfc"] != "30.0" {
+    t.Errorf("namelists read fail: %v", in.Namelists)
+  }
+  if len(in.Species) != 1 || in.Species[0].Pseudo != "Si.pz-vbc.UPF" {
+    t.Errorf("species read fail: %v", in.Species)
+  }
+  if k := in.Cards["K_POINTS"]; k.Option != "automatic" || k.Lines[0] != "4 4 4 1 1 1" {
+    t.Errorf("K_POINTS read fail: %v", k)
+  }
+}
+
+func TestPwInputRoundTrip(t *testing.T) {
+  txt := `&control
+  calculation = 'relax'
+/
+&system
+  ibrav = 0, nat = 2, ntyp = 2, ecutwfc = 40
+/
+&electrons
+/
+&ions
+/
+ATOMIC_SPECIES
+  Na 22.99 Na.upf
+  Cl 35.45 Cl.upf
+CELL_PARAMETERS {angstrom}
+  5.6 0.0 0.0
+  0.0 5.6 0.0
+  0.0 0.0 5.6
+ATOMIC_POSITIONS {crystal}
+  Na 0.0 0.0 0.0 0 0 0
+  Cl 0.5 0.5 0.5
+K_POINTS gamma
+`
+  in, err := ParsePwInput(txt)
+  if err != nil {
+    t.Fatalf("parse pw input error: %v", err)
+  }
+  expect_flags := []bool{false, false, false, true, true, true}
+  for i := range expect_flags {
+    if in.Cell.SelectiveDynamics[i] != expect_flags[i] {
+      t.Errorf("if_pos expected %v, got %v", expect_flags, in.Cell.SelectiveDynamics)
+      break
+    }
+  }
+  out, err := FormatPwInput(in)
+  if err != nil {
+    t.Fatalf("format pw input error: %v", err)
+  }
+  again, err := ParsePwInput(out)
+  if err != nil {
+    t.Fatalf("parse written pw input error: %v\n%s", err, out)
+  }
+  if again.Cell.Coordinate != Fractional || again.Cell.Types[1] != "Cl" || again.Cell.Lattice[8] != 5.6 {
+    t.Errorf("round trip cell: got %v", again.Cell)
+  }
+  for i := range expect_flags {
+    if again.Cell.SelectiveDynamics[i] != expect_flags[i] {
+      t.Errorf("round trip if_pos expected %v, got %v", expect_flags, again.Cell.SelectiveDynamics)
+      break
+    }
+  }
+  if again.Namelists["control"]["calculation"] != "'relax'" || again.Namelists["ions"] == nil {
+    t.Errorf("round trip namelists: got %v", again.Namelists)
+  }
+  if _, ok := again.Cards["K_POINTS"]; !ok {
+    t.Error("round trip lost K_POINTS")
+  }
+}
+
+func TestParsePwInputError(t *testing.T) {
+  cases := []string{
+    strings.Replace(pwSi, "ATOMIC_POSITIONS alat", "ATOMIC_POSITION alat", 1),
+    strings.Replace(pwSi, "nat = 2", "nat = 3", 1),
+    strings.Replace(pwSi, "celldm(1) = 10.20d0, ", "", 1),
+    strings.Replace(pwSi, "ibrav = 2", "ibrav = 0", 1),
+    strings.Replace(pwSi, "  Si 0.25 0.25 0.25", "  Si 0.25 0.25 x", 1),
+  }
+  for _, c := range cases {
+    if _, err := ParsePwInput(c); err == nil {
+      t.Errorf("expect error for\n%s", c)
+    }
+  }
+  var pe *PwError
+  if _, err := ParsePwInput("\n&"); !errors.As(err, &pe) || pe.Line != 2 {
+    t.Errorf("expect error at line 2 for bare &, got %v", err)
+  }
+}
+
+func FuzzParsePwInput(f *testing.F) {
+  f.Add(pwSi)
+  f.Add("&")
+  f.Add("&system / \nATOMIC_POSITIONS\n")
+  f.Fuzz(func(t *testing.T, txt string) {
+    in, err := ParsePwInput(txt)
+    if err != nil {
+      return
+    }
+    if c := in.Cell; len(c.Positions) != 3*len(c.Types) || len(c.Lattice) != 9 {
+      t.Errorf("inconsistent cell: %d positions for %d atoms", len(c.Positions), len(c.Types))
+    }
+  })
+}
+
+func TestIbravLattice(t *testing.T) {
+  a := 10.0
+  cases := []struct{
+    ibrav int
+    celldm [6]float64
+    volume float64
+  }{
+    {1, [6]float64{a}, a*a*a},
+    {2, [6]float64{a}, a*a*a/4},
+    {3, [6]float64{a}, a*a*a/2},
+    {-3, [6]float64{a}, a*a*a/2},
+    {4, [6]float64{a, 0, 1.6}, a*a*a*1.6*math.Sqrt(3)/2},
+    {7, [6]float64{a, 0, 1.5}, a*a*a*1.5/2},
+    {8, [6]float64{a, 1.2, 1.5}, a*a*a*1.2*1.5},
+    {10, [6]float64{a, 1.2, 1.5}, a*a*a*1.2*1.5/4},
+    {11, [6]float64{a, 1.2, 1.5}, a*a*a*1.2*1.5/2},
+    {14, [6]float64{a, 1, 1, 0, 0, 0}, a*a*a},
+  }
+  for _, c := range cases {
+    latt, err := IbravLattice(c.ibrav, c.celldm)
+    if err != nil {
+      t.Errorf("ibrav %d: %v", c.ibrav, err)
+      continue
+    }
+    vol := math.Abs(det3(latt)) / (Bohr*Bohr*Bohr)
+    if math.Abs(vol - c.volume) > 1e-6 {
+      t.Errorf("ibrav %d: volume expected %v, got %v", c.ibrav, c.volume, vol)
+    }
+  }
+  // rhombohedral settings 5 and -5 have the same lengths
+  for _, ibrav := range []int{5, -5} {
+    latt, _ := IbravLattice(ibrav, [6]float64{a, 0, 0, 0.5})
+    for i:=0; i<3; i++ {
+      l := math.Sqrt(latt[i*3]*latt[i*3] + latt[i*3+1]*latt[i*3+1] + latt[i*3+2]*latt[i*3+2])
+      if math.Abs(l - a*Bohr) > 1e-8 {
+        t.Errorf("ibrav %d: length expected %v, got %v", ibrav, a*Bohr, l)
+      }
+    }
+  }
+  if _, err := IbravLattice(15, [6]float64{a}); err == nil {
+    t.Error("expect error for unsupported ibrav")
+  }
+}
+
+const pwOutput = `
+     bravais-lattice index     =            2
+     lattice parameter (alat)  =      10.2000  a.u.
+     unit-cell volume          =     265.3020 (a.u.)^3
+     number of atoms/cell      =            2
+     number of atomic types    =            1
+
+     crystal axes: (cart. coord. in units of alat)
+               a(1) = (  -0.500000   0.000000   0.500000 )  
+               a(2) = (   0.000000   0.500000   0.500000 )  
+               a(3) = (  -0.500000   0.500000   0.000000 )  
+
+     site n.     atom                  positions (alat units)
+         1           Si  tau(   1) = (   0.0000000   0.0000000   0.0000000  )
+         2           Si  tau(   2) = (   0.2500000   0.2500000   0.2500000  )
+
+!    total energy              =     -15.79441709 Ry
+
+     Forces acting on atoms (cartesian axes, Ry/au):
+
+     atom    1 type  1   force =     0.00100000    0.00000000    0.00000000
+     atom    2 type  1   force =    -0.00100000    0.00000000    0.00000000
+
+     Total force =     0.001414     Total SCF correction =     0.000000
+
+     Computing stress (Cartesian axis) and pressure
+
+          total   stress  (Ry/bohr**3)                   (kbar)     P=       -0.84
+  -0.00000571   0.00000000   0.00000000           -0.84        0.00        0.00
+   0.00000000  -0.00000571   0.00000000            0.00       -0.84        0.00
+   0.00000000   0.00000000  -0.00000571            0.00        0.00       -0.84
+
+Begin final coordinates
+
+ATOMIC_POSITIONS (crystal)
+Si            0.0000000000        0.0000000000        0.0000000000
+Si            0.2600000000        0.2500000000        0.2500000000
+End final coordinates
+`
+
+func TestParsePwOutput(t *testing.T) {
+  out, err := ParsePwOutput(pwOutput)
+  if err != nil {
+    t.Fatalf("parse pw output error: %v", err)
+  }
+  if math.Abs(out.Energy - (-15.79441709*Rydberg)) > 1e-8 {
+    t.Errorf("energy expected %v, got %v", -15.79441709*Rydberg, out.Energy)
+  }
+  if len(out.Forces) != 6 || math.Abs(out.Forces[0] - 0.001*Rydberg/Bohr) > 1e-8 {
+    t.Errorf("forces read fail: %v", out.Forces)
+  }
+  if len(out.Stress) != 9 || out.Stress[0] != -0.84 || out.Stress[8] != -0.84 {
+    t.Errorf("stress read fail: %v", out.Stress)
+  }
+  c := out.Cell
+  if c.Coordinate != Fractional || c.Positions[3] != 0.26 || c.Types[1] != "Si" {
+    t.Errorf("final positions read fail: %v", c)
+  }
+  if math.Abs(c.Lattice[0] - (-0.5*10.2*Bohr)) > 1e-8 {
+    t.Errorf("lattice read fail: %v", c.Lattice)
+  }
+
+  scf := strings.Split(pwOutput, "Begin final coordinates")[0]
+  out, err = ParsePwOutput(scf)
+  if err != nil {
+    t.Fatalf("parse scf output error: %v", err)
+  }
+  if out.Cell.Coordinate != Cartesian || math.Abs(out.Cell.Positions[3] - 0.25*10.2*Bohr) > 1e-8 {
+    t.Errorf("header positions read fail: %v", out.Cell)
+  }
+
+  if _, err := ParsePwOutput(strings.Replace(scf, "!    total energy", "     total energy", 1)); err == nil {
+    t.Error("expect error without total energy")
+  }
+  huge := "     number of atoms/cell      =   99999999999999\n     Forces acting on atoms (cartesian axes, Ry/au):\n"
+  if _, err := ParsePwOutput(huge); err == nil {
+    t.Error("expect error for forces of too many atoms")
+  }
+}
@@ -0,0 +1,432 @@
+package io
+
+import (
+  "bufio"
+  "fmt"
+  "io"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+// ExtXYZError is returned when extended XYZ input is malformed
+type ExtXYZError struct {
+  // Line is 1-based line number of the offending line
+  Line int
+  // Msg describe what is wrong
+  Msg string
+}
+
+func (e *ExtXYZError) Error() string {
+  return fmt.Sprintf("extxyz line %d: %s", e.Line, e.Msg)
+}
+
+// ExtXYZProperty is a per-atom column of extended XYZ, e.g. forces:R:3.
+// Values are stored row by row, Cols per atom, in the slice of its type
+type ExtXYZProperty struct {
+  Name string
+  // Type is one of 'S' (string), 'R' (real), 'I' (integer), 'L' (logical)
+  Type byte
+  Cols int
+  // Floats hold values of R and I properties
+  Floats []float64
+  // Strings hold values of S properties
+  Strings []string
+  // Bools hold values of L properties
+  Bools []bool
+}
+
+// ExtXYZFrame is a frame of extended XYZ
+type ExtXYZFrame struct {
+  // Cell holds species and cartesian positions, and the lattice if the
+  // frame has one, otherwise Cell.Lattice is nil
+  Cell *Cell
+  // Info holds key-value pairs of comment line except Lattice and
+  // Properties, empty for a plain XYZ comment, which goes to Cell.System
+  Info map[string]string
+  // Properties are per-atom columns except species and pos
+  Properties []ExtXYZProperty
+}
+
+// Property return per-atom property by name, nil if absent
+func (f *ExtXYZFrame) Property(name string) *ExtXYZProperty {
+  for i := range f.Properties {
+    if f.Properties[i].Name == name {
+      return &f.Properties[i]
+    }
+  }
+  return nil
+}
+
+// ExtXYZReader read frames of extended XYZ one at a time, so trajectories
+// need not fit in memory
+type ExtXYZReader struct {
+  r *bufio.Reader
+  line int
+}
+
+// NewExtXYZReader return reader of frames from r
+func NewExtXYZReader(r io.Reader) *ExtXYZReader {
+  return &ExtXYZReader{r: bufio.NewReader(r)}
+}
+
+// readLine return next line without line ending, io.EOF at end of input
+func (x *ExtXYZReader) readLine() (string, error) {
+  s, err := x.r.ReadString('\n')
+  x.line++
+  if err == io.EOF && s != "" {
+    err = nil
+  }
+  if err != nil {
+    return "", err
+  }
+  return strings.TrimRight(s, "\r\n"), nil
+}
+
+func (x *ExtXYZReader) errorf(format string, a ...interface{}) error {
+  return &ExtXYZError{Line: x.line, Msg: fmt.Sprintf(format, a...)}
+}
+
+// Next return next frame, io.EOF when there are no more frames
+func (x *ExtXYZReader) Next() (*ExtXYZFrame, error) {
+  var line string
+  var err error
+  // blank lines between frames are skipped
+  for line == "" {
+    line, err = x.readLine()
+    if err != nil {
+      return nil, err
+    }
+    line = strings.TrimSpace(line)
+  }
+  natoms, err := strconv.Atoi(line)
+  if err != nil || natoms < 0 {
+    return nil, x.errorf("expect number of atoms, got %q", line)
+  }
+
+  comment, err := x.readLine()
+  if err == io.EOF {
+    return nil, x.errorf("unexpected end of file, expect comment line")
+  } else if err != nil {
+    return nil, err
+  }
+  info, err := parseExtXYZInfo(comment)
+  if err != nil {
+    return nil, x.errorf("%v", err)
+  }
+  system := ""
+  if info == nil {
+    // plain XYZ comment
+    info = make(map[string]string)
+    system = strings.TrimSpace(comment)
+  }
+
+  frame := &ExtXYZFrame{
+    Cell: &Cell{Coordinate: Cartesian},
+    Info: info,
+  }
+  if v, ok := info["Lattice"]; ok {
+    vs := strings.Fields(v)
+    if len(vs) != 9 {
+      return nil, x.errorf("expect 9 values in Lattice, got %d", len(vs))
+    }
+    frame.Cell.Lattice = make([]float64, 9)
+    for i, s := range vs {
+      if frame.Cell.Lattice[i], err = strconv.ParseFloat(s, 64); err != nil {
+        return nil, x.errorf("parse Lattice, %v", err)
+      }
+    }
+    delete(info, "Lattice")
+  }
+  props := "species:S:1:pos:R:3"
+  if v, ok := info["Properties"]; ok {
+    props = v
+    delete(info, "Properties")
+  }
+  columns, err := parseExtXYZProperties(props)
+  if err != nil {
+    return nil, x.errorf("%v", err)
+  }
+  species, pos := -1, -1
+  for i, c := range columns {
+    switch {
+    case c.Name == "species" && c.Type == 'S' && c.Cols == 1:
+      species = i
+    case c.Name == "pos" && c.Type == 'R' && c.Cols == 3:
+      pos = i
+    default:
+      frame.Properties = append(frame.Properties, ExtXYZProperty{Name: c.Name, Type: c.Type, Cols: c.Cols})
+    }
+  }
+  if species < 0 || pos < 0 {
+    return nil, x.errorf("Properties must contain species:S:1 and pos:R:3")
+  }
+  ncols := 0
+  for _, c := range columns {
+    ncols += c.Cols
+  }
+
+  // natoms comes from the file, slices grow with the lines actually read
+  frame.Cell.Types = make([]string, 0)
+  frame.Cell.Positions = make([]float64, 0)
+  for n:=0; n<natoms; n++ {
+    line, err := x.readLine()
+    if err == io.EOF {
+      return nil, x.errorf("unexpected end of file, expect %d atoms, got %d", natoms, n)
+    } else if err != nil {
+      return nil, err
+    }
+    vs := strings.Fields(line)
+    if len(vs) != ncols {
+      return nil, x.errorf("expect %d columns, got %d", ncols, len(vs))
+    }
+    p := 0
+    for i, c := range columns {
+      fields := vs[:c.Cols]
+      vs = vs[c.Cols:]
+      switch i {
+      case species:
+        frame.Cell.Types = append(frame.Cell.Types, fields[0])
+        continue
+      case pos:
+        for _, s := range fields {
+          v, err := strconv.ParseFloat(s, 64)
+          if err != nil {
+            return nil, x.errorf("parse pos, %v", err)
+          }
+          frame.Cell.Positions = append(frame.Cell.Positions, v)
+        }
+        continue
+      }
+      if err := frame.Properties[p].appendValues(fields); err != nil {
+        return nil, x.errorf("%v", err)
+      }
+      p++
+    }
+  }
+  frame.Cell.System = system
+  if s, ok := info["comment"]; ok {
+    frame.Cell.System = s
+  }
+  return frame, nil
+}
+
+func (p *ExtXYZProperty) appendValues(fields []string) error {
+  for _, s := range fields {
+    switch p.Type {
+    case 'S':
+      p.Strings = append(p.Strings, s)
+    case 'R', 'I':
+      v, err := strconv.ParseFloat(s, 64)
+      if err != nil {
+        return fmt.Errorf("parse %s, %v", p.Name, err)
+      }
+      p.Floats = append(p.Floats, v)
+    case 'L':
+      switch strings.ToUpper(s) {
+      case "T", "TRUE":
+        p.Bools = append(p.Bools, true)
+      case "F", "FALSE":
+        p.Bools = append(p.Bools, false)
+      default:
+        return fmt.Errorf("parse %s, expect logical value, got %q", p.Name, s)
+      }
+    }
+  }
+  return nil
+}
+
+// parseExtXYZProperties parse Properties value such as
+// species:S:1:pos:R:3:forces:R:3
+func parseExtXYZProperties(s string) ([]ExtXYZProperty, error) {
+  vs := strings.Split(s, ":")
+  if len(vs)%3 != 0 {
+    return nil, fmt.Errorf("Properties %q: expect name:type:columns triples", s)
+  }
+  props := make([]ExtXYZProperty, 0, len(vs)/3)
+  for i:=0; i<len(vs); i+=3 {
+    p := ExtXYZProperty{Name: vs[i]}
+    if len(vs[i+1]) != 1 || !strings.Contains("SRIL", vs[i+1]) {
+      return nil, fmt.Errorf("Properties %q: unknown type %q of %s", s, vs[i+1], vs[i])
+    }
+    p.Type = vs[i+1][0]
+    n, err := strconv.Atoi(vs[i+2])
+    if err != nil || n < 1 {
+      return nil, fmt.Errorf("Properties %q: bad column count %q of %s", s, vs[i+2], vs[i])
+    }
+    p.Cols = n
+    props = append(props, p)
+  }
+  return props, nil
+}
+
+// parseExtXYZInfo parse key=value pairs of comment line, values may be
+// quoted with "" or {}. Info is nil if a word has no =, the line is then a
+// plain XYZ comment
+func parseExtXYZInfo(s string) (map[string]string, error) {
+  info := make(map[string]string)
+  i := 0
+  for {
+    for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+      i++
+    }
+    if i >= len(s) {
+      return info, nil
+    }
+    j := i
+    for j < len(s) && s[j] != '=' && s[j] != ' ' && s[j] != '\t' {
+      j++
+    }
+    key := s[i:j]
+    if j >= len(s) || s[j] != '=' {
+      return nil, nil
+    }
+    j++
+    if j < len(s) && (s[j] == '"' || s[j] == '{') {
+      end := byte('"')
+      if s[j] == '{' {
+        end = '}'
+      }
+      k := strings.IndexByte(s[j+1:], end)
+      if k < 0 {
+        return nil, fmt.Errorf("unterminated value of %s", key)
+      }
+      info[key] = s[j+1 : j+1+k]
+      i = j+1+k+1
+      continue
+    }
+    k := j
+    for k < len(s) && s[k] != ' ' && s[k] != '\t' {
+      k++
+    }
+    info[key] = s[j:k]
+    i = k
+  }
+}
+
+// ParseExtXYZ parse all frames of extended XYZ, use ExtXYZReader for
+// large trajectories
+func ParseExtXYZ(txt string) ([]*ExtXYZFrame, error) {
+  r := NewExtXYZReader(strings.NewReader(txt))
+  frames := make([]*ExtXYZFrame, 0)
+  for {
+    f, err := r.Next()
+    if err == io.EOF {
+      return frames, nil
+    }
+    if err != nil {
+      return nil, err
+    }
+    frames = append(frames, f)
+  }
+}
+
+// ExtXYZWriter write frames of extended XYZ one at a time
+type ExtXYZWriter struct {
+  w io.Writer
+  // Precision is number of decimals of real values, 0 means 8
+  Precision int
+}
+
+// NewExtXYZWriter return writer of frames to w
+func NewExtXYZWriter(w io.Writer) *ExtXYZWriter {
+  return &ExtXYZWriter{w: w}
+}
+
+// Write write frame, positions are written in cartesian coordinate and
+// Cell.System, if set, as comment key
+func (x *ExtXYZWriter) Write(f *ExtXYZFrame) error {
+  prec := x.Precision
+  if prec <= 0 {
+    prec = 8
+  }
+  c := f.Cell
+  natoms := len(c.Types)
+  if len(c.Positions) != 3*natoms {
+    return fmt.Errorf("atom number not compatible, len(types)=%d, len(positions)/3=%d/3", natoms, len(c.Positions))
+  }
+  pos, err := c.CartesianPositions()
+  if err != nil {
+    return err
+  }
+  props := "species:S:1:pos:R:3"
+  for _, p := range f.Properties {
+    if p.Type == 0 || !strings.ContainsRune("SRIL", rune(p.Type)) {
+      return fmt.Errorf("property %s has unknown type %q", p.Name, p.Type)
+    }
+    if n := p.len(); n != p.Cols*natoms {
+      return fmt.Errorf("property %s has %d values, expect %d", p.Name, n, p.Cols*natoms)
+    }
+    props += fmt.Sprintf(":%s:%c:%d", p.Name, p.Type, p.Cols)
+  }
+
+  var b strings.Builder
+  fmt.Fprintf(&b, "%d\n", natoms)
+  if c.Lattice != nil {
+    if len(c.Lattice) != 9 {
+      return fmt.Errorf("expect 9 value as lattice, got %d", len(c.Lattice))
+    }
+    ls := make([]string, 9)
+    for i, v := range c.Lattice {
+      ls[i] = strconv.FormatFloat(v, 'f', prec, 64)
+    }
+    fmt.Fprintf(&b, "Lattice=\"%s\" ", strings.Join(ls, " "))
+  }
+  fmt.Fprintf(&b, "Properties=%s", props)
+  info := make(map[string]string, len(f.Info)+1)
+  if c.System != "" {
+    info["comment"] = c.System
+  }
+  for k, v := range f.Info {
+    info[k] = v
+  }
+  keys := make([]string, 0, len(info))
+  for k := range info {
+    if k != "Lattice" && k != "Properties" {
+      keys = append(keys, k)
+    }
+  }
+  sort.Strings(keys)
+  for _, k := range keys {
+    v := info[k]
+    switch {
+    case v == "" || strings.ContainsAny(v, " \t="):
+      fmt.Fprintf(&b, " %s=\"%s\"", k, v)
+    default:
+      fmt.Fprintf(&b, " %s=%s", k, v)
+    }
+  }
+  fmt.Fprintln(&b)
+
+  for i:=0; i<natoms; i++ {
+    fmt.Fprintf(&b, "%-3s %.*f %.*f %.*f", c.Types[i], prec, pos[i*3], prec, pos[i*3+1], prec, pos[i*3+2])
+    for _, p := range f.Properties {
+      for j:=i*p.Cols; j<(i+1)*p.Cols; j++ {
+        switch p.Type {
+        case 'S':
+          fmt.Fprintf(&b, " %s", p.Strings[j])
+        case 'R':
+          fmt.Fprintf(&b, " %.*f", prec, p.Floats[j])
+        case 'I':
+          fmt.Fprintf(&b, " %d", int(p.Floats[j]))
+        case 'L':
+          fmt.Fprintf(&b, " %s", flagString(p.Bools[j]))
+        }
+      }
+    }
+    fmt.Fprintln(&b)
+  }
+  _, err = io.WriteString(x.w, b.String())
+  return err
+}
+
+// len return number of values of property
+func (p *ExtXYZProperty) len() int {
+  switch p.Type {
+  case 'S':
+    return len(p.Strings)
+  case 'L':
+    return len(p.Bools)
+  }
+  return len(p.Floats)
+}
@@ -0,0 +1,163 @@
+package io
+
+import(
+  "errors"
+  "io"
+  "math"
+  "strings"
+  "testing"
+)
+
+const extxyzTraj = `2
+Lattice="4.0 0.0 0.0 0.0 4.0 0.0 0.0 0.0 4.0" Properties=species:S:1:pos:R:3:forces:R:3:fixed:L:1 energy=-10.5 pbc="T T T" comment="step 0"
+B 0.0 0.0 0.0 0.1 0.0 0.0 T
+N 2.0 2.0 2.0 -0.1 0.0 0.0 F
+2
+Lattice="4.0 0.0 0.0 0.0 4.0 0.0 0.0 0.0 4.0" Properties=species:S:1:pos:R:3:forces:R:3:fixed:L:1 energy=-10.7 pbc="T T T"
+B 0.0 0.0 0.1 0.0 0.0 0.0 T
+N 2.0 2.0 1.9 0.0 0.0 0.0 F
+`
+
+func TestExtXYZRead(t *testing.T) {
+  r := NewExtXYZReader(strings.NewReader(extxyzTraj))
+  f, err := r.Next()
+  if err != nil {
+    t.Fatalf("read frame error: %v", err)
+  }
+  if f.Cell.Lattice[4] != 4.0 || f.Cell.Types[1] != "N" || f.Cell.Positions[3] != 2.0 {
+    t.Errorf("frame cell read fail: %v", f.Cell)
+  }
+  if f.Cell.System != "step 0" || f.Info["energy"] != "-10.5" || f.Info["pbc"] != "T T T" {
+    t.Errorf("frame info read fail: %v", f.Info)
+  }
+  forces := f.Property("forces")
+  if forces == nil || forces.Cols != 3 || forces.Floats[3] != -0.1 {
+    t.Errorf("forces read fail: %v", forces)
+  }
+  fixed := f.Property("fixed")
+  if fixed == nil || !fixed.Bools[0] || fixed.Bools[1] {
+    t.Errorf("fixed read fail: %v", fixed)
+  }
+
+  f, err = r.Next()
+  if err != nil || f.Info["energy"] != "-10.7" {
+    t.Fatalf("read second frame: %v, %v", f, err)
+  }
+  if _, err = r.Next(); err != io.EOF {
+    t.Errorf("expect io.EOF after last frame, got %v", err)
+  }
+}
+
+func TestExtXYZReadPlain(t *testing.T) {
+  frames, err := ParseExtXYZ("1\nwater molecule fragment\nO 0.0 0.0 0.0\n")
+  if err != nil {
+    t.Fatalf("parse plain xyz error: %v", err)
+  }
+  if len(frames) != 1 || frames[0].Cell.Lattice != nil || frames[0].Cell.Types[0] != "O" {
+    t.Errorf("plain xyz read fail: %v", frames)
+  }
+}
+
+func TestExtXYZPlainCommentRoundTrip(t *testing.T) {
+  frames, err := ParseExtXYZ("1\nwater molecule\nO 0.0 0.0 0.0\n")
+  if err != nil {
+    t.Fatalf("parse plain xyz error: %v", err)
+  }
+  if frames[0].Cell.System != "water molecule" || len(frames[0].Info) != 0 {
+    t.Fatalf("plain comment expect system %q and no info, got %q, %v", "water molecule", frames[0].Cell.System, frames[0].Info)
+  }
+  var b strings.Builder
+  if err := NewExtXYZWriter(&b).Write(frames[0]); err != nil {
+    t.Fatalf("write frame error: %v", err)
+  }
+  again, err := ParseExtXYZ(b.String())
+  if err != nil {
+    t.Fatalf("parse written frame error: %v\n%s", err, b.String())
+  }
+  if again[0].Cell.System != "water molecule" {
+    t.Errorf("comment expected %q, got %q\n%s", "water molecule", again[0].Cell.System, b.String())
+  }
+  for k, v := range again[0].Info {
+    if k != "comment" {
+      t.Errorf("unexpected info %s=%q", k, v)
+    }
+  }
+}
+
+func TestExtXYZReadError(t *testing.T) {
+  cases := []struct{
+    txt string
+    line int
+  }{
+    {"two\n", 1},
+    {"2\n\nB 0 0 0\n", 4},
+    {"1\nLattice=\"1 0 0\"\nB 0 0 0\n", 2},
+    {"1\nProperties=species:S:1:pos:R:2\nB 0 0\n", 2},
+    {"1\n\nB 0 0 x\n", 3},
+    {"1\nProperties=species:S:1:pos:R:3:fixed:L:1\nB 0 0 0 maybe\n", 3},
+    {"99999999999999\n\nB 0 0 0\n", 4},
+  }
+  for _, c := range cases {
+    _, err := ParseExtXYZ(c.txt)
+    var xe *ExtXYZError
+    if !errors.As(err, &xe) || xe.Line != c.line {
+      t.Errorf("%q: expect error at line %d, got %v", c.txt, c.line, err)
+    }
+  }
+}
+
+func TestExtXYZRoundTrip(t *testing.T) {
+  frames, err := ParseExtXYZ(extxyzTraj)
+  if err != nil {
+    t.Fatalf("parse error: %v", err)
+  }
+  var b strings.Builder
+  w := NewExtXYZWriter(&b)
+  for _, f := range frames {
+    if err := w.Write(f); err != nil {
+      t.Fatalf("write frame error: %v", err)
+    }
+  }
+  again, err := ParseExtXYZ(b.String())
+  if err != nil {
+    t.Fatalf("parse written frames error: %v\n%s", err, b.String())
+  }
+  if len(again) != 2 {
+    t.Fatalf("expect 2 frames, got %d", len(again))
+  }
+  for i, f := range again {
+    for j, v := range frames[i].Cell.Positions {
+      if math.Abs(f.Cell.Positions[j] - v) > 1e-8 {
+        t.Errorf("frame %d positions expected %v, got %v", i, frames[i].Cell.Positions, f.Cell.Positions)
+        break
+      }
+    }
+    if f.Info["pbc"] != "T T T" || f.Info["energy"] != frames[i].Info["energy"] {
+      t.Errorf("frame %d info expected %v, got %v", i, frames[i].Info, f.Info)
+    }
+    if f.Cell.System != frames[i].Cell.System {
+      t.Errorf("frame %d comment expected %q, got %q", i, frames[i].Cell.System, f.Cell.System)
+    }
+    if p := f.Property("fixed"); p == nil || p.Bools[0] != true {
+      t.Errorf("frame %d fixed property lost: %v", i, p)
+    }
+  }
+}
+
+func FuzzParseExtXYZ(f *testing.F) {
+  f.Add(extxyzTraj)
+  f.Add("1\nwater molecule\nO 0.0 0.0 0.0\n")
+  f.Add("99999999999999\n\nB 0 0 0\n")
+  f.Fuzz(func(t *testing.T, txt string) {
+    frames, err := ParseExtXYZ(txt)
+    if err != nil {
+      return
+    }
+    for _, fr := range frames {
+      c := fr.Cell
+      if len(c.Positions) != 3*len(c.Types) || c.Lattice != nil && len(c.Lattice) != 9 {
+        t.Errorf("inconsistent frame: %d positions for %d atoms, lattice %v", len(c.Positions), len(c.Types), c.Lattice)
+      }
+    }
+  })
+}
@@ -0,0 +1,74 @@
+package io
+
+import (
+  "fmt"
+  "io"
+  "strings"
+
+  "github.com/unkcpz/gocmp/crystal"
+)
+
+// Kpoints is VASP KPOINTS file, either automatic mesh or explicit list of
+// points in fraction of reciprocal lattice
+type Kpoints struct {
+  Comment string
+  // Mesh is written in automatic mode if not nil, Points and Weights are
+  // ignored
+  Mesh *crystal.KMesh
+  Points [][3]float64
+  Weights []float64
+}
+
+// KpointsFromMesh return explicit list of irreducible points of mesh with
+// their weights
+func KpointsFromMesh(comment string, ir *crystal.IrreducibleMesh) *Kpoints {
+  k := &Kpoints{Comment: comment}
+  for i, p := range ir.Irreducible {
+    k.Points = append(k.Points, ir.Points[p])
+    k.Weights = append(k.Weights, float64(ir.Weights[i]))
+  }
+  return k
+}
+
+// FormatKpoints render k as VASP KPOINTS
+func FormatKpoints(k *Kpoints) (string, error) {
+  var b strings.Builder
+  fmt.Fprintln(&b, strings.Replace(k.Comment, "\n", " ", -1))
+  if m := k.Mesh; m != nil {
+    if m.Mesh[0] < 1 || m.Mesh[1] < 1 || m.Mesh[2] < 1 {
+      return "", fmt.Errorf("invalid k-point mesh %v", m.Mesh)
+    }
+    fmt.Fprintln(&b, "0")
+    if m.Gamma {
+      fmt.Fprintln(&b, "Gamma")
+    } else {
+      fmt.Fprintln(&b, "Monkhorst-Pack")
+    }
+    fmt.Fprintf(&b, "  %d %d %d\n", m.Mesh[0], m.Mesh[1], m.Mesh[2])
+    fmt.Fprintf(&b, "  %g %g %g\n", m.Shift[0], m.Shift[1], m.Shift[2])
+    return b.String(), nil
+  }
+
+  if len(k.Points) == 0 {
+    return "", fmt.Errorf("no k-points to write")
+  }
+  if len(k.Weights) != len(k.Points) {
+    return "", fmt.Errorf("expect one weight per k-point, got %d for %d points", len(k.Weights), len(k.Points))
+  }
+  fmt.Fprintln(&b, len(k.Points))
+  fmt.Fprintln(&b, "Reciprocal")
+  for i, p := range k.Points {
+    fmt.Fprintf(&b, "  %14.10f %14.10f %14.10f %g\n", p[0], p[1], p[2], k.Weights[i])
+  }
+  return b.String(), nil
+}
+
+// WriteKpoints write k to w in VASP KPOINTS format, see FormatKpoints
+func WriteKpoints(w io.Writer, k *Kpoints) error {
+  txt, err := FormatKpoints(k)
+  if err != nil {
+    return err
+  }
+  _, err = io.WriteString(w, txt)
+  return err
+}
@@ -0,0 +1,64 @@
+package io
+
+import (
+  "testing"
+
+  "github.com/unkcpz/gocmp/crystal"
+)
+
+func TestKpointsWriteMesh(t *testing.T) {
+  k := &Kpoints{
+    Comment: "Si",
+    Mesh: &crystal.KMesh{Mesh: [3]int{8, 8, 6}, Shift: [3]float64{0, 0, 0.5}, Gamma: true},
+  }
+  txt, err := FormatKpoints(k)
+  if err != nil {
+    t.Fatalf("format kpoints error: %v", err)
+  }
+  expect := `Si
+0
+Gamma
+  8 8 6
+  0 0 0.5
+`
+  if txt != expect {
+    t.Errorf("kpoints write expected\n%s\ngot\n%s", expect, txt)
+  }
+
+  k.Mesh.Mesh[2] = 0
+  if _, err := FormatKpoints(k); err == nil {
+    t.Error("expect error for empty mesh")
+  }
+}
+
+func TestKpointsWriteList(t *testing.T) {
+  m := &crystal.KMesh{Mesh: [3]int{2, 2, 2}, Gamma: true}
+  ir, err := m.Reduce(nil, true)
+  if err != nil {
+    t.Fatalf("reduce error: %v", err)
+  }
+  txt, err := FormatKpoints(KpointsFromMesh("P1 2x2x2", ir))
+  if err != nil {
+    t.Fatalf("format kpoints error: %v", err)
+  }
+  // all points of 2x2x2 grid are their own time reversal image
+  expect := `P1 2x2x2
+8
+Reciprocal
+    0.0000000000   0.0000000000   0.0000000000 1
+   -0.5000000000   0.0000000000   0.0000000000 1
+    0.0000000000  -0.5000000000   0.0000000000 1
+   -0.5000000000  -0.5000000000   0.0000000000 1
+    0.0000000000   0.0000000000  -0.5000000000 1
+   -0.5000000000   0.0000000000  -0.5000000000 1
+    0.0000000000  -0.5000000000  -0.5000000000 1
+   -0.5000000000  -0.5000000000  -0.5000000000 1
+`
+  if txt != expect {
+    t.Errorf("kpoints write expected\n%s\ngot\n%s", expect, txt)
+  }
+
+  if _, err := FormatKpoints(&Kpoints{Points: [][3]float64{{0, 0, 0}}}); err == nil {
+    t.Error("expect error for missing weights")
+  }
+}
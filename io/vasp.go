@@ -3,40 +3,235 @@ package io
 import (
   "strings"
   "fmt"
+  "io"
+  "math"
   "strconv"
+
+  "github.com/unkcpz/gocmp/crystal"
 )
 
+// DefaultPoscarPrecision is the number of decimals written when
+// PoscarOptions.Precision is not set
+const DefaultPoscarPrecision = 10
+
+// PoscarOptions control how FormatPoscar render a Cell
+type PoscarOptions struct {
+  // Precision is number of decimals of lattice and positions
+  Precision int
+  // Cartesian write positions in cartesian instead of direct coordinate
+  Cartesian bool
+  // SelectiveDynamics holds 3 flags (x, y, z) per atom, true means the
+  // coordinate is allowed to relax. nil falls back to the flags of the
+  // cell, and the selective dynamics line is omitted if both are nil
+  SelectiveDynamics []bool
+}
+
+// PoscarError is returned by ParsePoscar when the input is malformed
+type PoscarError struct {
+  // Line is 1-based line number of the offending line
+  Line int
+  // Text is content of the offending line, empty if past end of input
+  Text string
+  // Msg describe what is wrong
+  Msg string
+  // Err is the underlying error, if any
+  Err error
+}
+
+func (e *PoscarError) Error() string {
+  s := fmt.Sprintf("poscar line %d %q: %s", e.Line, e.Text, e.Msg)
+  if e.Err != nil {
+    s += ": " + e.Err.Error()
+  }
+  return s
+}
+
+func (e *PoscarError) Unwrap() error {
+  return e.Err
+}
+
+// poscarLines walk lines of POSCAR and build PoscarError with line number
+type poscarLines struct {
+  lines []string
+  next int
+}
+
+func (p *poscarLines) errorf(i int, err error, format string, a ...interface{}) error {
+  var text string
+  if i < len(p.lines) {
+    text = p.lines[i]
+  }
+  return &PoscarError{Line: i+1, Text: text, Msg: fmt.Sprintf(format, a...), Err: err}
+}
+
+// line return index and content of next line, what describe the expected
+// content in error message when input ended
+func (p *poscarLines) line(what string) (int, string, error) {
+  i := p.next
+  if i >= len(p.lines) {
+    return i, "", p.errorf(i, nil, "unexpected end of file, expect %s", what)
+  }
+  p.next++
+  return i, p.lines[i], nil
+}
+
+// floats parse the first n fields of line i as float
+func (p *poscarLines) floats(i int, n int, what string) ([]float64, error) {
+  vs := strings.Fields(stripComment(p.lines[i]))
+  if len(vs) < n {
+    return nil, p.errorf(i, nil, "expect %d values as %s, got %d", n, what, len(vs))
+  }
+  r := make([]float64, n)
+  for j:=0; j<n; j++ {
+    v, err := strconv.ParseFloat(vs[j], 64)
+    if err != nil {
+      return nil, p.errorf(i, err, "parse %s", what)
+    }
+    r[j] = v
+  }
+  return r, nil
+}
+
+// stripComment remove trailing "!" or "#" comment of line
+func stripComment(s string) string {
+  if i := strings.IndexAny(s, "!#"); i >= 0 {
+    return s[:i]
+  }
+  return s
+}
+
+// PoscarReadOptions control ParsePoscarWithOptions
+type PoscarReadOptions struct {
+  // Species is symbol of each species in order of atom counts, e.g. from
+  // POTCAR. It is used for VASP 4 files which have no species line
+  Species []string
+}
+
+// ParsePoscar parse POSCAR or CONTCAR, malformed input is reported as
+// *PoscarError. See ParsePoscarWithOptions for supported variants
 func ParsePoscar(txt string) (*Cell, error) {
-  lines := strings.Split(strings.Replace(txt, "\r\n", "\n", -1), "\n")
+  return ParsePoscarWithOptions(txt, nil)
+}
 
-  system := lines[0]
+// ParsePoscarWithOptions parse POSCAR or CONTCAR of VASP 4, 5 and 6.
+//
+// The scale factor line holds either one value or three per-axis values.
+// A negative single value is the target cell volume. Species of VASP 4
+// files, which have no species line, are taken from opt.Species, or else
+// from the system comment when it lists exactly one element per count.
+// opt can be nil
+func ParsePoscarWithOptions(txt string, opt *PoscarReadOptions) (*Cell, error) {
+  if opt == nil {
+    opt = &PoscarReadOptions{}
+  }
+  p := &poscarLines{
+    lines: strings.Split(strings.Replace(txt, "\r\n", "\n", -1), "\n"),
+  }
+
+  _, system, err := p.line("system comment")
+  if err != nil {
+    return nil, err
+  }
 
-  scale, err := strconv.ParseFloat(strings.TrimSpace(lines[1]), 64)
+  si, _, err := p.line("scale factor")
   if err != nil {
-    return nil, fmt.Errorf("parse poscar scale, %v", err)
+    return nil, err
+  }
+  scale, err := p.floats(si, 3, "scale factor")
+  if err != nil {
+    scale, err = p.floats(si, 1, "scale factor")
+    if err != nil {
+      return nil, err
+    }
+    if scale[0] == 0 {
+      return nil, p.errorf(si, nil, "scale factor cannot be zero")
+    }
+  } else {
+    for _, v := range scale {
+      if v <= 0 {
+        return nil, p.errorf(si, nil, "per-axis scale factors must be positive")
+      }
+    }
   }
 
   lattice := make([]float64, 9, 9)
-  for i:=0; i<3; i++ {
-    vs := strings.Fields(lines[2+i])
-    lattice[i*3+0], _ = strconv.ParseFloat(vs[0], 64)
-    lattice[i*3+1], _ = strconv.ParseFloat(vs[1], 64)
-    lattice[i*3+2], _ = strconv.ParseFloat(vs[2], 64)
+  for j:=0; j<3; j++ {
+    i, _, err := p.line("lattice vector")
+    if err != nil {
+      return nil, err
+    }
+    vs, err := p.floats(i, 3, "lattice vector")
+    if err != nil {
+      return nil, err
+    }
+    copy(lattice[j*3:], vs)
+  }
+  // factor of cartesian x, y, z applied to lattice and cartesian positions
+  factor := []float64{1, 1, 1}
+  switch {
+  case len(scale) == 3:
+    copy(factor, scale)
+  case scale[0] > 0:
+    factor = []float64{scale[0], scale[0], scale[0]}
+  default:
+    vol := math.Abs(det3(lattice))
+    if vol < 1e-10 {
+      return nil, p.errorf(si, nil, "cannot scale volume of lattice with det=0")
+    }
+    f := math.Cbrt(-scale[0] / vol)
+    factor = []float64{f, f, f}
   }
   for i, _ := range lattice {
-    lattice[i] *= scale
+    lattice[i] *= factor[i%3]
   }
 
-  es := strings.Fields(lines[5])
+  i, line, err := p.line("species symbols")
+  if err != nil {
+    return nil, err
+  }
+  var es []string
+  if !allInts(stripComment(line)) {
+    es = strings.Fields(stripComment(line))
+    if len(es) == 0 {
+      return nil, p.errorf(i, nil, "expect species symbols")
+    }
+    i, line, err = p.line("atom counts")
+    if err != nil {
+      return nil, err
+    }
+  }
+  str := strings.Fields(stripComment(line))
+  if es == nil {
+    // VASP 4, no species line
+    es = opt.Species
+    if es == nil {
+      es = systemSpecies(system, len(str))
+    }
+    if es == nil {
+      return nil, p.errorf(i, nil, "no species line, species must be given in options")
+    }
+  }
+  if len(str) != len(es) {
+    return nil, p.errorf(i, nil, "expect %d atom counts for species %v, got %d", len(es), es, len(str))
+  }
   ntype := make([]int, 0, 0)
-  str := strings.Fields(lines[6])
   for _, s := range str {
-    n, _ := strconv.Atoi(s)
+    n, err := strconv.Atoi(s)
+    if err != nil {
+      return nil, p.errorf(i, err, "parse atom count")
+    }
+    if n <= 0 {
+      return nil, p.errorf(i, nil, "atom count must be positive, got %d", n)
+    }
     ntype = append(ntype, n)
   }
   var natoms int
   for _, v := range ntype {
     natoms += v
+    // each atom needs a line, so a larger count can never be satisfied
+    if v > len(p.lines) || natoms > len(p.lines) {
+      return nil, p.errorf(i, nil, "%d atoms exceed %d lines of file", natoms, len(p.lines))
+    }
   }
   types := make([]string, 0, 0)
   for i, n := range ntype {
@@ -45,24 +240,69 @@ func ParsePoscar(txt string) (*Cell, error) {
     }
   }
 
-  var coorLine int = 7
-  s := strings.ToUpper(strings.TrimSpace(lines[7])[:1])
-  if s == "S" {
-    coorLine = 8
+  i, line, err = p.line("coordinate type")
+  if err != nil {
+    return nil, err
+  }
+  selective := modeChar(line) == 'S'
+  if selective {
+    i, line, err = p.line("coordinate type")
+    if err != nil {
+      return nil, err
+    }
   }
 
+  // like VASP, any word not starting with C or K means direct
   var ctype CoorType = Fractional
-  ct := strings.ToUpper(strings.TrimSpace(lines[coorLine])[:1])
-  if ct == "K" || ct == "C" {
+  switch c := modeChar(line); {
+  case c == 'K' || c == 'C':
     ctype = Cartesian
+  case c == 0 || isNumberStart(c):
+    return nil, p.errorf(i, nil, "expect Direct or Cartesian")
   }
 
   positions := make([]float64, 3*natoms, 3*natoms)
-  for i:=0; i<natoms; i++ {
-    vs := strings.Fields(lines[coorLine+1+i])
-    positions[i*3+0], _ = strconv.ParseFloat(vs[0], 64)
-    positions[i*3+1], _ = strconv.ParseFloat(vs[1], 64)
-    positions[i*3+2], _ = strconv.ParseFloat(vs[2], 64)
+  var flags []bool
+  if selective {
+    flags = make([]bool, 3*natoms)
+  }
+  for j:=0; j<natoms; j++ {
+    i, line, err := p.line(fmt.Sprintf("position of atom %d", j+1))
+    if err == nil && strings.TrimSpace(line) == "" {
+      err = p.errorf(i, nil, "expect %d position lines, got %d", natoms, j)
+    }
+    if err != nil {
+      return nil, err
+    }
+    vs, err := p.floats(i, 3, "position")
+    if err != nil {
+      return nil, err
+    }
+    if ctype == Cartesian {
+      for k:=0; k<3; k++ {
+        vs[k] *= factor[k]
+      }
+    }
+    copy(positions[j*3:], vs)
+    if selective {
+      // atoms without flags are free to relax
+      fs := strings.Fields(stripComment(line))
+      switch {
+      case len(fs) == 3:
+        fs = []string{"", "", "", "T", "T", "T"}
+      case len(fs) < 6:
+        return nil, p.errorf(i, nil, "expect 3 selective dynamics flags, got %d", len(fs)-3)
+      }
+      for k:=0; k<3; k++ {
+        switch modeChar(fs[3+k]) {
+        case 'T':
+          flags[j*3+k] = true
+        case 'F':
+        default:
+          return nil, p.errorf(i, nil, "selective dynamics flag must be T or F, got %q", fs[3+k])
+        }
+      }
+    }
   }
 
   poscar := &Cell {
@@ -71,6 +311,229 @@ func ParsePoscar(txt string) (*Cell, error) {
     Coordinate: ctype,
     Positions: positions,
     Types: types,
+    SelectiveDynamics: flags,
+  }
+
+  // optional velocities and predictor-corrector block of CONTCAR
+  i, line, err = p.line("")
+  if err != nil {
+    return poscar, nil
+  }
+  c := modeChar(line)
+  if isNumberStart(c) {
+    return nil, p.errorf(i, nil, "more position lines than %d atoms", natoms)
+  }
+  if i, line, err := p.line(""); err != nil || strings.TrimSpace(line) == "" {
+    p.next = i
+    return poscar, p.predictorCorrector(poscar)
+  }
+  p.next--
+  velocities := make([]float64, 3*natoms)
+  for j:=0; j<natoms; j++ {
+    i, line, err := p.line(fmt.Sprintf("velocity of atom %d", j+1))
+    if err == nil && strings.TrimSpace(line) == "" {
+      err = p.errorf(i, nil, "expect %d velocity lines, got %d", natoms, j)
+    }
+    if err != nil {
+      return nil, err
+    }
+    vs, err := p.floats(i, 3, "velocity")
+    if err != nil {
+      return nil, err
+    }
+    copy(velocities[j*3:], vs)
+  }
+  // blank, C or K means cartesian
+  if c != 0 && c != 'C' && c != 'K' {
+    velocities = mulRows(velocities, lattice)
+  }
+  poscar.Velocities = velocities
+  return poscar, p.predictorCorrector(poscar)
+}
+
+// predictorCorrector store remaining non-blank lines verbatim in
+// c.PredictorCorrector
+func (p *poscarLines) predictorCorrector(c *Cell) error {
+  for p.next < len(p.lines) && strings.TrimSpace(p.lines[p.next]) == "" {
+    p.next++
+  }
+  rest := p.lines[p.next:]
+  for len(rest) > 0 && strings.TrimSpace(rest[len(rest)-1]) == "" {
+    rest = rest[:len(rest)-1]
+  }
+  if len(rest) == 0 {
+    return nil
+  }
+  if c.Velocities == nil {
+    return p.errorf(p.next, nil, "predictor-corrector block without velocities")
+  }
+  c.PredictorCorrector = strings.Join(rest, "\n")
+  return nil
+}
+
+// isNumberStart report whether c can begin a number
+func isNumberStart(c byte) bool {
+  return c == '-' || c == '+' || c == '.' || '0' <= c && c <= '9'
+}
+
+// allInts report whether line is non-empty and all fields are integers
+func allInts(line string) bool {
+  vs := strings.Fields(line)
+  for _, v := range vs {
+    if _, err := strconv.Atoi(v); err != nil {
+      return false
+    }
+  }
+  return len(vs) > 0
+}
+
+// systemSpecies return words of system comment as species if there are
+// exactly n of them and all are element symbols, otherwise nil
+func systemSpecies(system string, n int) []string {
+  es := strings.Fields(system)
+  if len(es) != n {
+    return nil
+  }
+  for _, e := range es {
+    if crystal.SymToNum(speciesSymbol(e)) == 0 {
+      return nil
+    }
+  }
+  return es
+}
+
+// modeChar return upper case of first non-space character of line, 0 if
+// the line is blank
+func modeChar(line string) byte {
+  s := strings.TrimSpace(line)
+  if s == "" {
+    return 0
+  }
+  c := s[0]
+  if 'a' <= c && c <= 'z' {
+    c -= 'a' - 'A'
+  }
+  return c
+}
+
+// FormatPoscar render cell as VASP 5 POSCAR, consecutive atoms of the same
+// type are grouped on the species and counts lines. Velocities and
+// predictor-corrector block of the cell are appended as in CONTCAR.
+// opt can be nil
+func FormatPoscar(c *Cell, opt *PoscarOptions) (string, error) {
+  if opt == nil {
+    opt = &PoscarOptions{}
+  }
+  prec := opt.Precision
+  if prec <= 0 {
+    prec = DefaultPoscarPrecision
+  }
+  if len(c.Lattice) != 9 {
+    return "", fmt.Errorf("expect 9 value as lattice, got %d", len(c.Lattice))
+  }
+  natoms := len(c.Types)
+  if len(c.Positions) != 3*natoms {
+    return "", fmt.Errorf("atom number not compatible, len(types)=%d, len(positions)/3=%d/3", natoms, len(c.Positions))
+  }
+  sd := opt.SelectiveDynamics
+  if sd == nil {
+    sd = c.SelectiveDynamics
+  }
+  if sd != nil && len(sd) != 3*natoms {
+    return "", fmt.Errorf("expect 3 selective dynamics flags per atom, got %d for %d atoms", len(sd), natoms)
+  }
+  if c.Velocities != nil && len(c.Velocities) != 3*natoms {
+    return "", fmt.Errorf("expect 3 velocities per atom, got %d for %d atoms", len(c.Velocities), natoms)
+  }
+  if c.PredictorCorrector != "" && c.Velocities == nil {
+    return "", fmt.Errorf("predictor-corrector block without velocities")
+  }
+
+  var positions []float64
+  var err error
+  if opt.Cartesian {
+    positions, err = c.CartesianPositions()
+  } else {
+    positions, err = c.FractionalPositions()
+  }
+  if err != nil {
+    return "", err
+  }
+
+  species := make([]string, 0)
+  counts := make([]int, 0)
+  for i, t := range c.Types {
+    if strings.ContainsAny(t, " \t") || t == "" {
+      return "", fmt.Errorf("invalid species %q of atom %d", t, i)
+    }
+    if len(species) > 0 && species[len(species)-1] == t {
+      counts[len(counts)-1]++
+      continue
+    }
+    species = append(species, t)
+    counts = append(counts, 1)
+  }
+
+  f := fmt.Sprintf("%%%d.%df", prec+5, prec)
+  vec := f + " " + f + " " + f
+  var b strings.Builder
+  fmt.Fprintln(&b, strings.Replace(c.System, "\n", " ", -1))
+  fmt.Fprintln(&b, "1.0")
+  for i:=0; i<3; i++ {
+    fmt.Fprintf(&b, "  "+vec+"\n", c.Lattice[i*3+0], c.Lattice[i*3+1], c.Lattice[i*3+2])
+  }
+  for _, s := range species {
+    fmt.Fprintf(&b, " %4s", s)
+  }
+  fmt.Fprintln(&b)
+  for _, n := range counts {
+    fmt.Fprintf(&b, " %4d", n)
+  }
+  fmt.Fprintln(&b)
+  if sd != nil {
+    fmt.Fprintln(&b, "Selective dynamics")
+  }
+  if opt.Cartesian {
+    fmt.Fprintln(&b, "Cartesian")
+  } else {
+    fmt.Fprintln(&b, "Direct")
+  }
+  for i:=0; i<natoms; i++ {
+    fmt.Fprintf(&b, "  "+vec, positions[i*3+0], positions[i*3+1], positions[i*3+2])
+    if sd != nil {
+      for j:=0; j<3; j++ {
+        fmt.Fprintf(&b, " %s", flagString(sd[i*3+j]))
+      }
+    }
+    fmt.Fprintln(&b)
+  }
+  if c.Velocities != nil {
+    fmt.Fprintln(&b, "Cartesian")
+    v := c.Velocities
+    for i:=0; i<natoms; i++ {
+      fmt.Fprintf(&b, "  "+vec+"\n", v[i*3+0], v[i*3+1], v[i*3+2])
+    }
+  }
+  if c.PredictorCorrector != "" {
+    fmt.Fprintln(&b)
+    fmt.Fprintln(&b, c.PredictorCorrector)
+  }
+  return b.String(), nil
+}
+
+// WritePoscar write cell to w in VASP 5 POSCAR format, see FormatPoscar
+func WritePoscar(w io.Writer, c *Cell, opt *PoscarOptions) error {
+  txt, err := FormatPoscar(c, opt)
+  if err != nil {
+    return err
+  }
+  _, err = io.WriteString(w, txt)
+  return err
+}
+
+func flagString(b bool) string {
+  if b {
+    return "T"
   }
-  return poscar, nil
+  return "F"
 }
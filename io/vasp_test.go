@@ -1,6 +1,9 @@
 package io
 
 import(
+  "errors"
+  "math"
+  "strings"
   "testing"
 )
 
@@ -49,3 +52,383 @@ Karti
     t.Error("poscar coordinate type parse failed")
   }
 }
+
+func TestPoscarWrite(t *testing.T) {
+  c := &Cell{
+    System: "BN",
+    Lattice: []float64{4.0, 0.0, 0.0, 0.0, 4.0, 0.0, 0.0, 0.0, 4.0},
+    Coordinate: Cartesian,
+    Positions: []float64{0.0, 0.0, 0.0, 2.0, 2.0, 2.0, 1.0, 1.0, 1.0},
+    Types: []string{"B", "N", "N"},
+  }
+  txt, err := FormatPoscar(c, &PoscarOptions{
+    Precision: 6,
+    SelectiveDynamics: []bool{false, false, false, true, true, true, true, true, false},
+  })
+  if err != nil {
+    t.Fatalf("format poscar error: %v", err)
+  }
+  expect := `BN
+1.0
+     4.000000    0.000000    0.000000
+     0.000000    4.000000    0.000000
+     0.000000    0.000000    4.000000
+    B    N
+    1    2
+Selective dynamics
+Direct
+     0.000000    0.000000    0.000000 F F F
+     0.500000    0.500000    0.500000 T T T
+     0.250000    0.250000    0.250000 T T F
+`
+  if txt != expect {
+    t.Errorf("poscar write expected\n%s\ngot\n%s", expect, txt)
+  }
+
+  _, err = FormatPoscar(c, &PoscarOptions{SelectiveDynamics: []bool{true}})
+  if err == nil {
+    t.Error("expect error for wrong number of selective dynamics flags")
+  }
+}
+
+func TestPoscarRoundTrip(t *testing.T) {
+  c := &Cell{
+    System: "rocksalt NaCl",
+    Lattice: []float64{0.0, 2.8, 2.8, 2.8, 0.0, 2.8, 2.8, 2.8, 0.0},
+    Coordinate: Fractional,
+    Positions: []float64{0.0, 0.0, 0.0, 0.5, 0.5, 0.5},
+    Types: []string{"Na", "Cl"},
+  }
+  for _, cart := range []bool{false, true} {
+    txt, err := FormatPoscar(c, &PoscarOptions{Cartesian: cart})
+    if err != nil {
+      t.Fatalf("format poscar error: %v", err)
+    }
+    p, err := ParsePoscar(txt)
+    if err != nil {
+      t.Fatalf("parse written poscar error: %v", err)
+    }
+    if p.System != c.System {
+      t.Errorf("round trip system: expected %q, got %q", c.System, p.System)
+    }
+    for i := range c.Lattice {
+      if math.Abs(p.Lattice[i] - c.Lattice[i]) > 1e-8 {
+        t.Errorf("round trip lattice: expected %v, got %v", c.Lattice, p.Lattice)
+        break
+      }
+    }
+    pos, _ := p.FractionalPositions()
+    for i := range c.Positions {
+      if math.Abs(pos[i] - c.Positions[i]) > 1e-8 {
+        t.Errorf("round trip positions: expected %v, got %v", c.Positions, pos)
+        break
+      }
+    }
+    for i := range c.Types {
+      if p.Types[i] != c.Types[i] {
+        t.Errorf("round trip types: expected %v, got %v", c.Types, p.Types)
+        break
+      }
+    }
+    if cart && p.Coordinate != Cartesian || !cart && p.Coordinate != Fractional {
+      t.Errorf("round trip coordinate type: cartesian %v, got %v", cart, p.Coordinate)
+    }
+  }
+}
+
+const poscarBN = `BN
+1.0
+4.0 0.0 0.0
+0.0 4.0 0.0
+0.0 0.0 4.0
+B N
+1 1
+Direct
+0.0 0.0 0.0
+0.5 0.5 0.5
+`
+
+func TestPoscarReadComments(t *testing.T) {
+  txt := `BN
+1.0 ! scale
+4.0 0.0 0.0
+0.0 4.0 0.0
+0.0 0.0 4.0
+B N
+1 1
+direct
+0.0 0.0 0.0 B1
+0.5 0.5 0.5 ! N1
+
+
+`
+  poscar, err := ParsePoscar(txt)
+  if err != nil {
+    t.Fatalf("parse error: %v", err)
+  }
+  if len(poscar.Positions) != 6 || poscar.Positions[5] != 0.5 {
+    t.Errorf("poscar positions read fail: %v", poscar.Positions)
+  }
+  if poscar.Coordinate != Fractional {
+    t.Error("poscar coordinate type parse failed")
+  }
+}
+
+func TestPoscarReadError(t *testing.T) {
+  lines := strings.Split(poscarBN, "\n")
+  replace := func(n int, s string) string {
+    ls := make([]string, len(lines))
+    copy(ls, lines)
+    ls[n-1] = s
+    return strings.Join(ls, "\n")
+  }
+  cases := []struct{
+    name string
+    txt string
+    line int
+  }{
+    {"empty", "", 2},
+    {"scale", replace(2, "one"), 2},
+    {"zero scale", replace(2, "0.0"), 2},
+    {"lattice value", replace(4, "0.0 4.O 0.0"), 4},
+    {"short lattice", replace(5, "0.0 0.0"), 5},
+    {"truncated lattice", strings.Join(lines[:4], "\n"), 5},
+    {"counts mismatch", replace(7, "1 1 1"), 7},
+    {"count value", replace(7, "1 x"), 7},
+    {"negative count", replace(7, "1 -1"), 7},
+    {"huge count", replace(7, "1 9223372036854775807"), 7},
+    {"coordinate type", replace(8, ""), 8},
+    {"position value", replace(10, "0.5 0.5 abc"), 10},
+    {"too few positions", strings.Join(lines[:9], "\n"), 10},
+    {"blank position", replace(10, ""), 10},
+    {"too many positions", replace(11, "0.2 0.2 0.2"), 11},
+  }
+  for _, c := range cases {
+    _, err := ParsePoscar(c.txt)
+    var pe *PoscarError
+    if !errors.As(err, &pe) {
+      t.Errorf("%s: expect *PoscarError, got %v", c.name, err)
+      continue
+    }
+    if pe.Line != c.line {
+      t.Errorf("%s: expect error at line %d, got %v", c.name, c.line, err)
+    }
+  }
+}
+
+func FuzzParsePoscar(f *testing.F) {
+  f.Add(poscarBN)
+  f.Add(`system
+1
+4.0 0.0 0.0
+0.0 4.0 0.0
+0.0 0.0 4.0
+B N
+1 1
+selective dynamics
+Karti
+0.0 0.0 0.0 T T F
+2.0 2.0 2.0 F F F`)
+  f.Fuzz(func(t *testing.T, txt string) {
+    c, err := ParsePoscar(txt)
+    if err != nil {
+      return
+    }
+    if len(c.Positions) != 3*len(c.Types) || len(c.Lattice) != 9 {
+      t.Errorf("inconsistent cell: %d positions for %d atoms", len(c.Positions), len(c.Types))
+    }
+    if c.SelectiveDynamics != nil && len(c.SelectiveDynamics) != 3*len(c.Types) ||
+      c.Velocities != nil && len(c.Velocities) != 3*len(c.Types) {
+      t.Errorf("inconsistent cell: %d flags and %d velocities for %d atoms", len(c.SelectiveDynamics), len(c.Velocities), len(c.Types))
+    }
+  })
+}
+
+func TestPoscarReadVasp4(t *testing.T) {
+  txt := `Ga As
+1.0
+5.6 0.0 0.0
+0.0 5.6 0.0
+0.0 0.0 5.6
+1 1
+Direct
+0.0 0.0 0.0
+0.25 0.25 0.25`
+  poscar, err := ParsePoscar(txt)
+  if err != nil {
+    t.Fatalf("parse vasp 4 error: %v", err)
+  }
+  if poscar.Types[0] != "Ga" || poscar.Types[1] != "As" {
+    t.Errorf("species from system comment expected [Ga As], got %v", poscar.Types)
+  }
+
+  poscar, err = ParsePoscarWithOptions(txt, &PoscarReadOptions{Species: []string{"Al", "P"}})
+  if err != nil {
+    t.Fatalf("parse vasp 4 error: %v", err)
+  }
+  if poscar.Types[0] != "Al" || poscar.Types[1] != "P" {
+    t.Errorf("species from options expected [Al P], got %v", poscar.Types)
+  }
+
+  _, err = ParsePoscar(strings.Replace(txt, "Ga As", "zincblende", 1))
+  var pe *PoscarError
+  if !errors.As(err, &pe) || pe.Line != 6 {
+    t.Errorf("expect error at line 6 for missing species, got %v", err)
+  }
+}
+
+func TestPoscarReadScale(t *testing.T) {
+  txt := `scaled
+%s
+2.0 0.0 0.0
+0.0 2.0 0.0
+0.0 0.0 2.0
+H
+1
+Cartesian
+1.0 1.0 1.0`
+  cases := []struct{
+    scale string
+    latt []float64
+    pos []float64
+  }{
+    {"2.0", []float64{4, 0, 0, 0, 4, 0, 0, 0, 4}, []float64{2, 2, 2}},
+    {"-64.0", []float64{4, 0, 0, 0, 4, 0, 0, 0, 4}, []float64{2, 2, 2}},
+    {"1.0 2.0 3.0", []float64{2, 0, 0, 0, 4, 0, 0, 0, 6}, []float64{1, 2, 3}},
+  }
+  for _, c := range cases {
+    poscar, err := ParsePoscar(strings.Replace(txt, "%s", c.scale, 1))
+    if err != nil {
+      t.Errorf("scale %s: parse error: %v", c.scale, err)
+      continue
+    }
+    for i := range c.latt {
+      if math.Abs(poscar.Lattice[i] - c.latt[i]) > 1e-8 {
+        t.Errorf("scale %s: lattice expected %v, got %v", c.scale, c.latt, poscar.Lattice)
+        break
+      }
+    }
+    for i := range c.pos {
+      if math.Abs(poscar.Positions[i] - c.pos[i]) > 1e-8 {
+        t.Errorf("scale %s: positions expected %v, got %v", c.scale, c.pos, poscar.Positions)
+        break
+      }
+    }
+  }
+
+  if _, err := ParsePoscar(strings.Replace(txt, "%s", "1.0 -1.0 1.0", 1)); err == nil {
+    t.Error("expect error for negative per-axis scale factor")
+  }
+}
+
+func TestContcarReadWrite(t *testing.T) {
+  txt := `slab
+1.0
+4.0 0.0 0.0
+0.0 4.0 0.0
+0.0 0.0 4.0
+B N
+1 1
+Selective dynamics
+Direct
+0.0 0.0 0.0 F F F
+0.5 0.5 0.5 T T T
+
+0.1 0.0 0.0
+0.0 0.0 -0.2
+
+  1
+  0.10000000E-02
+  0.00000000E+00  0.00000000E+00  0.00000000E+00`
+  c, err := ParsePoscar(txt)
+  if err != nil {
+    t.Fatalf("parse contcar error: %v", err)
+  }
+  expect_flags := []bool{false, false, false, true, true, true}
+  for i := range expect_flags {
+    if c.SelectiveDynamics[i] != expect_flags[i] {
+      t.Errorf("selective dynamics expected %v, got %v", expect_flags, c.SelectiveDynamics)
+      break
+    }
+  }
+  expect_vel := []float64{0.1, 0.0, 0.0, 0.0, 0.0, -0.2}
+  for i := range expect_vel {
+    if math.Abs(c.Velocities[i] - expect_vel[i]) > 1e-8 {
+      t.Errorf("velocities expected %v, got %v", expect_vel, c.Velocities)
+      break
+    }
+  }
+  if !strings.HasPrefix(c.PredictorCorrector, "  1\n") {
+    t.Errorf("predictor-corrector block not kept, got %q", c.PredictorCorrector)
+  }
+
+  out, err := FormatPoscar(c, nil)
+  if err != nil {
+    t.Fatalf("format contcar error: %v", err)
+  }
+  r, err := ParsePoscar(out)
+  if err != nil {
+    t.Fatalf("parse written contcar error: %v\n%s", err, out)
+  }
+  for i := range expect_flags {
+    if r.SelectiveDynamics[i] != expect_flags[i] {
+      t.Errorf("round trip selective dynamics expected %v, got %v", expect_flags, r.SelectiveDynamics)
+      break
+    }
+  }
+  for i := range expect_vel {
+    if math.Abs(r.Velocities[i] - expect_vel[i]) > 1e-8 {
+      t.Errorf("round trip velocities expected %v, got %v", expect_vel, r.Velocities)
+      break
+    }
+  }
+  if r.PredictorCorrector != c.PredictorCorrector {
+    t.Errorf("round trip predictor-corrector expected %q, got %q", c.PredictorCorrector, r.PredictorCorrector)
+  }
+
+  bad := strings.Replace(txt, "0.5 0.5 0.5 T T T", "0.5 0.5 0.5 T X T", 1)
+  var pe *PoscarError
+  if _, err := ParsePoscar(bad); !errors.As(err, &pe) || pe.Line != 11 {
+    t.Errorf("expect error at line 11 for bad flag, got %v", err)
+  }
+  for _, flags := range []string{"F T", "T"} {
+    bad = strings.Replace(txt, "0.0 0.0 0.0 F F F", "0.0 0.0 0.0 "+flags, 1)
+    if _, err := ParsePoscar(bad); !errors.As(err, &pe) || pe.Line != 10 {
+      t.Errorf("expect error at line 10 for flags %q, got %v", flags, err)
+    }
+  }
+  free := strings.Replace(txt, "0.0 0.0 0.0 F F F", "0.0 0.0 0.0", 1)
+  if c, err := ParsePoscar(free); err != nil || !c.SelectiveDynamics[0] {
+    t.Errorf("expect atom without flags free, got %v", err)
+  }
+}
+
+func TestPoscarReadDirectVelocities(t *testing.T) {
+  txt := `H2
+1.0
+2.0 0.0 0.0
+0.0 2.0 0.0
+0.0 0.0 2.0
+H
+2
+Direct
+0.0 0.0 0.0
+0.5 0.5 0.5
+Direct
+0.5 0.0 0.0
+0.0 0.0 0.25`
+  c, err := ParsePoscar(txt)
+  if err != nil {
+    t.Fatalf("parse error: %v", err)
+  }
+  if c.SelectiveDynamics != nil {
+    t.Errorf("expect no selective dynamics, got %v", c.SelectiveDynamics)
+  }
+  expect_vel := []float64{1.0, 0.0, 0.0, 0.0, 0.0, 0.5}
+  for i := range expect_vel {
+    if math.Abs(c.Velocities[i] - expect_vel[i]) > 1e-8 {
+      t.Errorf("velocities expected %v, got %v", expect_vel, c.Velocities)
+      break
+    }
+  }
+}
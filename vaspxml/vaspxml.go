@@ -19,7 +19,11 @@ type VaspRunXML struct {
 	FinalStructure   Structure
 	InitialStructure Structure
 	Structures       []Structure
-	DOS              DOS
+	// Stresses of ionic steps in kBar, positive under compression
+	Stresses [][][]float64
+	// Stress of last ionic step, nil if not calculated
+	Stress [][]float64
+	DOS    DOS
 }
 
 type CalInfo struct {
@@ -89,6 +93,14 @@ func Parse(r io.Reader) (VaspRunXML, error) {
 				vr.InitialStructure = vr.Structures[0]
 				vr.FinalStructure = vr.Structures[len(vr.Structures)-1]
 			}
+			if se.Name.Local == "varray" && attrValue(se, "name") == "stress" {
+				st, err := vParser(decoder, &se)
+				if err != nil {
+					return vr, fmt.Errorf("parsing xml stress %v: %v", se, err)
+				}
+				vr.Stresses = append(vr.Stresses, st)
+				vr.Stress = st
+			}
 			if se.Name.Local == "dos" {
 				dosDec := xmlstream.Inner(decoder)
 				vr.DOS = handlerDOS(dosDec)
@@ -98,6 +110,16 @@ func Parse(r io.Reader) (VaspRunXML, error) {
 	return vr, nil
 }
 
+// attrValue return value of attribute name of element, empty if absent
+func attrValue(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
 func handlerAtomInfo(tr xml.TokenReader) (ai AtomInfo) {
 	for {
 		tok, err := tr.Token()
@@ -215,6 +215,49 @@ func TestParseStructures(t *testing.T) {
 	}
 }
 
+const stressdata = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<modeling>
+ <calculation>
+  <varray name="forces" >
+   <v>       0.00000000       0.00000000       0.00000000 </v>
+  </varray>
+  <varray name="stress" >
+   <v>      12.50000000       0.00000000       0.00000000 </v>
+   <v>       0.00000000      12.50000000       0.00000000 </v>
+   <v>       0.00000000       0.00000000      12.50000000 </v>
+  </varray>
+ </calculation>
+ <calculation>
+  <varray name="stress" >
+   <v>      -1.00000000       0.20000000       0.00000000 </v>
+   <v>       0.20000000      -1.00000000       0.00000000 </v>
+   <v>       0.00000000       0.00000000       3.00000000 </v>
+  </varray>
+ </calculation>
+</modeling>
+`
+
+func TestParseStress(t *testing.T) {
+	vasprun, err := Parse(strings.NewReader(stressdata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(vasprun.Stresses); got != 2 {
+		t.Fatalf("len(vasprun.Stresses) == %d, want 2", got)
+	}
+	wanted := [][]float64{
+		{-1, 0.2, 0},
+		{0.2, -1, 0},
+		{0, 0, 3},
+	}
+	if got := vasprun.Stress; !twoDSliceEqual(got, wanted) {
+		t.Errorf("vasprun.Stress == %v, want %v", got, wanted)
+	}
+	if got := vasprun.Stresses[0][2][2]; got != 12.5 {
+		t.Errorf("vasprun.Stresses[0][2][2] == %v, want 12.5", got)
+	}
+}
+
 func TestParseFromFile(t *testing.T) {
 	absFilePath, err := filepath.Abs("test.xml")
 	if err != nil {